@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/metadata"
+)
+
+// Method of the CoreFewerSrvClient that performs the ListStreams() RPC of the FewerAdmin service,
+//   returning the aggregation streams open on the server, oldest first, and whether the server is
+//   draining.
+func (c *CoreFewerSrvClient) PerformListStreamsOp() ([]*pb.ActiveStream, bool, error) {
+	resp, err := c.adminClient.ListStreams(c.adminContext(), &pb.ListStreamsRequest{})
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformListStreamsOp", fmt.Sprintf("ListStreams RPC failed: %v", err))
+		return nil, false, err
+	}
+	for _, stream := range resp.Streams {
+		c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformListStreamsOp", fmt.Sprintf("Open stream on Fewer Service server: %v", stream))
+	}
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformListStreamsOp", fmt.Sprintf("Fewer Service server has %d open streams (draining: %t)", len(resp.Streams), resp.Draining))
+	return resp.Streams, resp.Draining, nil
+}
+
+// Method of the CoreFewerSrvClient that performs the CancelStream() RPC of the FewerAdmin service,
+//   ending the open aggregation stream with the given ID.  The client of the stream gets a
+//   Canceled status error, with the reason in its trailer.  It returns the stream as it was when
+//   cancelled.
+func (c *CoreFewerSrvClient) PerformCancelStreamOp(streamID, reason string) (*pb.ActiveStream, error) {
+	resp, err := c.adminClient.CancelStream(c.adminContext(), &pb.CancelStreamRequest{StreamId: streamID, Reason: reason})
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformCancelStreamOp", fmt.Sprintf("CancelStream RPC failed: %v", err))
+		return nil, err
+	}
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformCancelStreamOp", fmt.Sprintf("Cancelled stream on Fewer Service server: %v", resp.Stream))
+	return resp.Stream, nil
+}
+
+// Method of the CoreFewerSrvClient that performs the Drain() RPC of the FewerAdmin service, having
+//   the server reject new aggregation calls while its open streams run to their end, and then shut
+//   down if shutdown is set.  It returns the number of streams still open on the server.
+func (c *CoreFewerSrvClient) PerformDrainOp(shutdown bool) (int, error) {
+	resp, err := c.adminClient.Drain(c.adminContext(), &pb.DrainRequest{Shutdown: shutdown})
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformDrainOp", fmt.Sprintf("Drain RPC failed: %v", err))
+		return 0, err
+	}
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformDrainOp", fmt.Sprintf("Fewer Service server is draining, with %d streams still open (shutdown once drained: %t)", resp.OpenStreams, shutdown))
+	return int(resp.OpenStreams), nil
+}
+
+// Internal method of the CoreFewerSrvClient that returns the context of an admin call, presenting
+//   its admin token, if it has one.
+func (c *CoreFewerSrvClient) adminContext() context.Context {
+	ctx := context.Background()
+	if c.adminToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, adminAuthorizationMetadataKey, bearerScheme+c.adminToken)
+	}
+	return ctx
+}
@@ -1,7 +1,21 @@
 package internal
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/logging"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
 )
 
 
@@ -13,7 +27,33 @@ type Cli struct {
 	address     *string
 	port        *int
 	totalInputs *int
+	maxInFlight *int
+	coalesce    *int
+	linger      *time.Duration
+	streamKey   *string
+	tenant      *string
+	reducer     *string
+	rules       wire.ValidationRules
+	authToken   *string
+	useTLS      *bool
+	tlsCAFile   *string
+	keepaliveTime       *time.Duration
+	keepaliveTimeout    *time.Duration
+	keepaliveWithoutRPC *bool
 	prod        *bool
+	logDir      *string
+	logFile     *string
+	logLevel    *string
+	logFormat   *string
+	logOutputs  *string
+	logMaxSize    *int64
+	logMaxAge     *time.Duration
+	logMaxBackups *int
+	logCompress   *bool
+
+	// Subcommand given after the flags (e.g., "query"), and the arguments that follow it.
+	subcommand  string
+	subArgs     []string
 }
 
 // Constructor function that creates a new instance of the *Cli object
@@ -25,42 +65,192 @@ func NewCli() *Cli {
 //   `go run` command.
 func (cli *Cli) LoadAndParseFlags() {
 	// Address and port of the Fewer Service server to connect to
-	cli.address = flag.String("address", "localhost", "address or hostname of the Fewer Service server to connect to")
+	cli.address = flag.String("address", "localhost", "address or hostname of the Fewer Service server to connect to, or unix:///path/to/socket to connect through a Unix domain socket")
 	cli.port = flag.Int("port", 50051, "port of the Fewer Service server to connect to")
 
 	// Maximum number of requests to send to the service
 	cli.totalInputs = flag.Int("totalInputs", 15, "maximum number of requests to send to Fewer Service")
-	
+
+	// Maximum number of requests waiting on an acknowledgement from the service
+	cli.maxInFlight = flag.Int("maxInFlight", DefaultMaxInFlight, "maximum number of unacknowledged requests in flight (0 turns off flow control)")
+
+	// Number of requests packed into one message, and how long to wait for a message to fill up
+	cli.coalesce = flag.Int("coalesce", DefaultCoalesceSize, "maximum number of requests packed into one message (1 turns off packing)")
+	cli.linger = flag.Duration("linger", DefaultCoalesceLinger, "maximum time a packed message waits to fill up before being sent")
+
+	// Key and tenant labels of the stream opened to the service
+	cli.streamKey = flag.String("streamKey", "", "key to label the aggregation stream with")
+	cli.tenant = flag.String("tenant", "", "tenant to label the aggregation stream with")
+
+	// Reducer applied to every batch of inputs of the stream
+	cli.reducer = flag.String("reducer", "", "reducer applied to every batch of inputs: sum, min or max (default sum)")
+
+	// Validation rules of the inputs of the stream, and policy applied to invalid inputs
+	flag.Func("minInput", "smallest valid input (default no minimum)", func(value string) error {
+		n, err := parseInputNum(value)
+		cli.rules.Min = &n
+		return err
+	})
+	flag.Func("maxInput", "largest valid input (default no maximum)", func(value string) error {
+		n, err := parseInputNum(value)
+		cli.rules.Max = &n
+		return err
+	})
+	flag.Func("disallowedInputs", "comma-separated inputs that are never valid", func(value string) error {
+		for _, item := range strings.Split(value, ",") {
+			n, err := parseInputNum(item)
+			if err != nil {
+				return err
+			}
+			cli.rules.Disallowed = append(cli.rules.Disallowed, n)
+		}
+		return nil
+	})
+	flag.StringVar(&cli.rules.Policy, "invalidInputPolicy", "", "policy applied to invalid inputs: fail, skip or clamp (default fail)")
+
+	// Bearer token presented to the service, if it authenticates its clients
+	cli.authToken = flag.String("authToken", os.Getenv("FEWER_AUTH_TOKEN"), "bearer token presented to the Fewer Service server (default $FEWER_AUTH_TOKEN)")
+
+	// TLS securing the connection to the service
+	cli.useTLS = flag.Bool("tls", false, "secure the connection to the Fewer Service server with TLS, verifying its certificate against the system roots")
+	cli.tlsCAFile = flag.String("tlsCAFile", "", "PEM file of the CA certificates the server's certificate is verified against instead of the system roots (turns on -tls)")
+
+	// Keepalive pings sent to the service, to detect connections that died silently
+	cli.keepaliveTime = flag.Duration("keepaliveTime", 0, "time after which the client pings a server connection it has heard nothing from (0 turns pings off, at least 10s otherwise)")
+	cli.keepaliveTimeout = flag.Duration("keepaliveTimeout", DefaultKeepaliveTimeout, "time the client waits for a keepalive ping to be answered before closing the connection as dead")
+	cli.keepaliveWithoutRPC = flag.Bool("keepalivePermitWithoutStream", false, "send keepalive pings even while no call is open")
+
 	// Whether the client is production-grade or not
 	cli.prod = flag.Bool("prod", true, "indicates whether the client is a production (true) or development/test (false) client")
 
+	// Location of the client log file
+	cli.logDir = flag.String("logDir", "clientlogs", "directory whose production/ or devtest/ subdirectory holds the client log file (created if missing)")
+	cli.logFile = flag.String("logFile", "", "name of the client log file inside the log directory, or absolute path of the client log file (default client.log for production, client_devtest.log for development)")
+
+	// Level, format and destinations of the client's logged activity
+	cli.logLevel = flag.String("logLevel", "", "minimum level of logged activity: debug, info, warn or error (default info for production, debug for development)")
+	cli.logFormat = flag.String("logFormat", logging.FormatLogfmt, "format of logged activity (logfmt, json or text)")
+	cli.logOutputs = flag.String("logOutputs", "", "comma-separated destinations of logged activity: stdout, stderr or log file paths (default stdout and the client log file)")
+
+	// Rotation and retention of the client log file
+	cli.logMaxSize = flag.Int64("logMaxSize", 0, "size in megabytes past which the client log file is rotated (0 turns off size-based rotation)")
+	cli.logMaxAge = flag.Duration("logMaxAge", 0, "age past which the client log file is rotated (0 turns off age-based rotation)")
+	cli.logMaxBackups = flag.Int("logMaxBackups", 0, "number of rotated client log files kept (0 keeps all of them)")
+	cli.logCompress = flag.Bool("logCompress", false, "compress rotated client log files with gzip")
+
 	flag.Parse()
+
+	// Any argument left after the flags names a subcommand, and is followed by its own flags.
+	if flag.NArg() > 0 {
+		cli.subcommand = flag.Arg(0)
+		cli.subArgs = flag.Args()[1:]
+	}
 }
 
-// Method of the Cli object that creates a core client object and performs the process 
-//   of sending over number request messages to the Fewer Service server, in an attempt
-//   to get a few number responses back.
-func (cli *Cli) PerformGetAggregatesOp() error {
-	// Create a ClientLogger, based on whether the client will be production
-	//   or development/testing (non-production).
+// Method of the Cli object that runs the operation named by the subcommand.  Without a
+//   subcommand, the GetAggregatesStream() operation is performed.
+func (cli *Cli) Run() error {
+	switch cli.subcommand {
+	case "", "aggregate":
+		return cli.PerformGetAggregatesOp()
+	case "batch":
+		return cli.PerformAggregateBatchOp()
+	case "upload":
+		return cli.PerformAggregateUploadOp()
+	case "load":
+		return cli.PerformLoadTestOp()
+	case "query":
+		return cli.PerformQueryAggregatesOp()
+	case "subscribe":
+		return cli.PerformSubscribeAggregatesOp()
+	case "admin":
+		return cli.PerformAdminOp()
+	default:
+		return fmt.Errorf("unknown subcommand %q (expected aggregate, batch, upload, load, query, subscribe or admin)", cli.subcommand)
+	}
+}
+
+// Internal method of the Cli object that creates a ClientLogger, based on whether the client will
+//   be production or development/testing (non-production), and on the logging flags.
+func (cli *Cli) newClientLogger() (ClientLogger, error) {
 	const clientLogProdFilename = "client.log"
 	const clientLogDevFilename = "client_devtest.log"
-	var clientLogger ClientLogger
-	if *cli.prod {
-		clientLogger = NewClientLoggingObjectPROD(clientLogProdFilename)
-	} else {
-		clientLogger = NewClientLoggingObjectDEV(clientLogDevFilename)
+	clientLogFilename := *cli.logFile
+	if clientLogFilename == "" && *cli.prod {
+		clientLogFilename = clientLogProdFilename
+	} else if clientLogFilename == "" {
+		clientLogFilename = clientLogDevFilename
+	}
+	clientLogFilename = logging.LogFilePath(*cli.logDir, clientLogFilename, *cli.prod)
+
+	logConfig := DefaultClientLogConfig(clientLogFilename, *cli.prod)
+	if *cli.logLevel != "" {
+		logConfig.Level = *cli.logLevel
+	}
+	logConfig.Format = *cli.logFormat
+	if destinations := logging.SplitDestinations(*cli.logOutputs); len(destinations) > 0 {
+		logConfig.Destinations = destinations
+	}
+	logConfig.Rotation = logging.RotationConfig{
+		MaxSize:    *cli.logMaxSize << 20,
+		MaxAge:     *cli.logMaxAge,
+		MaxBackups: *cli.logMaxBackups,
+		Compress:   *cli.logCompress,
+	}
+	clientLogger, err := NewClientLoggingObject(logConfig)
+	if err != nil {
+		return nil, err
+	}
+	// Reopen the client log file on SIGHUP, so that it can be rotated by an external tool (e.g.,
+	//   logrotate) while a long-running subcommand (e.g., subscribe) is logging to it.
+	clientLogger.Logger().ReopenOnSignal(syscall.SIGHUP)
+	return clientLogger, nil
+}
+
+// Internal method of the Cli object that creates a core client object and connects it to the
+//   Fewer Service server.  The caller is responsible for closing the returned client.
+func (cli *Cli) newConnectedCoreClient() (*CoreFewerSrvClient, error) {
+	clientLogger, err := cli.newClientLogger()
+	if err != nil {
+		return nil, err
 	}
+	return cli.newConnectedCoreClientWithLogger(clientLogger)
+}
 
+// Internal method of the Cli object that creates a core client object logging to clientLogger, and
+//   connects it to the Fewer Service server.  The caller is responsible for closing the returned
+//   client.
+func (cli *Cli) newConnectedCoreClientWithLogger(clientLogger ClientLogger) (*CoreFewerSrvClient, error) {
 	// Create core client object.
 	coreClient := NewCoreFewerSrvClient(*cli.address, *cli.port, clientLogger, *cli.prod)
-	defer coreClient.Close()
+	coreClient.SetMaxInFlight(*cli.maxInFlight)
+	coreClient.SetCoalescing(*cli.coalesce, *cli.linger)
+	coreClient.SetStreamLabels(*cli.streamKey, *cli.tenant)
+	coreClient.SetReducer(*cli.reducer)
+	coreClient.SetValidationRules(cli.rules)
+	coreClient.SetAuthToken(*cli.authToken)
+	if *cli.useTLS || *cli.tlsCAFile != "" {
+		coreClient.SetTLS(*cli.tlsCAFile)
+	}
+	coreClient.SetKeepalive(*cli.keepaliveTime, *cli.keepaliveTimeout, *cli.keepaliveWithoutRPC)
 
 	// Connect the core client to the Fewer Service server.
-	err := coreClient.ConnectToServer()
+	if err := coreClient.ConnectToServer(); err != nil {
+		coreClient.Close()
+		return nil, err
+	}
+	return coreClient, nil
+}
+
+// Method of the Cli object that creates a core client object and performs the process
+//   of sending over number request messages to the Fewer Service server, in an attempt
+//   to get a few number responses back.
+func (cli *Cli) PerformGetAggregatesOp() error {
+	coreClient, err := cli.newConnectedCoreClient()
 	if err != nil {
 		return err
 	}
+	defer coreClient.Close()
 
 	// Perform the Fewer Service's bidirectional-streaming GetAggregatesStream() RPC,
 	//   sending over many number requests, and receiving back only a few number responses
@@ -72,4 +262,251 @@ func (cli *Cli) PerformGetAggregatesOp() error {
 
 	// A nil error indicates successful connection and operation
 	return nil
-}
\ No newline at end of file
+}
+
+// Method of the Cli object that sends the numbers 1 to totalInputs to the Fewer Service server all
+//   at once, using the unary AggregateBatch() RPC.
+func (cli *Cli) PerformAggregateBatchOp() error {
+	coreClient, err := cli.newConnectedCoreClient()
+	if err != nil {
+		return err
+	}
+	defer coreClient.Close()
+
+	_, err = coreClient.PerformAggregateBatchOp(cli.inputNums())
+	return err
+}
+
+// Method of the Cli object that streams the numbers 1 to totalInputs to the Fewer Service server,
+//   using the client-streaming AggregateUpload() RPC, and gets back a single summary.
+func (cli *Cli) PerformAggregateUploadOp() error {
+	coreClient, err := cli.newConnectedCoreClient()
+	if err != nil {
+		return err
+	}
+	defer coreClient.Close()
+
+	_, err = coreClient.PerformAggregateUploadOp(cli.inputNums())
+	return err
+}
+
+// Internal method of the Cli object that returns the numbers 1 to totalInputs, which are the
+//   numbers the CLI sends to the Fewer Service.
+func (cli *Cli) inputNums() []int32 {
+	inputNums := make([]int32, 0, *cli.totalInputs)
+	for i := 1; i <= *cli.totalInputs; i++ {
+		inputNums = append(inputNums, int32(i))
+	}
+	return inputNums
+}
+
+// Helper function that parses an input number given to a validation rule flag.
+func parseInputNum(value string) (int32, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a 32-bit integer", value)
+	}
+	return int32(n), nil
+}
+
+// Method of the Cli object that parses the flags of the `load` subcommand, and runs a load test
+//   against the Fewer Service server: several concurrent streams are fed inputs at a target rate
+//   for a set duration, and the throughput and batch latencies are reported.
+func (cli *Cli) PerformLoadTestOp() error {
+	loadFlags := flag.NewFlagSet("load", flag.ContinueOnError)
+	streams := loadFlags.Int("streams", 4, "number of concurrent streams, each on its own core client")
+	rate := loadFlags.Float64("rate", 0, "target number of inputs per second over all streams (0 for as fast as possible)")
+	duration := loadFlags.Duration("duration", 10*time.Second, "how long to send inputs for")
+	format := loadFlags.String("format", "text", "format of the report (text or json)")
+	if err := loadFlags.Parse(cli.subArgs); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid --format %q (expected text or json)", *format)
+	}
+
+	// All core clients share one logger, which only logs their warnings and errors, as logging
+	//   every response of every stream would flood the logs.
+	clientLogger, err := cli.newClientLogger()
+	if err != nil {
+		return err
+	}
+	defer clientLogger.Close()
+	clientLogger.ClientLogInfo("method", "Cli.PerformLoadTestOp", fmt.Sprintf("Starting load test: %d streams, %v inputs/s, %v", *streams, *rate, *duration))
+
+	config := LoadTestConfig{Streams: *streams, InputRate: *rate, Duration: *duration}
+	report, err := RunLoadTest(config, func() (*CoreFewerSrvClient, error) {
+		return cli.newConnectedCoreClientWithLogger(NewQuietClientLogger(clientLogger))
+	})
+	if err != nil {
+		clientLogger.ClientLogError("method", "Cli.PerformLoadTestOp", fmt.Sprintf("Load test failed: %v", err))
+		return err
+	}
+	clientLogger.ClientLogInfo("method", "Cli.PerformLoadTestOp", fmt.Sprintf("Load test finished: %.1f inputs/s, p99 batch latency %.3f ms", report.InputsPerSec, report.Latency.P99Ms))
+
+	if *format == "json" {
+		return report.WriteJSON(os.Stdout)
+	}
+	report.WriteText(os.Stdout)
+	return nil
+}
+
+// Method of the Cli object that parses the flags of the `query` subcommand, and reads back the
+//   aggregates that the Fewer Service server has persisted and that match them.
+func (cli *Cli) PerformQueryAggregatesOp() error {
+	queryFlags := flag.NewFlagSet("query", flag.ContinueOnError)
+	streamID := queryFlags.String("streamId", "", "only return aggregates of the stream with this ID")
+	key := queryFlags.String("key", "", "only return aggregates with this key")
+	since := queryFlags.String("since", "", "only return aggregates emitted at or after this RFC 3339 time")
+	until := queryFlags.String("until", "", "only return aggregates emitted before this RFC 3339 time")
+	pageSize := queryFlags.Int("pageSize", 0, "number of aggregates per page streamed back by the server (0 for server default)")
+	pageToken := queryFlags.String("pageToken", "", "page token to resume a previous query from")
+	limit := queryFlags.Int("limit", 0, "maximum number of aggregates to return (0 for no limit)")
+	if err := queryFlags.Parse(cli.subArgs); err != nil {
+		return err
+	}
+
+	query := AggregateQuery{
+		StreamID:  *streamID,
+		Key:       *key,
+		PageSize:  *pageSize,
+		PageToken: *pageToken,
+		Limit:     *limit,
+	}
+	var err error
+	if *since != "" {
+		if query.EmittedAfter, err = time.Parse(time.RFC3339, *since); err != nil {
+			return fmt.Errorf("invalid --since time: %v", err)
+		}
+	}
+	if *until != "" {
+		if query.EmittedBefore, err = time.Parse(time.RFC3339, *until); err != nil {
+			return fmt.Errorf("invalid --until time: %v", err)
+		}
+	}
+
+	coreClient, err := cli.newConnectedCoreClient()
+	if err != nil {
+		return err
+	}
+	defer coreClient.Close()
+
+	// Perform the Fewer Service's server-streaming QueryAggregates() RPC.  The aggregates
+	//   themselves are logged by the core client as they come in.
+	_, nextPageToken, err := coreClient.PerformQueryAggregatesOp(query)
+	if err != nil {
+		return err
+	}
+	if nextPageToken != "" {
+		fmt.Printf("More aggregates match this query.  Resume with --pageToken %s\n", nextPageToken)
+	}
+
+	return nil
+}
+
+// Method of the Cli object that parses the flags of the `subscribe` subcommand, and watches the
+//   aggregates that the Fewer Service emits on matching streams until interrupted (Ctrl+C).
+func (cli *Cli) PerformSubscribeAggregatesOp() error {
+	subscribeFlags := flag.NewFlagSet("subscribe", flag.ContinueOnError)
+	key := subscribeFlags.String("key", "", "only watch streams labelled with this key")
+	tenant := subscribeFlags.String("tenant", "", "only watch streams labelled with this tenant")
+	bufferSize := subscribeFlags.Uint("bufferSize", 0, "number of events the server buffers for this subscriber (0 for server default)")
+	policy := subscribeFlags.String("slowConsumerPolicy", "drop", "what the server does when the buffer is full (drop or disconnect)")
+	if err := subscribeFlags.Parse(cli.subArgs); err != nil {
+		return err
+	}
+
+	req := &pb.SubscribeAggregatesRequest{
+		Key:        *key,
+		Tenant:     *tenant,
+		BufferSize: uint32(*bufferSize),
+	}
+	switch *policy {
+	case "drop":
+		req.SlowConsumerPolicy = pb.SlowConsumerPolicy_SLOW_CONSUMER_POLICY_DROP
+	case "disconnect":
+		req.SlowConsumerPolicy = pb.SlowConsumerPolicy_SLOW_CONSUMER_POLICY_DISCONNECT
+	default:
+		return fmt.Errorf("invalid --slowConsumerPolicy %q (expected drop or disconnect)", *policy)
+	}
+
+	coreClient, err := cli.newConnectedCoreClient()
+	if err != nil {
+		return err
+	}
+	defer coreClient.Close()
+
+	// Watch the events until an interruption/termination signal is received.  The events
+	//   themselves are logged by the core client as they come in.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return coreClient.PerformSubscribeAggregatesOp(ctx, req, nil)
+}
+
+// Method of the Cli object that parses the flags of the `admin` subcommand, and performs the
+//   FewerAdmin operation named by the argument that follows them: `list` prints the open
+//   aggregation streams of the server, `cancel <stream ID>` cancels one of them, and `drain` has
+//   the server stop taking new aggregation calls.
+func (cli *Cli) PerformAdminOp() error {
+	adminFlags := flag.NewFlagSet("admin", flag.ContinueOnError)
+	adminToken := adminFlags.String("adminToken", os.Getenv("FEWER_ADMIN_TOKEN"), "admin token presented to the FewerAdmin service (default $FEWER_ADMIN_TOKEN)")
+	reason := adminFlags.String("reason", "", "reason given to the client of a cancelled stream")
+	shutdown := adminFlags.Bool("shutdown", false, "have a drained server shut down once its open streams end")
+	if err := adminFlags.Parse(cli.subArgs); err != nil {
+		return err
+	}
+	action, args := adminFlags.Arg(0), adminFlags.Args()
+	switch {
+	case action == "cancel" && len(args) != 2:
+		return fmt.Errorf("admin cancel expects the ID of the stream to cancel")
+	case (action == "list" || action == "drain") && len(args) != 1:
+		return fmt.Errorf("admin %s expects no argument", action)
+	case action != "list" && action != "cancel" && action != "drain":
+		return fmt.Errorf("unknown admin operation %q (expected list, cancel or drain)", action)
+	}
+
+	coreClient, err := cli.newConnectedCoreClient()
+	if err != nil {
+		return err
+	}
+	defer coreClient.Close()
+	coreClient.SetAdminToken(*adminToken)
+
+	switch action {
+	case "cancel":
+		_, err := coreClient.PerformCancelStreamOp(args[1], *reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cancelled stream %s\n", args[1])
+	case "drain":
+		open, err := coreClient.PerformDrainOp(*shutdown)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Server is draining, with %d streams still open\n", open)
+	default:
+		streams, draining, err := coreClient.PerformListStreamsOp()
+		if err != nil {
+			return err
+		}
+		writeActiveStreams(os.Stdout, streams, draining)
+	}
+	return nil
+}
+
+// Helper function that prints the open aggregation streams of a server as a table.
+func writeActiveStreams(w io.Writer, streams []*pb.ActiveStream, draining bool) {
+	if draining {
+		fmt.Fprintln(w, "Server is draining.")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STREAM ID\tMETHOD\tPEER\tKEY\tTENANT\tSTARTED\tINPUTS\tPARTIAL SUM\tREDUCER")
+	for _, stream := range streams {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			stream.StreamId, stream.Method, stream.Peer, stream.Key, stream.Tenant,
+			stream.StartedAt.AsTime().Format(time.RFC3339), stream.InputsSeen, stream.PartialSum, stream.Reducer,
+		)
+	}
+	tw.Flush()
+}
@@ -1,16 +1,19 @@
 package internal
 
 import (
-	"os"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/go-kit/log"
-	"github.com/go-kit/log/level"
+	"github.com/astronomical3/fewer_grpc/internal/logging"
 )
 
 //********************************************************************************************
 // Definition of a ClientLogger interface that includes common logging operation methods that
 //   will be used across multiple concrete logger types.
 type ClientLogger interface {
+	ClientLogDebug(key, value, message string)
 	ClientLogInfo(key, value, message string)
 	ClientLogWarn(key, value, message string)
 	ClientLogError(key, value, message string)
@@ -19,148 +22,251 @@ type ClientLogger interface {
 
 
 
-//*********************************************************************************************
-// Definition of a production-level client activity logger that logs both on the 
-//   terminal/standard output and a file.
-type ClientLoggingObjectPROD struct {
-	// Log only current client session activity on standard output
-	terminalLogger   log.Logger
-	// Log all client sessions' history on a file
-	clientFileLogger log.Logger
-	// Log file that the clientFileLogger operates on.  Stored in this field 
-	//   so that it can be properly closed when client app is exited.
-	clientLogFile    *os.File
+//*************************************************************************************************
+// Definition of a client activity logger that logs through the structured logging package shared
+//   with the server, to every destination (terminal, log files) and in the format of its logging
+//   configuration.  The key/value pair of each ClientLogger call is logged as an attribute of the
+//   entry.
+type ClientLoggingObject struct {
+	logger *logging.Logger
+}
+
+// Constructor function that creates a client activity logger from the given logging configuration.
+func NewClientLoggingObject(config logging.Config) (*ClientLoggingObject, error) {
+	logger, err := logging.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientLoggingObject{logger: logger}, nil
+}
+
+// Function that returns the default logging configuration of a production-level client (INFO-level
+//   and above) or development-level client (DEBUG-level and above), logging both on the terminal
+//   and the given client log file.
+func DefaultClientLogConfig(clientLogFilename string, isProd bool) logging.Config {
+	config := logging.Config{
+		Level:        "info",
+		Format:       logging.FormatLogfmt,
+		Destinations: []string{logging.StdoutDestination, clientLogFilename},
+	}
+	if !isProd {
+		config.Level = "debug"
+	}
+	return config
 }
 
 // Constructor function that creates a production-level logger for logging all client activity,
-//   INFO-level and above.
-func NewClientLoggingObjectPROD(clientLogFilename string) *ClientLoggingObjectPROD {
-	// Create terminal logger
-	terminalLogger := log.NewLogfmtLogger(os.Stdout)
-	terminalLogger = level.NewFilter(terminalLogger, level.AllowInfo())
-	terminalLogger = log.With(terminalLogger, "time", log.DefaultTimestampUTC)
-
-	// Add filepath to clientlogs directory to get full client log file path.
-	//   clientLogFilename = "github.com/astronomical3/fewer_grpc/client/clientlogs/production/" + clientLogFilename
-
-	// Create or open the client log file object
-	clientLogFile, err := os.OpenFile(clientLogFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+//   INFO-level and above, on both the terminal/stdout and a file.
+func NewClientLoggingObjectPROD(clientLogFilename string) *ClientLoggingObject {
+	clientLogger, err := NewClientLoggingObject(DefaultClientLogConfig(clientLogFilename, true))
 	if err != nil {
 		panic(err)
 	}
+	return clientLogger
+}
 
-	// Create the logger to operate logging operations on the client log file
-	clientFileLogger := log.NewLogfmtLogger(clientLogFile)
-	clientFileLogger = level.NewFilter(clientFileLogger, level.AllowInfo())
-	clientFileLogger = log.With(clientFileLogger, "time", log.DefaultTimestampUTC)
-
-	return &ClientLoggingObjectPROD{
-		terminalLogger:   terminalLogger,
-		clientFileLogger: clientFileLogger,
-		clientLogFile:    clientLogFile,
+// Constructor function that creates a development-level logger for logging all client activity,
+//   DEBUG-level and above, on both the terminal/stdout and a file.
+func NewClientLoggingObjectDEV(clientLogFilename string) *ClientLoggingObject {
+	clientLogger, err := NewClientLoggingObject(DefaultClientLogConfig(clientLogFilename, false))
+	if err != nil {
+		panic(err)
 	}
+	return clientLogger
 }
 
-// Method of the ClientLoggingObjectPROD that is used for simultaneously logging INFO-level
-//   activity on both the terminal and client log file.
-func (clop *ClientLoggingObjectPROD) ClientLogInfo(key, value, message string) {
-	level.Info(clop.terminalLogger).Log(key, value, "message", message)
-	level.Info(clop.clientFileLogger).Log(key, value, "message", message)
+// Method of the ClientLoggingObject that is used for logging DEBUG-level activity.
+func (clo *ClientLoggingObject) ClientLogDebug(key, value, message string) {
+	clo.logger.Debug(message, key, value)
 }
 
-// Method of the ClientLoggingObjectPROD that is used for simultaneously logging WARN-level
-//   activity on both the terminal and client log file.
-func (clop *ClientLoggingObjectPROD) ClientLogWarn(key, value, message string) {
-	level.Warn(clop.terminalLogger).Log(key, value, "message", message)
-	level.Warn(clop.clientFileLogger).Log(key, value, "message", message)
+// Method of the ClientLoggingObject that is used for logging INFO-level activity.
+func (clo *ClientLoggingObject) ClientLogInfo(key, value, message string) {
+	clo.logger.Info(message, key, value)
 }
 
-// Method of the ClientLoggingObjectPROD that is used for simultaneously logging ERROR-level
-//   activity on both the terminal and client log file.
-func (clop *ClientLoggingObjectPROD) ClientLogError(key, value, message string) {
-	level.Error(clop.terminalLogger).Log(key, value, "error", message)
-	level.Error(clop.clientFileLogger).Log(key, value, "error", message)
+// Method of the ClientLoggingObject that is used for logging WARN-level activity.
+func (clo *ClientLoggingObject) ClientLogWarn(key, value, message string) {
+	clo.logger.Warn(message, key, value)
 }
 
-// Method of the ClientLoggingObjectPROD that is used for closing the client log file properly.
-func (clop *ClientLoggingObjectPROD) Close() {
-	clop.clientLogFile.Close()
+// Method of the ClientLoggingObject that is used for logging ERROR-level activity.
+func (clo *ClientLoggingObject) ClientLogError(key, value, message string) {
+	clo.logger.Error(message, key, value)
+}
+
+// Method of the ClientLoggingObject that returns its underlying structured logger, for logging
+//   entries with any number of attributes (e.g., clo.Logger().Info("Received aggregate", "result",
+//   result, "window_index", index)), or changing its level.
+func (clo *ClientLoggingObject) Logger() *logging.Logger {
+	return clo.logger
+}
+
+// Method of the ClientLoggingObject that is used for closing the client log file properly when the
+//   client app is exited.
+func (clo *ClientLoggingObject) Close() {
+	clo.logger.Close()
 }
 
 
 
 //***********************************************************************************************************
-// Definition of a development-level client activity logger that logs both on the 
-//   terminal/standard output and a file.
-type ClientLoggingObjectDEV struct {
-	// Log only current session activity on standard output
-	terminalLogger   log.Logger
-	// Log all client sessions' history on a file
-	clientFileLogger log.Logger
-	// Log file that the clientFileLogger operates on.  Stored in this field
-	//   so that it can be properly closed when client app is exited.
-	clientLogFile    *os.File
-}
-
-// Constructor function that creates a production-level logger for logging all client
-//   activity, DEBUG-level and above.
-func NewClientLoggingObjectDEV(clientLogFilename string) *ClientLoggingObjectDEV{
-	// Create terminal logger
-	terminalLogger := log.NewLogfmtLogger(os.Stdout)
-	terminalLogger = level.NewFilter(terminalLogger, level.AllowDebug())
-	terminalLogger = log.With(terminalLogger, "time", log.DefaultTimestampUTC)
-
-	// Add filepath to clientlogs directory to get full client log file path.
-	//  clientLogFilename = "github.com/astronomical3/fewer_grpc/client/clientlogs/devtest/" + clientLogFilename
-
-	// Create or open the client log file object
-	clientLogFile, err := os.OpenFile(clientLogFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		panic (err)
-	}
+// Definition of a client activity logger that only passes warnings and errors on to another
+//   ClientLogger.  Used when many core clients share one logger (e.g., in load tests), where logging
+//   every response would flood the logs.  Closing it does not close the wrapped logger.
+type QuietClientLogger struct {
+	clientLogger ClientLogger
+}
+
+// Constructor function that creates a logger passing only warnings and errors to clientLogger.
+func NewQuietClientLogger(clientLogger ClientLogger) *QuietClientLogger {
+	return &QuietClientLogger{clientLogger: clientLogger}
+}
+
+// Methods of the QuietClientLogger that drop DEBUG- and INFO-level activity.
+func (cql *QuietClientLogger) ClientLogDebug(key, value, message string) {}
+func (cql *QuietClientLogger) ClientLogInfo(key, value, message string)  {}
+
+// Method of the QuietClientLogger that passes WARN-level activity to the wrapped logger.
+func (cql *QuietClientLogger) ClientLogWarn(key, value, message string) {
+	cql.clientLogger.ClientLogWarn(key, value, message)
+}
+
+// Method of the QuietClientLogger that passes ERROR-level activity to the wrapped logger.
+func (cql *QuietClientLogger) ClientLogError(key, value, message string) {
+	cql.clientLogger.ClientLogError(key, value, message)
+}
+
+// Method of the QuietClientLogger that leaves the wrapped logger open, as it is shared.
+func (cql *QuietClientLogger) Close() {}
 
-	// Create the logger to operate logging operations on the client log file
-	clientFileLogger := log.NewLogfmtLogger(clientLogFile)
-	clientFileLogger = level.NewFilter(clientFileLogger, level.AllowDebug())
-	clientFileLogger = log.With(clientFileLogger, "time", log.DefaultTimestampUTC)
 
-	return &ClientLoggingObjectDEV{
-		terminalLogger: terminalLogger,
-		clientFileLogger: clientFileLogger,
-		clientLogFile: clientLogFile,
+
+//*************************************************************************************************
+// Definition of one activity entry captured by a RecordingClientLogger.  Level is "debug", "info",
+//   "warn" or "error".
+type ClientLogRecord struct {
+	Level   string
+	Key     string
+	Value   string
+	Message string
+}
+
+// Definition of the subset of testing.TB used by the assertion helpers of a RecordingClientLogger,
+//   so that the client itself does not depend on the testing package.
+type LogAssertionT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Definition of a client activity logger that captures every entry in memory instead of writing it
+//   anywhere, for tests to inspect.  It is safe for concurrent use, and its entries stay readable
+//   after it is closed.
+type RecordingClientLogger struct {
+	mu      sync.Mutex
+	records []ClientLogRecord
+}
+
+// Constructor function that creates an empty in-memory client activity logger.
+func NewRecordingClientLogger() *RecordingClientLogger {
+	return &RecordingClientLogger{}
+}
+
+// Method of the RecordingClientLogger that captures DEBUG-level activity.
+func (rcl *RecordingClientLogger) ClientLogDebug(key, value, message string) {
+	rcl.record("debug", key, value, message)
+}
+
+// Method of the RecordingClientLogger that captures INFO-level activity.
+func (rcl *RecordingClientLogger) ClientLogInfo(key, value, message string) {
+	rcl.record("info", key, value, message)
+}
+
+// Method of the RecordingClientLogger that captures WARN-level activity.
+func (rcl *RecordingClientLogger) ClientLogWarn(key, value, message string) {
+	rcl.record("warn", key, value, message)
+}
+
+// Method of the RecordingClientLogger that captures ERROR-level activity.
+func (rcl *RecordingClientLogger) ClientLogError(key, value, message string) {
+	rcl.record("error", key, value, message)
+}
+
+// Method of the RecordingClientLogger that does nothing, as there is no file to close.
+func (rcl *RecordingClientLogger) Close() {}
+
+// Internal method of the RecordingClientLogger that captures one entry.
+func (rcl *RecordingClientLogger) record(level, key, value, message string) {
+	rcl.mu.Lock()
+	rcl.records = append(rcl.records, ClientLogRecord{Level: level, Key: key, Value: value, Message: message})
+	rcl.mu.Unlock()
+}
+
+// Method of the RecordingClientLogger that returns a copy of the entries captured so far.
+func (rcl *RecordingClientLogger) Records() []ClientLogRecord {
+	rcl.mu.Lock()
+	defer rcl.mu.Unlock()
+	return append([]ClientLogRecord(nil), rcl.records...)
+}
+
+// Method of the RecordingClientLogger that returns the captured entries of the given level (any
+//   level, if empty) whose message contains substring.
+func (rcl *RecordingClientLogger) Find(level, substring string) []ClientLogRecord {
+	var found []ClientLogRecord
+	for _, record := range rcl.Records() {
+		if (level == "" || record.Level == level) && strings.Contains(record.Message, substring) {
+			found = append(found, record)
+		}
 	}
+	return found
 }
 
-// Method of the ClientLoggingObjectDEV that is used for simultaneously logging DEBUG-level
-//   activity on both the terminal and client log file.  This is an operation only used when
-//   a ClientLoggingObjectDev for a ClientLogger.
-func (clod *ClientLoggingObjectDEV) ClientLogDebug(key, value, message string) {
-	level.Debug(clod.terminalLogger).Log(key, value, "message", message)
-	level.Debug(clod.clientFileLogger).Log(key, value, "message", message)
+// Method of the RecordingClientLogger that reports whether an entry of the given level (any level,
+//   if empty) was captured with a message containing substring.
+func (rcl *RecordingClientLogger) Contains(level, substring string) bool {
+	return len(rcl.Find(level, substring)) > 0
 }
 
-// Method of the ClientLoggingObjectDEV that is used for simultaneously logging INFO-level
-//   activity on both the terminal and client log file.
-func (clod *ClientLoggingObjectDEV) ClientLogInfo(key, value, message string) {
-	level.Info(clod.terminalLogger).Log(key, value, "message", message)
-	level.Info(clod.clientFileLogger).Log(key, value, "message", message)
+// Assertion helper of the RecordingClientLogger that fails the test if no entry of the given level
+//   was captured with a message containing substring.
+func (rcl *RecordingClientLogger) AssertLogged(t LogAssertionT, level, substring string) {
+	t.Helper()
+	if !rcl.Contains(level, substring) {
+		t.Errorf("client logged no %s entry containing %q; captured entries:\n%s", level, substring, rcl.dump())
+	}
 }
 
-// Method of the ClientLoggingObjectDEV that is used for simultaneously logging WARN-level
-//   activity on both the terminal and client log file.
-func (clod *ClientLoggingObjectDEV) ClientLogWarn(key, value, message string) {
-	level.Warn(clod.terminalLogger).Log(key, value, "message", message)
-	level.Warn(clod.clientFileLogger).Log(key, value, "message", message)
+// Assertion helper of the RecordingClientLogger that fails the test if an entry of the given level
+//   was captured with a message containing substring.
+func (rcl *RecordingClientLogger) AssertNotLogged(t LogAssertionT, level, substring string) {
+	t.Helper()
+	if found := rcl.Find(level, substring); len(found) > 0 {
+		t.Errorf("client logged unexpected %s entry containing %q: %+v", level, substring, found[0])
+	}
 }
 
-// Method of the ClientLoggingObjectDEV that is used for simultaneously logging ERROR-level
-//   activity on both the terminal and client log file.
-func (clod *ClientLoggingObjectDEV) ClientLogError(key, value, message string) {
-	level.Warn(clod.terminalLogger).Log(key, value, "error", message)
-	level.Warn(clod.clientFileLogger).Log(key, value, "error", message)
+// Assertion helper of the RecordingClientLogger that waits up to timeout for an entry of the given
+//   level, with a message containing substring, to be captured, and stops the test if none is.
+//   Used for entries that are logged asynchronously from the test's point of view.
+func (rcl *RecordingClientLogger) WaitForLogged(t LogAssertionT, level, substring string, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !rcl.Contains(level, substring) {
+		if time.Now().After(deadline) {
+			t.Fatalf("client logged no %s entry containing %q within %v; captured entries:\n%s", level, substring, timeout, rcl.dump())
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
 }
 
-// Method of the ClientLoggingObjectDEV that is used for closing the client log file properly.
-func (clod *ClientLoggingObjectDEV) Close() {
-	clod.clientLogFile.Close()
-}
\ No newline at end of file
+// Internal method of the RecordingClientLogger that formats every captured entry, one per line, for
+//   failure messages.
+func (rcl *RecordingClientLogger) dump() string {
+	var b strings.Builder
+	for _, record := range rcl.Records() {
+		fmt.Fprintf(&b, "  level=%s %s=%s message=%q\n", record.Level, record.Key, record.Value, record.Message)
+	}
+	return b.String()
+}
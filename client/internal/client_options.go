@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// Definition of an option of a CoreFewerSrvClient, given to its constructor or AddOptions(), which
+//   adds to the interceptors, stats handlers or dial options of its connection to the server.  The
+//   built-in features of the client (transport credentials, bearer token authentication and
+//   keepalive) are added through the same options, ahead of the ones given to the client.
+type Option func(*connectionOptions)
+
+// Definition of everything the options of a CoreFewerSrvClient add to its connection.
+type connectionOptions struct {
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	statsHandlers      []stats.Handler
+	dialOptions        []grpc.DialOption
+}
+
+// Function that returns an option adding unary interceptors to the client.  Interceptors run in
+//   the order they are added.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *connectionOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// Function that returns an option adding stream interceptors to the client.  Interceptors run in
+//   the order they are added.
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(o *connectionOptions) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// Function that returns an option adding stats handlers (e.g., for metrics or tracing) to the
+//   client.
+func WithStatsHandlers(handlers ...stats.Handler) Option {
+	return func(o *connectionOptions) {
+		o.statsHandlers = append(o.statsHandlers, handlers...)
+	}
+}
+
+// Function that returns an option adding options used when dialing up to the server (e.g., a
+//   custom dialer for an in-memory connection).  Interceptors and stats handlers should be added
+//   with the other options instead, so that they are chained with the rest.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *connectionOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// Helper function that applies the given options, in order, and returns the dial options they add
+//   up to.
+func buildDialOptions(options []Option) []grpc.DialOption {
+	var o connectionOptions
+	for _, option := range options {
+		option(&o)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(o.unaryInterceptors...),
+		grpc.WithChainStreamInterceptor(o.streamInterceptors...),
+	}
+	for _, handler := range o.statsHandlers {
+		opts = append(opts, grpc.WithStatsHandler(handler))
+	}
+	return append(opts, o.dialOptions...)
+}
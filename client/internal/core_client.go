@@ -2,15 +2,54 @@ package internal
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Default maximum number of inputs the core client sends to the Fewer Service before it waits
+//   for the service to acknowledge some of them.
+const DefaultMaxInFlight = 32
+
+// Default number of inputs the core client coalesces into one packed NumberRequest (1 sends every
+//   input in its own message), and default time it waits for more inputs before sending a packed
+//   request that is not full yet.
+const DefaultCoalesceSize = 1
+const DefaultCoalesceLinger = 5 * time.Millisecond
+
+// Default time the core client waits for a keepalive ping to be answered by the server, once
+//   keepalive pings are turned on.
+const DefaultKeepaliveTimeout = 20 * time.Second
+
+// Error wrapped by the errors of operations whose summary from the server does not match what the
+//   client sent and received, which means messages were lost along the way.
+var ErrSummaryMismatch = errors.New("summary from server does not match what the client counted")
+
+// Metadata key, and scheme of its value, through which the core client presents its bearer token
+//   to the server.
+const authorizationMetadataKey = "authorization"
+const bearerScheme = "Bearer "
+
+// Metadata key through which the core client presents its admin token to the FewerAdmin service.
+const adminAuthorizationMetadataKey = "fewer-admin-authorization"
+
 //***************************************************************************************************
 // Definition of a core client object that can be easily set up and used in different implementations
 //   of the Fewer Service Client Application (e.g., CLI, object included in a microservice).  This 
@@ -22,87 +61,312 @@ type CoreFewerSrvClient struct {
 	addrString   string
 	clientLogger ClientLogger
 	isProd       bool
+	// Maximum number of sent inputs that may be waiting on an acknowledgement from the server.
+	//   A value of 0 turns off client-side flow control.
+	maxInFlight  int
+	// Maximum number of inputs packed into one NumberRequest, and maximum time the first input of
+	//   a packed request waits for more inputs before the request is sent anyway.
+	coalesceSize   int
+	coalesceLinger time.Duration
+	// Key and tenant labels attached to every GetAggregatesStream() stream the client opens.
+	streamKey    string
+	tenant       string
+	// Validation rules of every aggregation call the client makes.
+	rules        wire.ValidationRules
+	// Reducer the Fewer Service applies to the batches of every aggregation call the client makes.
+	//   Empty for the server default, wire.SumReducer.
+	reducer      string
+	// Options adding interceptors, stats handlers or dial options to the connection to the server,
+	//   such as a custom dialer for an in-memory connection.
+	options      []Option
+	// Bearer token presented to the server on every call.  Empty to not authenticate.
+	authToken    string
+	// Admin token presented to the FewerAdmin service on admin calls.
+	adminToken   string
+	// Time after which the client pings a server connection it has heard nothing from (0 to not
+	//   ping), time it waits for the ping to be answered before closing the connection as dead,
+	//   and whether it pings while it has no open calls.
+	keepaliveTime       time.Duration
+	keepaliveTimeout    time.Duration
+	keepaliveWithoutRPC bool
+	// Whether the connection to the server is secured with TLS, and the PEM file of the CA
+	//   certificates the server's certificate is verified against.  Empty for the system roots.
+	useTLS       bool
+	tlsCAFile    string
 
 	// Obtained objects throughout connection and RPC execution process
 	rpcCred      credentials.TransportCredentials
 	grpcConn     *grpc.ClientConn
 	grpcClient   pb.FewerServiceClient
+	adminClient  pb.FewerAdminClient
 }
 
 // Constructor function for creating a new CoreFewerSrvClient that will dial up to the gRPC Fewer
-//   Service server and perform operations from the service.
-func NewCoreFewerSrvClient(address string, port int, clientLogger ClientLogger, isProd bool) *CoreFewerSrvClient {
-	// Create the TCP address out of the given address/hostname and port.
+//   Service server and perform operations from the service.  An address starting with "unix:"
+//   (e.g., unix:///run/fewer/fewer.sock) is the Unix domain socket of the server, and the port is
+//   ignored.  The options add to the interceptors, stats handlers and dial options of the
+//   connection to the server.
+func NewCoreFewerSrvClient(address string, port int, clientLogger ClientLogger, isProd bool, options ...Option) *CoreFewerSrvClient {
+	// Create the TCP address out of the given address/hostname and port, unless the server is
+	//   reached through a Unix domain socket, which gRPC dials by itself.
 	addrString := fmt.Sprintf("%s:%d", address, port)
+	if strings.HasPrefix(address, "unix:") {
+		addrString = address
+	}
 
 	return &CoreFewerSrvClient{
 		addrString:   addrString,
 		clientLogger: clientLogger,
 		isProd:       isProd,
+		maxInFlight:  DefaultMaxInFlight,
+		coalesceSize:   DefaultCoalesceSize,
+		coalesceLinger: DefaultCoalesceLinger,
+		options:        options,
 	}
 }
 
+// Method of the CoreFewerSrvClient for changing the maximum number of unacknowledged inputs the
+//   client keeps in flight during an operation.  A value of 0 turns off client-side flow control,
+//   letting the sender go as fast as the stream allows.
+func (c *CoreFewerSrvClient) SetMaxInFlight(maxInFlight int) {
+	if maxInFlight >= 0 {
+		c.maxInFlight = maxInFlight
+	}
+}
+
+// Method of the CoreFewerSrvClient for changing how inputs are coalesced into packed NumberRequest
+//   messages.  Up to coalesceSize inputs are sent in one message, cutting per-message overhead on
+//   high-throughput streams.  A packed message that is not full is sent once its first input has
+//   waited for coalesceLinger, or, with a linger of 0, as soon as no more inputs are ready.  A size
+//   of 1 sends every input in its own message.
+func (c *CoreFewerSrvClient) SetCoalescing(coalesceSize int, coalesceLinger time.Duration) {
+	if coalesceSize >= 1 {
+		c.coalesceSize = coalesceSize
+	}
+	if coalesceLinger >= 0 {
+		c.coalesceLinger = coalesceLinger
+	}
+}
+
+// Method of the CoreFewerSrvClient for labelling the GetAggregatesStream() streams it opens with a
+//   key and a tenant.  Empty labels are not sent.
+func (c *CoreFewerSrvClient) SetStreamLabels(streamKey, tenant string) {
+	c.streamKey = streamKey
+	c.tenant = tenant
+}
+
+// Method of the CoreFewerSrvClient for setting the reducer the Fewer Service applies to the batches
+//   of inputs of its aggregation calls (e.g., wire.MinReducer).  A reducer the server does not
+//   allow fails the call with a *ValidationError.  Empty leaves the reducer to the server.
+func (c *CoreFewerSrvClient) SetReducer(reducer string) {
+	c.reducer = reducer
+}
+
+// Method of the CoreFewerSrvClient for setting the validation rules the Fewer Service checks the
+//   inputs of its aggregation calls against.  The skipped and clamped inputs are counted in every
+//   aggregate, and in the summary of a batch or upload.  An input rejected by the fail policy ends
+//   the call with a *ValidationError.
+func (c *CoreFewerSrvClient) SetValidationRules(rules wire.ValidationRules) {
+	c.rules = rules
+}
+
+// Method of the CoreFewerSrvClient for adding options to its connection to the server, after the
+//   ones it was created with.  Must be called before ConnectToServer().
+func (c *CoreFewerSrvClient) AddOptions(options ...Option) {
+	c.options = append(c.options, options...)
+}
+
+// Method of the CoreFewerSrvClient for setting the bearer token it presents to the server on every
+//   call, for servers that authenticate their clients.  Must be called before ConnectToServer().
+func (c *CoreFewerSrvClient) SetAuthToken(authToken string) {
+	c.authToken = authToken
+}
+
+// Method of the CoreFewerSrvClient for setting the admin token it presents to the FewerAdmin service
+//   of the server on admin calls (see PerformListStreamsOp()).  It is only sent on those calls.
+func (c *CoreFewerSrvClient) SetAdminToken(adminToken string) {
+	c.adminToken = adminToken
+}
+
+// Method of the CoreFewerSrvClient for turning on keepalive pings to the server, so that a
+//   connection that died silently (e.g., behind a NAT) is detected, and its calls fail, after
+//   keepaliveTime + keepaliveTimeout.  gRPC pings at most every 10 seconds, and the server may
+//   disconnect clients that ping more often than it allows.  A keepaliveTime of 0 turns pings off.
+//   Must be called before ConnectToServer().
+func (c *CoreFewerSrvClient) SetKeepalive(keepaliveTime, keepaliveTimeout time.Duration, permitWithoutStream bool) {
+	c.keepaliveTime = keepaliveTime
+	c.keepaliveTimeout = keepaliveTimeout
+	c.keepaliveWithoutRPC = permitWithoutStream
+}
+
+// Method of the CoreFewerSrvClient for securing its connection to the server with TLS.  The
+//   server's certificate is verified against the CA certificates of caFile (PEM), or against the
+//   system roots if caFile is empty.  Must be called before ConnectToServer().
+func (c *CoreFewerSrvClient) SetTLS(caFile string) {
+	c.useTLS = true
+	c.tlsCAFile = caFile
+}
+
 // Method of the CoreFewerSrvClient for dialing up to the gRPC Fewer Service server app and receiving
 //   a client stub to the service.
 func (c *CoreFewerSrvClient) ConnectToServer() error {
 	// Get RPC credentials to use for dialing up to the Fewer Service server app.
 	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.ConnectToServer", "Obtaining credentials for connecting core client to Fewer Service server...")
-	if c.isProd {
-		// NOTE: Right now, log a warning that this is not secure and that secure credentials for production
-		//   environment clients need to be implemented.
-		c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.ConnectToServer", "Right now, insecure credentials will be used.  Working on implementing secure credentials for production environment operations...")
+	if c.useTLS {
+		creds, err := clientTLSCredentials(c.tlsCAFile)
+		if err != nil {
+			c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.ConnectToServer", fmt.Sprintf("Client failed to obtain TLS credentials: %v", err))
+			return err
+		}
+		c.rpcCred = creds
+	} else {
+		if c.isProd && !c.isUnixSocket() {
+			c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.ConnectToServer", "Insecure credentials will be used.  Turn on TLS to secure the connection to the server in production environment operations...")
+		}
+		c.rpcCred = insecure.NewCredentials()
 	}
-	c.rpcCred = insecure.NewCredentials()
 
 	// Dial up to the Fewer Service server app
 	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.ConnectToServer", fmt.Sprintf("Connecting core client object to Fewer Service server at address %s...", c.addrString))
 
+	// The built-in options of the client come first, followed by the ones given to it.  Errors of
+	//   calls are decoded from their status details into typed errors (see RPCError).
 	var err error
-	c.grpcConn, err = grpc.NewClient(c.addrString, grpc.WithTransportCredentials(c.rpcCred))
+	options := []Option{
+		WithDialOptions(grpc.WithTransportCredentials(c.rpcCred)),
+		WithUnaryInterceptors(decodeErrorsUnaryInterceptor),
+		WithStreamInterceptors(decodeErrorsStreamInterceptor),
+	}
+	if c.authToken != "" {
+		options = append(options, WithDialOptions(grpc.WithPerRPCCredentials(bearerTokenCredentials{token: c.authToken, requireTLS: !c.isUnixSocket()})))
+	}
+	if c.keepaliveTime > 0 {
+		options = append(options, WithDialOptions(grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.keepaliveTime,
+			Timeout:             c.keepaliveTimeout,
+			PermitWithoutStream: c.keepaliveWithoutRPC,
+		})))
+	}
+	options = append(options, c.options...)
+	c.grpcConn, err = grpc.NewClient(c.addrString, buildDialOptions(options)...)
 	if err != nil {
 		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.ConnectToServer", fmt.Sprintf("Client failed to connect to server with grpc.NewClient: %v", err))
 		return err
 	}
 
-	// Create a client stub to the Fewer Service.
+	// Create client stubs to the Fewer Service and to the FewerAdmin service.
 	c.grpcClient = pb.NewFewerServiceClient(c.grpcConn)
+	c.adminClient = pb.NewFewerAdminClient(c.grpcConn)
 
 	// An error of nil indicates that the client to the Fewer Service has been successfully made.
 	return nil
 }
 
 // Method of the CoreFewerSrvClient that actually performs the operation of sending over to the Fewer Service server app
-//   a bunch of pb.NumberRequest input messages, and receiving back pb.NumberResponse messages containing a sum of the
-//   latest 3 inputs sent.
+//   a bunch of pb.NumberRequest input messages, and receiving back pb.NumberResponse messages containing the aggregate
+//   of every batch of inputs sent, whose size is set by the server, reduced as the client's reducer asks (the sum of
+//   the batch by default).
+// The stream ends with the server's summary of it, which is checked against what the client sent and received, and
+//   any mismatch is returned as an error wrapping ErrSummaryMismatch.
 // This can be performed multiple times with the same client, by simply calling this function every time an operation is
 //   requested.
 func (c *CoreFewerSrvClient) PerformGetAggregatesOp(totalInputs int) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	return c.performGetAggregatesStream(context.Background(), feedSequentialInputs(totalInputs, stop), nil)
+}
+
+// Method of the CoreFewerSrvClient that performs the same operation as PerformGetAggregatesOp(), but
+//   can be cancelled through ctx, and returns the aggregates received back from the server (up to the
+//   point of failure, if the operation fails).
+func (c *CoreFewerSrvClient) PerformGetAggregatesOpWithContext(ctx context.Context, totalInputs int) ([]*pb.NumberResponse, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Only the receiver goroutine appends to aggregates, and the operation has returned by the time
+	//   they are read.
+	var aggregates []*pb.NumberResponse
+	observer := &streamObserver{
+		aggregateReceived: func(aggregate *pb.NumberResponse) {
+			aggregates = append(aggregates, aggregate)
+		},
+	}
+	err := c.performGetAggregatesStream(ctx, feedSequentialInputs(totalInputs, stop), observer)
+	return aggregates, err
+}
+
+// Helper function that feeds the numbers 1 to totalInputs to an operation through the returned
+//   channel, which is closed once all of them were taken.  The feeding goroutine is stopped if stop
+//   is closed, in case the operation ends before taking all of them.
+func feedSequentialInputs(totalInputs int, stop <-chan struct{}) <-chan int32 {
+	inputs := make(chan int32)
+	go func() {
+		defer close(inputs)
+		for i := 1; i <= totalInputs; i++ {
+			select {
+			case inputs <- int32(i):
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return inputs
+}
+
+// Definition of an observer of a GetAggregatesStream() stream, whose functions (if not nil) are
+//   called as inputs are sent and aggregates are received.  inputsSent is called from the sender
+//   goroutine with the total number of inputs sent so far, after each request is sent.
+//   aggregateReceived is called from the receiver goroutine for each aggregate.
+type streamObserver struct {
+	inputsSent        func(sentInputs int64)
+	aggregateReceived func(aggregate *pb.NumberResponse)
+}
+
+// Internal method of the CoreFewerSrvClient that performs the GetAggregatesStream() RPC, sending
+//   over every input received on the inputs channel until it is closed, or until parentCtx is done.
+//   The observer may be nil.
+func (c *CoreFewerSrvClient) performGetAggregatesStream(parentCtx context.Context, inputs <-chan int32, observer *streamObserver) error {
 	// Create a done channel that will receive a close signal once the receiver
 	//   goroutine in this operation has received all responses at end of operation.
 	done := make(chan struct{})
 
+	// Create a cancellable context for the operation, so that the sender goroutine
+	//   is released if the operation ends while it is waiting on an acknowledgement.
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+	if c.maxInFlight > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, wire.MaxInFlightMetadataKey, strconv.Itoa(c.maxInFlight))
+	}
+	ctx = c.labelledContext(ctx)
+
 	// Create a stream, numStream, through which the client will send NumberRequest
 	//   messages to the Fewer Service Server through.
-	numStream, err := c.grpcClient.GetAggregatesStream(context.Background())
+	numStream, err := c.grpcClient.GetAggregatesStream(ctx)
 	if err != nil {
 		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Failure to open stream using GetAggregatesStream RPC: %v", err))
 		return err
 	}
 
+	// Keep track of the number of inputs the server has acknowledged.  The receiver
+	//   goroutine stores every new acknowledgement and signals the sender goroutine
+	//   through ackSignal, in case it is waiting for its in-flight window to open up.
+	var ackedInputs atomic.Int64
+	ackSignal := make(chan struct{}, 1)
+
+	// Keep track of what was sent and received, to check it against the summary of the stream
+	//   the server ends it with.  Only the receiver goroutine touches the received counts and the
+	//   summary, until it closes done.
+	var sentInputs atomic.Int64
+	var receivedAggregates, receivedTotal int64
+	var summary *pb.AggregationSummary
+
 	// Start up a sender goroutine that sends NumberRequest messages to the Fewer
 	//   Service server via the opened numStream.
-	go func() {
-		for i := 1; i <= totalInputs; i++ {
-			if err := numStream.Send(&pb.NumberRequest{InputNum: int32(i)}); err != nil {
-				c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Failed to send NumberRequest to server through numStream at request %d: %v", (i + 1), err))
-				return
-			}
-		}
-		numStream.CloseSend()
-	}()
+	go c.sendInputs(ctx, numStream, inputs, &ackedInputs, ackSignal, &sentInputs, observer)
 
 	// Start up a concurrent receiver goroutine that will receive some responses
-	//   from the Fewer Service every 3 NumberRequest sends.
+	//   from the Fewer Service every 3 NumberRequest sends, as well as the
+	//   acknowledgements of the inputs the service has processed.
 	// Also, create a recvErr channel that will return the error of the receive
 	//   operation.
 	recvErr := make(chan error)
@@ -124,7 +388,27 @@ func (c *CoreFewerSrvClient) PerformGetAggregatesOp(totalInputs int) error {
 				recvErr <- err
 				return
 			}
-			c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Received response from Fewer Service server: %v", resp))
+			switch payload := resp.Payload.(type) {
+			case *pb.AggregatesStreamResponse_Aggregate:
+				receivedAggregates++
+				receivedTotal += int64(payload.Aggregate.Result)
+				if observer != nil && observer.aggregateReceived != nil {
+					observer.aggregateReceived(payload.Aggregate)
+				}
+				c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Received response from Fewer Service server: %v", payload.Aggregate))
+			case *pb.AggregatesStreamResponse_Ack:
+				ackedInputs.Store(payload.Ack.ProcessedInputs)
+				select {
+				case ackSignal <- struct{}{}:
+				default:
+				}
+				c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Fewer Service server acknowledged %d processed inputs", payload.Ack.ProcessedInputs))
+			case *pb.AggregatesStreamResponse_Summary:
+				summary = payload.Summary
+				c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Received stream summary from Fewer Service server: %v", payload.Summary))
+			default:
+				c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Received response with unknown payload from Fewer Service server: %v", resp))
+			}
 		}
 	}()
 
@@ -132,19 +416,389 @@ func (c *CoreFewerSrvClient) PerformGetAggregatesOp(totalInputs int) error {
 	<-done
 	
 	// Get the final error of the receive operation from the receiving goroutine.
-	//   If the final error was not nil, return the actual error. Otherwise, the
-	//   method returns nil, indicating the entire operation was successful.
+	//   If the final error was not nil, return the actual error, after logging how far
+	//   the server got according to the summary in the trailer of the stream.
 	finalErr := <-recvErr
 	if finalErr != nil {
+		if reason := numStream.Trailer().Get(wire.CancelReasonTrailerKey); len(reason) > 0 {
+			c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Fewer Service server cancelled the stream (reason: %q)", reason[0]))
+		}
+		if trailerSummary := summaryFromTrailer(numStream.Trailer()); trailerSummary != nil {
+			c.clientLogger.ClientLogInfo(
+				"method",
+				"CoreFewerSrvClient.PerformGetAggregatesOp",
+				fmt.Sprintf("Fewer Service server aggregated %d inputs into %d batches before the stream failed", trailerSummary.TotalInputs, trailerSummary.TotalBatches),
+			)
+		}
 		return finalErr
 	}
 
-	return nil
+	// Otherwise, check the summary of the stream (from its last message, or else from its
+	//   trailer) against what was sent and received, to catch lost messages.
+	if summary == nil {
+		summary = summaryFromTrailer(numStream.Trailer())
+	}
+	if summary == nil {
+		c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformGetAggregatesOp", "Fewer Service server did not send a summary of the stream, so it cannot be checked")
+		return nil
+	}
+	return c.checkSummary("CoreFewerSrvClient.PerformGetAggregatesOp", summary, sentInputs.Load(), receivedAggregates, receivedTotal)
+}
+
+// Helper function that decodes the summary of a GetAggregatesStream() stream from its trailer, or
+//   returns nil if the trailer does not hold one.
+func summaryFromTrailer(trailer metadata.MD) *pb.AggregationSummary {
+	values := trailer.Get(wire.SummaryTrailerKey)
+	if len(values) == 0 {
+		return nil
+	}
+	summary := &pb.AggregationSummary{}
+	if err := proto.Unmarshal([]byte(values[0]), summary); err != nil {
+		return nil
+	}
+	return summary
+}
+
+// Internal method of the CoreFewerSrvClient that checks the summary of an operation from the server
+//   against the numbers of inputs the client sent, and of aggregates it received along with their
+//   total.  A negative number of aggregates leaves the aggregates unchecked, for operations that do
+//   not receive them.  Mismatches are logged, and returned as an error wrapping ErrSummaryMismatch.
+func (c *CoreFewerSrvClient) checkSummary(method string, summary *pb.AggregationSummary, sentInputs, receivedAggregates, receivedTotal int64) error {
+	var mismatches []string
+	if summary.TotalInputs != sentInputs {
+		mismatches = append(mismatches, fmt.Sprintf("server received %d inputs, client sent %d", summary.TotalInputs, sentInputs))
+	}
+	if receivedAggregates >= 0 && summary.TotalBatches != receivedAggregates {
+		mismatches = append(mismatches, fmt.Sprintf("server sent %d aggregates, client received %d", summary.TotalBatches, receivedAggregates))
+	}
+	if receivedAggregates >= 0 && summary.GrandTotal != receivedTotal {
+		mismatches = append(mismatches, fmt.Sprintf("server grand total is %d, client received a total of %d", summary.GrandTotal, receivedTotal))
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%w: %s", ErrSummaryMismatch, strings.Join(mismatches, "; "))
+	c.clientLogger.ClientLogError("method", method, err.Error())
+	return err
+}
+
+// Internal method of the CoreFewerSrvClient that runs the sender side of a GetAggregatesStream()
+//   stream.  Inputs are coalesced into packed NumberRequest messages of up to coalesceSize inputs,
+//   and no more than maxInFlight inputs are left unacknowledged by the server.  The stream is
+//   closed for sending once the inputs channel is closed and every input has been sent.  The number
+//   of inputs sent so far is kept in sentCount.
+func (c *CoreFewerSrvClient) sendInputs(ctx context.Context, numStream pb.FewerService_GetAggregatesStreamClient, inputs <-chan int32, ackedInputs *atomic.Int64, ackSignal <-chan struct{}, sentCount *atomic.Int64, observer *streamObserver) {
+	var sentInputs int64
+	pending := make([]int32, 0, c.coalesceSize)
+	var lingerTimer *time.Timer
+	var lingerExpired <-chan time.Time
+
+	// Send the pending inputs in one request, reporting whether the send succeeded.
+	flush := func() bool {
+		if lingerTimer != nil {
+			lingerTimer.Stop()
+			lingerTimer, lingerExpired = nil, nil
+		}
+		if len(pending) == 0 {
+			return true
+		}
+		req := &pb.NumberRequest{}
+		if len(pending) == 1 {
+			req.InputNum = pending[0]
+		} else {
+			req.InputNums = append([]int32(nil), pending...)
+		}
+		if err := numStream.Send(req); err != nil {
+			c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformGetAggregatesOp", fmt.Sprintf("Failed to send NumberRequest to server through numStream at request %d: %v", sentInputs+1, err))
+			return false
+		}
+		sentInputs += int64(len(pending))
+		sentCount.Store(sentInputs)
+		pending = pending[:0]
+		if observer != nil && observer.inputsSent != nil {
+			observer.inputsSent(sentInputs)
+		}
+		return true
+	}
+
+	for {
+		// Wait while the max-in-flight window is full, so that a slow server applies
+		//   backpressure on the client instead of letting inputs pile up in buffers.  The
+		//   pending inputs are sent first, as the server cannot acknowledge them otherwise.
+		if c.maxInFlight > 0 && sentInputs+int64(len(pending))-ackedInputs.Load() >= int64(c.maxInFlight) {
+			if !flush() {
+				return
+			}
+			for sentInputs-ackedInputs.Load() >= int64(c.maxInFlight) {
+				select {
+				case <-ackSignal:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		// Take the next input.  With a linger of 0, a packed request is sent as soon as no
+		//   more inputs are ready; otherwise, it is sent once its first input has lingered.
+		var inputNum int32
+		var ok bool
+		if len(pending) > 0 && c.coalesceLinger == 0 {
+			select {
+			case inputNum, ok = <-inputs:
+			default:
+				if !flush() {
+					return
+				}
+				continue
+			}
+		} else {
+			select {
+			case inputNum, ok = <-inputs:
+			case <-lingerExpired:
+				if !flush() {
+					return
+				}
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if !ok {
+			if flush() {
+				numStream.CloseSend()
+			}
+			return
+		}
+		pending = append(pending, inputNum)
+		if len(pending) >= c.coalesceSize {
+			if !flush() {
+				return
+			}
+		} else if len(pending) == 1 && c.coalesceLinger > 0 {
+			lingerTimer = time.NewTimer(c.coalesceLinger)
+			lingerExpired = lingerTimer.C
+		}
+	}
+}
+
+// Internal method of the CoreFewerSrvClient that returns the context of a new aggregation RPC,
+//   carrying the client's stream labels, reducer and validation rules as metadata.
+func (c *CoreFewerSrvClient) labelledContext(ctx context.Context) context.Context {
+	if c.streamKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, wire.StreamKeyMetadataKey, c.streamKey)
+	}
+	if c.tenant != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, wire.TenantMetadataKey, c.tenant)
+	}
+	if c.reducer != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, wire.ReducerMetadataKey, c.reducer)
+	}
+	if pairs := c.rules.MetadataPairs(); len(pairs) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	}
+	return ctx
+}
+
+// Method of the CoreFewerSrvClient that performs the unary AggregateBatch() RPC, sending over all
+//   of the given numbers at once, and receiving back all of their aggregates in one response.  The
+//   summary of the response is checked against them, as with PerformGetAggregatesOp().
+func (c *CoreFewerSrvClient) PerformAggregateBatchOp(inputNums []int32) (*pb.AggregateBatchResponse, error) {
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformAggregateBatchOp", fmt.Sprintf("Sending batch of %d numbers to Fewer Service server...", len(inputNums)))
+	resp, err := c.grpcClient.AggregateBatch(c.labelledContext(context.Background()), &pb.AggregateBatchRequest{InputNums: inputNums})
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformAggregateBatchOp", fmt.Sprintf("AggregateBatch RPC failed: %v", err))
+		return nil, err
+	}
+	var receivedTotal int64
+	for _, result := range resp.Results {
+		receivedTotal += int64(result.Result)
+		c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformAggregateBatchOp", fmt.Sprintf("Received response from Fewer Service server: %v", result))
+	}
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformAggregateBatchOp", fmt.Sprintf("Received batch summary from Fewer Service server: %v", resp.Summary))
+	return resp, c.checkSummary("CoreFewerSrvClient.PerformAggregateBatchOp", resp.Summary, int64(len(inputNums)), int64(len(resp.Results)), receivedTotal)
+}
+
+// Method of the CoreFewerSrvClient that performs the client-streaming AggregateUpload() RPC,
+//   streaming the given numbers over to the Fewer Service server, and receiving back a single
+//   summary of their aggregation, whose number of inputs is checked against the numbers sent.
+//   Numbers are packed into messages per the client's coalescing size.
+func (c *CoreFewerSrvClient) PerformAggregateUploadOp(inputNums []int32) (*pb.AggregationSummary, error) {
+	uploadStream, err := c.grpcClient.AggregateUpload(c.labelledContext(context.Background()))
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformAggregateUploadOp", fmt.Sprintf("Failure to open stream using AggregateUpload RPC: %v", err))
+		return nil, err
+	}
+	// All of the numbers are at hand, so they are packed into messages of up to coalesceSize
+	//   numbers right away, without lingering.
+	for start := 0; start < len(inputNums); start += c.coalesceSize {
+		end := min(start+c.coalesceSize, len(inputNums))
+		req := &pb.NumberRequest{}
+		if end-start == 1 {
+			req.InputNum = inputNums[start]
+		} else {
+			req.InputNums = inputNums[start:end]
+		}
+		if err := uploadStream.Send(req); err != nil {
+			// The actual error of the RPC is returned by CloseAndRecv below.
+			c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformAggregateUploadOp", fmt.Sprintf("Failed to send NumberRequest to server at request %d: %v", start+1, err))
+			break
+		}
+	}
+	summary, err := uploadStream.CloseAndRecv()
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformAggregateUploadOp", fmt.Sprintf("AggregateUpload RPC failed: %v", err))
+		return nil, err
+	}
+	c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformAggregateUploadOp", fmt.Sprintf("Received upload summary from Fewer Service server: %v", summary))
+	return summary, c.checkSummary("CoreFewerSrvClient.PerformAggregateUploadOp", summary, int64(len(inputNums)), -1, 0)
+}
+
+// Definition of a query for aggregates that the Fewer Service server has persisted.  Empty filters
+//   match every aggregate.
+type AggregateQuery struct {
+	StreamID      string
+	Key           string
+	// Only aggregates emitted at or after EmittedAfter, and before EmittedBefore, are returned.
+	//   Zero times leave that end of the range open.
+	EmittedAfter  time.Time
+	EmittedBefore time.Time
+	// Maximum number of aggregates per page streamed back by the server (0 for server default).
+	PageSize      int
+	// Token of the page to resume from, as returned by a previous query.
+	PageToken     string
+	// Maximum number of aggregates to return in total (0 for no limit).
+	Limit         int
+}
+
+// Method of the CoreFewerSrvClient that performs the QueryAggregates() RPC, reading back the
+//   aggregates persisted by the Fewer Service server that match the given query.  It returns the
+//   aggregates, along with the token to resume after the last of them (empty if there are no more
+//   matching aggregates).
+func (c *CoreFewerSrvClient) PerformQueryAggregatesOp(query AggregateQuery) ([]*pb.StoredAggregate, string, error) {
+	req := &pb.QueryAggregatesRequest{
+		StreamId:  query.StreamID,
+		Key:       query.Key,
+		PageSize:  int32(query.PageSize),
+		PageToken: query.PageToken,
+		Limit:     int32(query.Limit),
+	}
+	if !query.EmittedAfter.IsZero() {
+		req.EmittedAfter = timestamppb.New(query.EmittedAfter)
+	}
+	if !query.EmittedBefore.IsZero() {
+		req.EmittedBefore = timestamppb.New(query.EmittedBefore)
+	}
+
+	pageStream, err := c.grpcClient.QueryAggregates(context.Background(), req)
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformQueryAggregatesOp", fmt.Sprintf("Failure to open stream using QueryAggregates RPC: %v", err))
+		return nil, "", err
+	}
+
+	var aggregates []*pb.StoredAggregate
+	var nextPageToken string
+	for {
+		page, err := pageStream.Recv()
+		if err == io.EOF {
+			c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformQueryAggregatesOp", fmt.Sprintf("Received all %d matching aggregates", len(aggregates)))
+			return aggregates, nextPageToken, nil
+		}
+		if err != nil {
+			c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformQueryAggregatesOp", fmt.Sprintf("Failed to receive a page of aggregates: %v", err))
+			return aggregates, nextPageToken, err
+		}
+		for _, aggregate := range page.Aggregates {
+			c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformQueryAggregatesOp", fmt.Sprintf("Received stored aggregate from Fewer Service server: %v", aggregate))
+		}
+		aggregates = append(aggregates, page.Aggregates...)
+		nextPageToken = page.NextPageToken
+	}
+}
+
+// Method of the CoreFewerSrvClient that performs the SubscribeAggregates() RPC, watching the
+//   aggregates the Fewer Service emits on other clients' GetAggregatesStream() streams that match
+//   the given request.  Every event is logged, and handed to onEvent if it is not nil.  The
+//   subscription lasts until ctx is cancelled (which is not treated as an error) or the server
+//   ends it.
+func (c *CoreFewerSrvClient) PerformSubscribeAggregatesOp(ctx context.Context, req *pb.SubscribeAggregatesRequest, onEvent func(*pb.AggregateEvent)) error {
+	eventStream, err := c.grpcClient.SubscribeAggregates(ctx, req)
+	if err != nil {
+		c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", fmt.Sprintf("Failure to open stream using SubscribeAggregates RPC: %v", err))
+		return err
+	}
+
+	for {
+		event, err := eventStream.Recv()
+		if err == io.EOF {
+			c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", "Server ended the subscription")
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", "Subscription cancelled")
+				return nil
+			}
+			c.clientLogger.ClientLogError("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", fmt.Sprintf("Failed to receive an event: %v", err))
+			return err
+		}
+		if event.DroppedBefore > 0 {
+			c.clientLogger.ClientLogWarn("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", fmt.Sprintf("Server dropped %d events before this one, as the subscriber was too slow", event.DroppedBefore))
+		}
+		c.clientLogger.ClientLogInfo("method", "CoreFewerSrvClient.PerformSubscribeAggregatesOp", fmt.Sprintf("Received aggregate event from Fewer Service server: %v", event))
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
 }
 
 // Method of the CoreFewerSrvClient for closing the client's resources (the gRPC 
 //   connection, the client log file used by the attached clientLogger, etc.)
 func (c *CoreFewerSrvClient) Close() {
-	c.grpcConn.Close()
+	if c.grpcConn != nil {
+		c.grpcConn.Close()
+	}
 	c.clientLogger.Close()
-}
\ No newline at end of file
+}
+
+// Internal method of the CoreFewerSrvClient that tells whether it reaches the server through a Unix
+//   domain socket, which only local processes can listen in on.
+func (c *CoreFewerSrvClient) isUnixSocket() bool {
+	return strings.HasPrefix(c.addrString, "unix:")
+}
+
+// Helper function that returns the TLS transport credentials of a core client, verifying the
+//   server's certificate against the CA certificates of caFile, or the system roots if it is empty.
+func clientTLSCredentials(caFile string) (credentials.TransportCredentials, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS CA file: %w", err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in TLS CA file %s", caFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+
+//***************************************************************************************************
+// Definition of the per-call credentials presenting the bearer token of a core client to the server.
+type bearerTokenCredentials struct {
+	token      string
+	// Whether the token may only be sent over TLS, which is the case unless the server is reached
+	//   through a Unix domain socket.
+	requireTLS bool
+}
+
+func (b bearerTokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationMetadataKey: bearerScheme + b.token}, nil
+}
+
+func (b bearerTokenCredentials) RequireTransportSecurity() bool {
+	return b.requireTLS
+}
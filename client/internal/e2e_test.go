@@ -0,0 +1,641 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/client/internal"
+	"github.com/astronomical3/fewer_grpc/client/internal/testharness"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
+	"github.com/astronomical3/fewer_grpc/server/fewerserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// End-to-end test of PerformGetAggregatesOp() against an in-process server, sending the numbers 1 to
+//   totalInputs and checking the aggregates received back, as well as how both sides logged the end
+//   of the stream.
+func TestGetAggregatesEndToEnd(t *testing.T) {
+	tests := []struct {
+		name        string
+		totalInputs int
+		// If set, the operation is cancelled this long after it starts.
+		cancelAfter time.Duration
+		wantResults []int32
+		wantCode    codes.Code
+		// Entry the server must log (at the given level) by the end of the stream.
+		wantServerLevel string
+		wantServerLog   string
+	}{
+		{
+			name:            "exact multiple of batch size",
+			totalInputs:     9,
+			wantResults:     []int32{6, 15, 24},
+			wantServerLevel: "info",
+			wantServerLog:   "No leftover data after final sum",
+		},
+		{
+			name:            "residual of one input",
+			totalInputs:     10,
+			wantResults:     []int32{6, 15, 24, 10},
+			wantServerLevel: "warn",
+			wantServerLog:   "Leftover data not reported in last returned sum.  Actual final sum is 10.",
+		},
+		{
+			name:            "residual of two inputs",
+			totalInputs:     11,
+			wantResults:     []int32{6, 15, 24, 21},
+			wantServerLevel: "warn",
+			wantServerLog:   "Leftover data not reported in last returned sum.  Actual final sum is 21.",
+		},
+		{
+			name:            "zero inputs",
+			totalInputs:     0,
+			wantResults:     nil,
+			wantServerLevel: "info",
+			wantServerLog:   "No leftover data after final sum",
+		},
+		{
+			name:            "client cancellation",
+			totalInputs:     1 << 30,
+			cancelAfter:     50 * time.Millisecond,
+			wantCode:        codes.Canceled,
+			// The cancellation reaches the server either while it receives or while it sends.
+			wantServerLevel: "error",
+			wantServerLog:   "Could not ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testharness.Start(t)
+			ctx := context.Background()
+			if tt.cancelAfter > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				timer := time.AfterFunc(tt.cancelAfter, cancel)
+				defer timer.Stop()
+			}
+
+			aggregates, err := h.Client.PerformGetAggregatesOpWithContext(ctx, tt.totalInputs)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("PerformGetAggregatesOpWithContext() error = %v, want code %v", err, tt.wantCode)
+			}
+			h.ServerLog.WaitForLogged(t, tt.wantServerLevel, tt.wantServerLog, testharness.WaitTimeout)
+			if tt.wantCode != codes.OK {
+				h.ClientLog.AssertLogged(t, "error", "Failed to receive a response")
+				return
+			}
+
+			if len(aggregates) != len(tt.wantResults) {
+				t.Fatalf("received %d aggregates %v, want results %v", len(aggregates), aggregates, tt.wantResults)
+			}
+			for i, aggregate := range aggregates {
+				if aggregate.Result != tt.wantResults[i] {
+					t.Errorf("aggregate %d result = %d, want %d", i, aggregate.Result, tt.wantResults[i])
+				}
+				if aggregate.WindowIndex != int64(i) {
+					t.Errorf("aggregate %d window index = %d, want %d", i, aggregate.WindowIndex, i)
+				}
+			}
+			if len(aggregates) > 0 {
+				if last := aggregates[len(aggregates)-1]; last.LastInput != int64(tt.totalInputs) {
+					t.Errorf("last aggregate covers inputs up to %d, want %d", last.LastInput, tt.totalInputs)
+				}
+			}
+		})
+	}
+}
+
+// End-to-end test of how the server handles a stream whose client goes away mid-stream: the receive
+//   error is logged with the iteration it happened at, and the residual batch is never reported.
+func TestGetAggregatesServerReceiveError(t *testing.T) {
+	tests := []struct {
+		name       string
+		inputsSent int
+	}{
+		{name: "mid-batch", inputsSent: 4},
+		{name: "on batch boundary", inputsSent: 6},
+		{name: "before any input", inputsSent: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testharness.Start(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stream, err := h.NewStub(t).GetAggregatesStream(ctx)
+			if err != nil {
+				t.Fatalf("GetAggregatesStream() error = %v", err)
+			}
+			for i := 1; i <= tt.inputsSent; i++ {
+				if err := stream.Send(&pb.NumberRequest{InputNum: int32(i)}); err != nil {
+					t.Fatalf("Send(%d) error = %v", i, err)
+				}
+			}
+			// Wait for the server to have processed every input sent before going away.
+			if tt.inputsSent > 0 {
+				h.ServerLog.WaitForLogged(t, "info", fmt.Sprintf("Received input number %d,", tt.inputsSent), testharness.WaitTimeout)
+			} else {
+				h.ServerLog.WaitForLogged(t, "info", "Opened stream", testharness.WaitTimeout)
+			}
+			cancel()
+
+			h.ServerLog.WaitForLogged(t, "error", fmt.Sprintf("Could not receive latest request at iteration %d:", tt.inputsSent+1), testharness.WaitTimeout)
+			h.ServerLog.WaitForLogged(t, "info", "END OF RPC OPERATION", testharness.WaitTimeout)
+			h.ServerLog.AssertNotLogged(t, "warn", "Leftover data")
+		})
+	}
+}
+
+// End-to-end test of a server that authenticates its clients with bearer tokens: only core clients
+//   presenting one of its tokens, over TLS, are served.
+func TestAuthTokenEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	config := fewerserver.DefaultServerConfig()
+	config.Auth.Tokens = []string{"s3cret"}
+	if err := h.Server.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	if _, err := h.Client.PerformAggregateBatchOp([]int32{1, 2, 3}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("PerformAggregateBatchOp() without token error = %v, want Unauthenticated", err)
+	}
+	h.ServerLog.AssertLogged(t, "warn", "Rejecting call without a bearer token")
+
+	for _, tt := range []struct {
+		token    string
+		wantCode codes.Code
+	}{
+		{token: "wrong", wantCode: codes.Unauthenticated},
+		{token: "s3cret", wantCode: codes.OK},
+	} {
+		client := h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) { c.SetAuthToken(tt.token) })
+		if _, err := client.PerformAggregateBatchOp([]int32{1, 2, 3}); status.Code(err) != tt.wantCode {
+			t.Errorf("PerformAggregateBatchOp() with token %q error = %v, want %v", tt.token, err, tt.wantCode)
+		}
+	}
+
+	// Over plain TCP, a core client refuses to send its token, and to connect at all.
+	client := internal.NewCoreFewerSrvClient("localhost", 50051, internal.NewRecordingClientLogger(), false)
+	defer client.Close()
+	client.SetAuthToken("s3cret")
+	if err := client.ConnectToServer(); err == nil {
+		t.Errorf("ConnectToServer() with a token over plain TCP error = nil, want an error")
+	}
+	client = internal.NewCoreFewerSrvClient("localhost", 50051, internal.NewRecordingClientLogger(), false)
+	defer client.Close()
+	client.SetTLS(filepath.Join(t.TempDir(), "missing.pem"))
+	if err := client.ConnectToServer(); err == nil {
+		t.Errorf("ConnectToServer() with a missing TLS CA file error = nil, want an error")
+	}
+}
+
+// End-to-end test of the interceptors given to a core client as options: they see every call the
+//   client makes, in the order they were added.
+func TestClientOptionsEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	var intercepted []string
+	unary := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		intercepted = append(intercepted, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		intercepted = append(intercepted, method)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	client := h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) {
+		c.AddOptions(internal.WithUnaryInterceptors(unary), internal.WithStreamInterceptors(stream))
+	})
+
+	if _, err := client.PerformAggregateBatchOp([]int32{1, 2, 3}); err != nil {
+		t.Fatalf("PerformAggregateBatchOp() error = %v", err)
+	}
+	if _, err := client.PerformAggregateUploadOp([]int32{1, 2, 3}); err != nil {
+		t.Fatalf("PerformAggregateUploadOp() error = %v", err)
+	}
+	want := []string{pb.FewerService_AggregateBatch_FullMethodName, pb.FewerService_AggregateUpload_FullMethodName}
+	if fmt.Sprint(intercepted) != fmt.Sprint(want) {
+		t.Errorf("intercepted calls = %v, want %v", intercepted, want)
+	}
+}
+
+// End-to-end test of the errors returned by the core client, decoded from the status details of
+//   the server's errors into typed errors.
+func TestTypedErrorsEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	config := fewerserver.DefaultServerConfig()
+	config.Auth.Tokens = []string{"s3cret"}
+	config.RateLimits.InputsPerSecond = 2
+	config.RateLimits.Mode = "reject"
+	if err := h.Server.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	// The error of a stream, rejected for its token.
+	_, err := h.Client.PerformAggregateUploadOp([]int32{1, 2, 3})
+	var rpcErr *internal.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Reason != pb.ErrorReason_MISSING_TOKEN || rpcErr.Code != codes.Unauthenticated {
+		t.Errorf("PerformAggregateUploadOp() without token error = %#v, want an RPCError with reason MISSING_TOKEN", err)
+	}
+
+	// The error of a unary call, rejected for its rate, which still has the status code.
+	client := h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) { c.SetAuthToken("s3cret") })
+	if _, err := client.PerformAggregateBatchOp([]int32{1, 2}); err != nil {
+		t.Fatalf("PerformAggregateBatchOp() within the rate limit error = %v", err)
+	}
+	_, err = client.PerformAggregateBatchOp([]int32{1, 2})
+	var quotaErr *internal.QuotaError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("rate limited PerformAggregateBatchOp() error = %#v, want a QuotaError", err)
+	}
+	if quotaErr.Reason != pb.ErrorReason_RATE_LIMITED || quotaErr.RetryDelay <= 0 || quotaErr.Metadata["limit"] != "2" {
+		t.Errorf("rate limited PerformAggregateBatchOp() error = %+v, want reason RATE_LIMITED with a retry delay and limit 2", quotaErr.RPCError)
+	}
+	if len(quotaErr.Violations) != 1 || quotaErr.Violations[0].Description != "rate_limits.inputs_per_second" {
+		t.Errorf("rate limited PerformAggregateBatchOp() violations = %v, want rate_limits.inputs_per_second", quotaErr.Violations)
+	}
+	if !errors.As(err, &rpcErr) || status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("rate limited PerformAggregateBatchOp() error = %v, want an RPCError with code ResourceExhausted", err)
+	}
+
+	// The error of a unary call carrying more inputs than the burst, which no wait lets through.
+	_, err = client.PerformAggregateBatchOp([]int32{1, 2, 3})
+	var validationErr *internal.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Reason != pb.ErrorReason_INPUTS_OVER_BURST || validationErr.RetryDelay != 0 {
+		t.Errorf("PerformAggregateBatchOp() over the burst error = %#v, want a ValidationError with reason INPUTS_OVER_BURST and no retry delay", err)
+	}
+}
+
+// End-to-end test of the validation rules of a core client: invalid inputs are skipped and counted
+//   under the skip policy, and end the call with a ValidationError under the fail policy.
+func TestValidationRulesEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	maxInput := int32(10)
+
+	h.Client.SetValidationRules(wire.ValidationRules{Max: &maxInput, Disallowed: []int32{2}, Policy: wire.InvalidInputSkip})
+	resp, err := h.Client.PerformAggregateBatchOp([]int32{1, 2, 30, 4})
+	if err != nil {
+		t.Fatalf("PerformAggregateBatchOp() error = %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Result != 1 || resp.Results[0].SkippedInputs != 2 || resp.Summary.SkippedInputs != 2 {
+		t.Errorf("PerformAggregateBatchOp() = %v, want a first aggregate of 1 with 2 skipped inputs", resp)
+	}
+
+	h.Client.SetValidationRules(wire.ValidationRules{Max: &maxInput})
+	_, err = h.Client.PerformAggregateUploadOp([]int32{1, 2, 30, 4})
+	var validationErr *internal.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Reason != pb.ErrorReason_INVALID_INPUT {
+		t.Fatalf("PerformAggregateUploadOp() error = %#v, want a ValidationError with reason INVALID_INPUT", err)
+	}
+	if len(validationErr.Violations) != 1 || validationErr.Violations[0].Description != "input 30 is above the maximum of 10" {
+		t.Errorf("PerformAggregateUploadOp() violations = %v, want input 30 above the maximum", validationErr.Violations)
+	}
+}
+
+// End-to-end test of the reducer of a core client: the server applies it to every batch, unless it
+//   is not among the allowed reducers of the server, which fails the call with a ValidationError.
+func TestReducerEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	config := fewerserver.DefaultServerConfig()
+	config.Aggregation.AllowedReducers = []string{wire.SumReducer, wire.MaxReducer}
+	if err := h.Server.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	h.Client.SetReducer(wire.MaxReducer)
+	resp, err := h.Client.PerformAggregateBatchOp([]int32{4, 1, 7, 2})
+	if err != nil {
+		t.Fatalf("PerformAggregateBatchOp() error = %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Result != 7 || resp.Results[1].Result != 2 || resp.Summary.GrandTotal != 9 {
+		t.Errorf("PerformAggregateBatchOp() = %v, want aggregates 7 and 2", resp)
+	}
+
+	h.Client.SetReducer(wire.MinReducer)
+	_, err = h.Client.PerformAggregateBatchOp([]int32{4, 1, 7, 2})
+	var validationErr *internal.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Reason != pb.ErrorReason_REDUCER_NOT_ALLOWED {
+		t.Errorf("PerformAggregateBatchOp() error = %#v, want a ValidationError with reason REDUCER_NOT_ALLOWED", err)
+	}
+}
+
+// End-to-end test of the summary a GetAggregatesStream() stream ends with: it matches what the
+//   client sent and received, unless an aggregate gets lost on the way, which the client reports
+//   as a summary mismatch.
+func TestStreamSummaryEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	clientLogger := internal.NewRecordingClientLogger()
+	client := h.NewClient(t, clientLogger)
+	if err := client.PerformGetAggregatesOp(7); err != nil {
+		t.Fatalf("PerformGetAggregatesOp() error = %v", err)
+	}
+	clientLogger.AssertLogged(t, "info", "Received stream summary from Fewer Service server")
+
+	// A stream interceptor losing the first aggregate of the stream.
+	losing := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &aggregateLosingStream{ClientStream: stream}, nil
+	}
+	client = h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) {
+		c.AddOptions(internal.WithStreamInterceptors(losing))
+	})
+	err := client.PerformGetAggregatesOp(7)
+	if !errors.Is(err, internal.ErrSummaryMismatch) {
+		t.Errorf("PerformGetAggregatesOp() with a lost aggregate error = %v, want ErrSummaryMismatch", err)
+	}
+}
+
+// Definition of a client stream that drops the first aggregate it receives.
+type aggregateLosingStream struct {
+	grpc.ClientStream
+	lost bool
+}
+
+func (s *aggregateLosingStream) RecvMsg(m any) error {
+	for {
+		if err := s.ClientStream.RecvMsg(m); err != nil {
+			return err
+		}
+		if resp, ok := m.(*pb.AggregatesStreamResponse); s.lost || !ok || resp.GetAggregate() == nil {
+			return nil
+		}
+		s.lost = true
+	}
+}
+
+// End-to-end test of how a core client coalesces the inputs of a GetAggregatesStream() stream into
+//   packed requests: a batch ends when it reaches the coalescing size, when the max-in-flight
+//   window fills up, or when its first input has lingered, and the inputs reach the server in order
+//   either way.
+func TestSendCoalescingEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	newCountingClient := func(maxInFlight, coalesceSize int, linger time.Duration) (*internal.CoreFewerSrvClient, *requestCountingStreams) {
+		counter := &requestCountingStreams{}
+		client := h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) {
+			c.SetMaxInFlight(maxInFlight)
+			c.SetCoalescing(coalesceSize, linger)
+			c.AddOptions(internal.WithStreamInterceptors(counter.intercept))
+		})
+		return client, counter
+	}
+	wantInputs := fmt.Sprint([]int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20})
+
+	// Batches ended by the coalescing size alone.
+	client, counter := newCountingClient(0, 8, time.Hour)
+	if err := client.PerformGetAggregatesOp(20); err != nil {
+		t.Fatalf("PerformGetAggregatesOp() error = %v", err)
+	}
+	if sizes := counter.sizes(); fmt.Sprint(sizes) != fmt.Sprint([]int{8, 8, 4}) {
+		t.Errorf("requests with coalescing size 8 carry %v inputs, want [8 8 4]", sizes)
+	}
+	if inputs := fmt.Sprint(counter.inputs()); inputs != wantInputs {
+		t.Errorf("inputs sent with coalescing size 8 = %v, want %v", inputs, wantInputs)
+	}
+
+	// Batches ended by a max-in-flight window smaller than the coalescing size.
+	client, counter = newCountingClient(4, 8, time.Hour)
+	if err := client.PerformGetAggregatesOp(20); err != nil {
+		t.Fatalf("PerformGetAggregatesOp() with max in flight 4 error = %v", err)
+	}
+	sizes := counter.sizes()
+	if len(sizes) == 0 || sizes[0] != 4 {
+		t.Errorf("requests with max in flight 4 carry %v inputs, want a first request of 4", sizes)
+	}
+	for _, size := range sizes {
+		if size > 4 {
+			t.Errorf("requests with max in flight 4 carry %v inputs, want at most 4 each", sizes)
+			break
+		}
+	}
+	if inputs := fmt.Sprint(counter.inputs()); inputs != wantInputs {
+		t.Errorf("inputs sent with max in flight 4 = %v, want %v", inputs, wantInputs)
+	}
+
+	// Batches of slowly fed inputs, ended by the linger timer long before the coalescing size is
+	//   reached, unless the linger outlasts the stream.
+	for _, linger := range []time.Duration{2 * time.Millisecond, time.Hour} {
+		var counter *requestCountingStreams
+		report, err := internal.RunLoadTest(
+			internal.LoadTestConfig{Streams: 1, InputRate: 20, Duration: 300 * time.Millisecond},
+			func() (*internal.CoreFewerSrvClient, error) {
+				var client *internal.CoreFewerSrvClient
+				client, counter = newCountingClient(0, 8, linger)
+				return client, nil
+			},
+		)
+		if err != nil {
+			t.Fatalf("RunLoadTest() with linger %v error = %v", linger, err)
+		}
+		sizes := counter.sizes()
+		if total := len(counter.inputs()); report.InputsSent < 2 || int64(total) != report.InputsSent {
+			t.Fatalf("requests with linger %v carry %d inputs, want the %d inputs of the load test (at least 2)", linger, total, report.InputsSent)
+		}
+		if linger == time.Hour && len(sizes) != 1 {
+			t.Errorf("requests with linger %v carry %v inputs, want a single request sent as the stream closes", linger, sizes)
+		}
+		if linger < time.Hour && len(sizes) < 2 {
+			t.Errorf("requests with linger %v carry %v inputs, want several requests flushed by the linger timer", linger, sizes)
+		}
+	}
+}
+
+// Definition of a stub counting the NumberRequest messages sent on the streams of a core client,
+//   through a stream interceptor, along with the inputs each of them carries.
+type requestCountingStreams struct {
+	mu       sync.Mutex
+	requests [][]int32
+}
+
+func (c *requestCountingStreams) intercept(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &requestCountingStream{ClientStream: stream, counter: c}, nil
+}
+
+// Method of the requestCountingStreams that returns the number of inputs of each request so far.
+func (c *requestCountingStreams) sizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sizes := []int{}
+	for _, request := range c.requests {
+		sizes = append(sizes, len(request))
+	}
+	return sizes
+}
+
+// Method of the requestCountingStreams that returns the inputs of every request so far, in the
+//   order they were sent.
+func (c *requestCountingStreams) inputs() []int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	inputs := []int32{}
+	for _, request := range c.requests {
+		inputs = append(inputs, request...)
+	}
+	return inputs
+}
+
+// Definition of a client stream that reports the NumberRequest messages it sends to its counter.
+type requestCountingStream struct {
+	grpc.ClientStream
+	counter *requestCountingStreams
+}
+
+func (s *requestCountingStream) SendMsg(m any) error {
+	if req, ok := m.(*pb.NumberRequest); ok {
+		inputs := req.GetInputNums()
+		if len(inputs) == 0 {
+			inputs = []int32{req.GetInputNum()}
+		}
+		s.counter.mu.Lock()
+		s.counter.requests = append(s.counter.requests, append([]int32(nil), inputs...))
+		s.counter.mu.Unlock()
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+// End-to-end test of the admin operations of a core client: listing the open streams of the server,
+//   cancelling one of them, and draining the server, with the admin token of the server.
+func TestAdminEndToEnd(t *testing.T) {
+	h := testharness.Start(t)
+	config := h.Server.Config()
+	config.Auth.AdminTokens = []string{"adm1n"}
+	if err := h.Server.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	admin := h.NewClient(t, internal.NewRecordingClientLogger(), func(c *internal.CoreFewerSrvClient) { c.SetAdminToken("adm1n") })
+
+	// A client without the admin token is turned away.
+	_, _, err := h.Client.PerformListStreamsOp()
+	var rpcErr *internal.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Reason != pb.ErrorReason_MISSING_TOKEN {
+		t.Errorf("PerformListStreamsOp() without admin token error = %v, want an RPCError with reason MISSING_TOKEN", err)
+	}
+
+	stream, err := h.NewStub(t).GetAggregatesStream(context.Background())
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{5, 6}})
+	var listed []*pb.ActiveStream
+	deadline := time.Now().Add(testharness.WaitTimeout)
+	for len(listed) != 1 || listed[0].InputsSeen != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("PerformListStreamsOp() = %v, want one stream with 2 inputs seen", listed)
+		}
+		if listed, _, err = admin.PerformListStreamsOp(); err != nil {
+			t.Fatalf("PerformListStreamsOp() error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if listed[0].PartialSum != 11 {
+		t.Errorf("listed stream partial sum = %d, want 11", listed[0].PartialSum)
+	}
+
+	if _, err := admin.PerformCancelStreamOp(listed[0].StreamId, "maintenance"); err != nil {
+		t.Fatalf("PerformCancelStreamOp() error = %v", err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("cancelled stream error = %v, want Canceled", err)
+	}
+	if reason := stream.Trailer().Get(wire.CancelReasonTrailerKey); len(reason) != 1 || reason[0] != "maintenance" {
+		t.Errorf("cancel reason trailer of cancelled stream = %v, want maintenance", reason)
+	}
+	_, err = admin.PerformCancelStreamOp(listed[0].StreamId, "")
+	if !errors.As(err, &rpcErr) || rpcErr.Reason != pb.ErrorReason_STREAM_NOT_FOUND {
+		t.Errorf("PerformCancelStreamOp() of ended stream error = %v, want an RPCError with reason STREAM_NOT_FOUND", err)
+	}
+
+	if open, err := admin.PerformDrainOp(false); err != nil || open != 0 {
+		t.Fatalf("PerformDrainOp() = %d, %v, want 0 open streams", open, err)
+	}
+	_, err = h.Client.PerformAggregateBatchOp([]int32{1, 2, 3})
+	if !errors.As(err, &rpcErr) || rpcErr.Reason != pb.ErrorReason_DRAINING || rpcErr.Code != codes.Unavailable {
+		t.Errorf("PerformAggregateBatchOp() on draining server error = %v, want an RPCError with reason DRAINING", err)
+	}
+}
+
+// End-to-end test of a core client connecting to the server through a Unix domain socket, as a
+//   local sidecar client would.  Its bearer token is sent without TLS, as only local processes can
+//   listen in on the socket.
+func TestUnixSocketEndToEnd(t *testing.T) {
+	dir, err := os.MkdirTemp("", "fewer")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "fewer.sock")
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := fewerserver.NewGeneralFewerServerWithLogger(fewerserver.NewRecordingServerLogger(), lis)
+	config := fewerserver.DefaultServerConfig()
+	config.Auth.Tokens = []string{"s3cret"}
+	if err := server.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	go server.Serve()
+	defer server.Shutdown()
+
+	client := internal.NewCoreFewerSrvClient("unix://"+socketPath, 0, internal.NewRecordingClientLogger(), false)
+	defer client.Close()
+	client.SetAuthToken("s3cret")
+	if err := client.ConnectToServer(); err != nil {
+		t.Fatalf("ConnectToServer() error = %v", err)
+	}
+	aggregates, err := client.PerformGetAggregatesOpWithContext(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("PerformGetAggregatesOpWithContext() error = %v", err)
+	}
+	if len(aggregates) != 2 || aggregates[0].Result != 6 || aggregates[1].Result != 4 {
+		t.Errorf("aggregates through Unix domain socket = %v, want results 6 and 4", aggregates)
+	}
+}
+
+// End-to-end test of keepalive pings on a connection that died silently: once the network is cut,
+//   both the server and the core client find out through their pings that the connection is dead,
+//   close it and log it, and the open subscription fails.
+func TestKeepaliveDetectsDeadConnection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping keepalive test, which waits for the 10 second minimum ping interval of clients")
+	}
+	config := fewerserver.DefaultServerConfig()
+	config.Keepalive.Time = time.Second
+	config.Keepalive.Timeout = 500 * time.Millisecond
+	h := testharness.StartWithConfig(t, config)
+	clientLog := internal.NewRecordingClientLogger()
+	client := h.NewClient(t, clientLog, func(c *internal.CoreFewerSrvClient) { c.SetKeepalive(10*time.Second, time.Second, true) })
+
+	subscribed := make(chan error, 1)
+	go func() { subscribed <- client.PerformSubscribeAggregatesOp(context.Background(), &pb.SubscribeAggregatesRequest{}, nil) }()
+	h.ServerLog.WaitForLogged(t, "info", "New subscriber", testharness.WaitTimeout)
+
+	h.CutNetwork()
+	h.ServerLog.WaitForLogged(t, "info", "Client connection closed after", testharness.WaitTimeout)
+	select {
+	case err := <-subscribed:
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("PerformSubscribeAggregatesOp() error = %v, want Unavailable", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatalf("subscription still open 20 seconds after the network was cut")
+	}
+	clientLog.AssertLogged(t, "error", "keepalive")
+}
@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+)
+
+// Upper bounds (in milliseconds) of the buckets of the batch-latency histogram in a LoadReport.
+//   Latencies above the last bound are counted in a final, unbounded bucket.
+var latencyBucketBoundsMs = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Interval at which the input feeders of a load test top up their streams to the target rate.
+const loadFeedInterval = time.Millisecond
+
+
+
+//*************************************************************************************************
+// Definition of the settings of a load test against a Fewer Service server.
+type LoadTestConfig struct {
+	// Number of concurrent GetAggregatesStream() streams, each on its own core client.
+	Streams   int
+	// Target number of inputs per second, over all streams together.  0 sends as fast as the
+	//   streams allow.
+	InputRate float64
+	// How long inputs are sent for.  Every stream is then closed, and its remaining aggregates
+	//   are received.
+	Duration  time.Duration
+}
+
+// Definition of the report of a load test.
+type LoadReport struct {
+	Streams          int              `json:"streams"`
+	TargetInputRate  float64          `json:"target_input_rate"`
+	Elapsed          time.Duration    `json:"elapsed_ns"`
+	InputsSent       int64            `json:"inputs_sent"`
+	AggregatesRecv   int64            `json:"aggregates_received"`
+	InputsPerSec     float64          `json:"inputs_per_sec"`
+	AggregatesPerSec float64          `json:"aggregates_per_sec"`
+	FailedStreams    int              `json:"failed_streams"`
+	// Batch latency: time from sending the input that completes a batch to receiving its
+	//   aggregate.
+	Latency          LatencySummary   `json:"batch_latency"`
+}
+
+// Definition of a summary of batch latencies, in milliseconds.
+type LatencySummary struct {
+	Count     int               `json:"count"`
+	MeanMs    float64           `json:"mean_ms"`
+	P50Ms     float64           `json:"p50_ms"`
+	P90Ms     float64           `json:"p90_ms"`
+	P99Ms     float64           `json:"p99_ms"`
+	MaxMs     float64           `json:"max_ms"`
+	Histogram []HistogramBucket `json:"histogram"`
+}
+
+// Definition of one bucket of a latency histogram, counting the latencies up to UpperBoundMs that
+//   are above the previous bucket's bound.  The last bucket has no upper bound (UpperBoundMs is
+//   +Inf in text output, and omitted in JSON output).
+type HistogramBucket struct {
+	UpperBoundMs float64 `json:"le_ms,omitempty"`
+	Count        int     `json:"count"`
+}
+
+// Method of the LoadReport that writes the report as human-readable text.
+func (r *LoadReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "streams:            %d (%d failed)\n", r.Streams, r.FailedStreams)
+	if r.TargetInputRate > 0 {
+		fmt.Fprintf(w, "target input rate:  %.0f inputs/s\n", r.TargetInputRate)
+	} else {
+		fmt.Fprintf(w, "target input rate:  unlimited\n")
+	}
+	fmt.Fprintf(w, "elapsed:            %v\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "inputs sent:        %d (%.1f inputs/s)\n", r.InputsSent, r.InputsPerSec)
+	fmt.Fprintf(w, "aggregates:         %d (%.1f aggregates/s)\n", r.AggregatesRecv, r.AggregatesPerSec)
+	fmt.Fprintf(w, "batch latency (ms): mean %.3f  p50 %.3f  p90 %.3f  p99 %.3f  max %.3f\n",
+		r.Latency.MeanMs, r.Latency.P50Ms, r.Latency.P90Ms, r.Latency.P99Ms, r.Latency.MaxMs)
+	fmt.Fprintf(w, "batch latency histogram:\n")
+	maxCount := 0
+	for _, bucket := range r.Latency.Histogram {
+		maxCount = max(maxCount, bucket.Count)
+	}
+	for _, bucket := range r.Latency.Histogram {
+		bound := "+Inf"
+		if bucket.UpperBoundMs > 0 {
+			bound = fmt.Sprintf("%g", bucket.UpperBoundMs)
+		}
+		bar := ""
+		if maxCount > 0 {
+			bar = strings.Repeat("#", bucket.Count*40/maxCount)
+		}
+		fmt.Fprintf(w, "  <= %6s ms %9d %s\n", bound, bucket.Count, bar)
+	}
+}
+
+// Method of the LoadReport that writes the report as indented JSON.
+func (r *LoadReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+
+
+//*************************************************************************************************
+// Definition of the batch-latency tracker of one load test stream.  It remembers when each request
+//   was sent, so that the latency of an aggregate can be measured from the send time of the
+//   request that carried its last input.
+type streamLatencyTracker struct {
+	mu        sync.Mutex
+	// Total number of inputs sent after each request, and the time the request was sent.  Entries
+	//   before the last aggregate received are dropped.
+	sentCount []int64
+	sentAt    []time.Time
+	latencies []float64
+}
+
+// Method of the streamLatencyTracker that records the send of a request.
+func (t *streamLatencyTracker) inputsSent(sentInputs int64) {
+	now := time.Now()
+	t.mu.Lock()
+	t.sentCount = append(t.sentCount, sentInputs)
+	t.sentAt = append(t.sentAt, now)
+	t.mu.Unlock()
+}
+
+// Method of the streamLatencyTracker that records the latency of a received aggregate.
+func (t *streamLatencyTracker) aggregateReceived(aggregate *pb.NumberResponse) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := sort.Search(len(t.sentCount), func(i int) bool { return t.sentCount[i] >= aggregate.LastInput })
+	if i == len(t.sentCount) {
+		return
+	}
+	t.latencies = append(t.latencies, float64(now.Sub(t.sentAt[i]))/float64(time.Millisecond))
+	t.sentCount = t.sentCount[i:]
+	t.sentAt = t.sentAt[i:]
+}
+
+
+
+//*************************************************************************************************
+// Function that runs a load test: it opens the configured number of concurrent GetAggregatesStream()
+//   streams, each on a core client returned by newClient, feeds them inputs at the target rate for
+//   the configured duration, and reports the throughput and batch latencies it measured.
+func RunLoadTest(config LoadTestConfig, newClient func() (*CoreFewerSrvClient, error)) (*LoadReport, error) {
+	if config.Streams < 1 {
+		return nil, fmt.Errorf("a load test needs at least 1 stream, got %d", config.Streams)
+	}
+	if config.Duration <= 0 {
+		return nil, fmt.Errorf("a load test needs a positive duration, got %v", config.Duration)
+	}
+
+	// Connect every core client before starting the clock.
+	clients := make([]*CoreFewerSrvClient, 0, config.Streams)
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+	for i := 0; i < config.Streams; i++ {
+		client, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	var inputsSent, aggregatesRecv atomic.Int64
+	var failedStreams atomic.Int64
+	trackers := make([]*streamLatencyTracker, config.Streams)
+	streamRate := config.InputRate / float64(config.Streams)
+	deadline := time.Now().Add(config.Duration)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		tracker := &streamLatencyTracker{}
+		trackers[i] = tracker
+		observer := &streamObserver{
+			inputsSent: tracker.inputsSent,
+			aggregateReceived: func(aggregate *pb.NumberResponse) {
+				aggregatesRecv.Add(1)
+				tracker.aggregateReceived(aggregate)
+			},
+		}
+		inputs := make(chan int32)
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(stop)
+			if err := client.performGetAggregatesStream(context.Background(), inputs, observer); err != nil {
+				failedStreams.Add(1)
+			}
+		}()
+		go feedLoadInputs(inputs, stop, streamRate, deadline, &inputsSent)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var latencies []float64
+	for _, tracker := range trackers {
+		latencies = append(latencies, tracker.latencies...)
+	}
+	report := &LoadReport{
+		Streams:          config.Streams,
+		TargetInputRate:  config.InputRate,
+		Elapsed:          elapsed,
+		InputsSent:       inputsSent.Load(),
+		AggregatesRecv:   aggregatesRecv.Load(),
+		InputsPerSec:     float64(inputsSent.Load()) / elapsed.Seconds(),
+		AggregatesPerSec: float64(aggregatesRecv.Load()) / elapsed.Seconds(),
+		FailedStreams:    int(failedStreams.Load()),
+		Latency:          summarizeLatencies(latencies),
+	}
+	return report, nil
+}
+
+// Helper function that feeds inputs to a load test stream at the given rate (inputs per second,
+//   0 for as fast as possible) until the deadline, then closes the inputs channel.  It stops early
+//   if the stream ends.
+func feedLoadInputs(inputs chan<- int32, stop <-chan struct{}, rate float64, deadline time.Time, inputsSent *atomic.Int64) {
+	defer close(inputs)
+	start := time.Now()
+	var fed int64
+	ticker := time.NewTicker(loadFeedInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		// Number of inputs that should have been fed by now to keep up with the rate.
+		due := int64(math.MaxInt64)
+		if rate > 0 {
+			due = int64(time.Since(start).Seconds() * rate)
+		}
+		for fed < due && time.Now().Before(deadline) {
+			select {
+			case inputs <- int32(fed % 1000):
+				fed++
+				inputsSent.Add(1)
+			case <-stop:
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Helper function that computes the summary of a set of latencies (in milliseconds).
+func summarizeLatencies(latencies []float64) LatencySummary {
+	summary := LatencySummary{Count: len(latencies)}
+	for _, bound := range latencyBucketBoundsMs {
+		summary.Histogram = append(summary.Histogram, HistogramBucket{UpperBoundMs: bound})
+	}
+	summary.Histogram = append(summary.Histogram, HistogramBucket{})
+	if len(latencies) == 0 {
+		return summary
+	}
+
+	sort.Float64s(latencies)
+	var total float64
+	for _, latency := range latencies {
+		total += latency
+		bucket := sort.SearchFloat64s(latencyBucketBoundsMs, latency)
+		summary.Histogram[bucket].Count++
+	}
+	percentile := func(p float64) float64 {
+		return latencies[int(math.Ceil(p*float64(len(latencies))))-1]
+	}
+	summary.MeanMs = total / float64(len(latencies))
+	summary.P50Ms = percentile(0.50)
+	summary.P90Ms = percentile(0.90)
+	summary.P99Ms = percentile(0.99)
+	summary.MaxMs = latencies[len(latencies)-1]
+	return summary
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+)
+
+// Test of the percentiles, mean and maximum of a latency summary, which are taken by nearest rank
+//   from the sorted latencies.
+func TestSummarizeLatenciesPercentiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []float64
+		want      LatencySummary
+	}{
+		{"one latency", []float64{7}, LatencySummary{Count: 1, MeanMs: 7, P50Ms: 7, P90Ms: 7, P99Ms: 7, MaxMs: 7}},
+		{"two latencies", []float64{4, 2}, LatencySummary{Count: 2, MeanMs: 3, P50Ms: 2, P90Ms: 4, P99Ms: 4, MaxMs: 4}},
+		{"ten latencies", []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, LatencySummary{Count: 10, MeanMs: 5.5, P50Ms: 5, P90Ms: 9, P99Ms: 10, MaxMs: 10}},
+		{"hundred latencies", latencyRange(1, 100), LatencySummary{Count: 100, MeanMs: 50.5, P50Ms: 50, P90Ms: 90, P99Ms: 99, MaxMs: 100}},
+		{"thousand latencies", latencyRange(1, 1000), LatencySummary{Count: 1000, MeanMs: 500.5, P50Ms: 500, P90Ms: 900, P99Ms: 990, MaxMs: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeLatencies(tt.latencies)
+			got.Histogram = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summarizeLatencies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test of the histogram of a latency summary: a latency exactly on a bucket bound is counted in
+//   that bucket, and latencies above the last bound in the final, unbounded bucket.
+func TestSummarizeLatenciesHistogram(t *testing.T) {
+	summary := summarizeLatencies([]float64{0.1, 0.2, 1, 1.5, 2.5, 1000, 1000.5, 5000})
+	wantCounts := map[float64]int{0.1: 1, 0.25: 1, 1: 1, 2.5: 2, 1000: 1, 0: 2}
+	if len(summary.Histogram) != len(latencyBucketBoundsMs)+1 {
+		t.Fatalf("histogram has %d buckets, want %d", len(summary.Histogram), len(latencyBucketBoundsMs)+1)
+	}
+	for i, bucket := range summary.Histogram {
+		wantBound := 0.0
+		if i < len(latencyBucketBoundsMs) {
+			wantBound = latencyBucketBoundsMs[i]
+		}
+		if bucket.UpperBoundMs != wantBound || bucket.Count != wantCounts[wantBound] {
+			t.Errorf("histogram bucket %d = %+v, want upper bound %v and count %d", i, bucket, wantBound, wantCounts[wantBound])
+		}
+	}
+}
+
+// Test of the summary of no latencies at all: every bucket of its histogram is empty, and it has
+//   no percentiles.
+func TestSummarizeLatenciesEmpty(t *testing.T) {
+	summary := summarizeLatencies(nil)
+	if summary.Count != 0 || summary.MeanMs != 0 || summary.P50Ms != 0 || summary.P99Ms != 0 || summary.MaxMs != 0 {
+		t.Errorf("summarizeLatencies(nil) = %+v, want a zero summary", summary)
+	}
+	if len(summary.Histogram) != len(latencyBucketBoundsMs)+1 {
+		t.Fatalf("histogram has %d buckets, want %d", len(summary.Histogram), len(latencyBucketBoundsMs)+1)
+	}
+	for i, bucket := range summary.Histogram {
+		if bucket.Count != 0 {
+			t.Errorf("histogram bucket %d count = %d, want 0", i, bucket.Count)
+		}
+	}
+}
+
+// Test of how a streamLatencyTracker matches aggregates to the requests that carried their last
+//   input: each aggregate is measured from the first request that brought the sent count up to its
+//   last input, and aggregates covering inputs that were never sent are left out.
+func TestStreamLatencyTracker(t *testing.T) {
+	now := time.Now()
+	tracker := &streamLatencyTracker{
+		sentCount: []int64{2, 6, 9},
+		sentAt:    []time.Time{now.Add(-30 * time.Second), now.Add(-20 * time.Second), now.Add(-10 * time.Second)},
+	}
+
+	// Inputs 4 to 6 were packed in the second request, so an aggregate ending at input 4 is
+	//   measured from it, and so is one ending at input 6.
+	tracker.aggregateReceived(&pb.NumberResponse{FirstInput: 1, LastInput: 4})
+	tracker.aggregateReceived(&pb.NumberResponse{FirstInput: 5, LastInput: 6})
+	tracker.aggregateReceived(&pb.NumberResponse{FirstInput: 7, LastInput: 9})
+	// No request carried input 12.
+	tracker.aggregateReceived(&pb.NumberResponse{FirstInput: 10, LastInput: 12})
+
+	wantLatencies := []float64{20000, 20000, 10000}
+	if len(tracker.latencies) != len(wantLatencies) {
+		t.Fatalf("tracker latencies = %v, want about %v", tracker.latencies, wantLatencies)
+	}
+	for i, latency := range tracker.latencies {
+		if latency < wantLatencies[i] || latency > wantLatencies[i]+5000 {
+			t.Errorf("latency of aggregate %d = %vms, want about %vms", i, latency, wantLatencies[i])
+		}
+	}
+	if fmt.Sprint(tracker.sentCount) != "[9]" || len(tracker.sentAt) != 1 {
+		t.Errorf("tracker kept requests with sent counts %v, want only the one of the last aggregate [9]", tracker.sentCount)
+	}
+}
+
+// Helper function that returns every whole latency between from and to (in milliseconds), in
+//   reverse order.
+func latencyRange(from, to int) []float64 {
+	var latencies []float64
+	for latency := to; latency >= from; latency-- {
+		latencies = append(latencies, float64(latency))
+	}
+	return latencies
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain of the ErrorInfo detail carried by the error statuses of the Fewer Service.
+const errorDomain = "fewer.astronomical3.github.com"
+
+
+
+//***************************************************************************************************
+// Definition of an error returned by a call to the Fewer Service, decoded from the details of its
+//   gRPC status.  The core client returns every error of a call as an *RPCError, or as one of the
+//   more specific errors wrapping it (*ValidationError, *QuotaError), which errors.As() can find.
+//   status.Code() and status.FromError() keep working on them.
+type RPCError struct {
+	Code       codes.Code
+	Message    string
+	// Reason of the error, and metadata of its ErrorInfo detail (e.g., the limit that was reached).
+	//   ERROR_REASON_UNSPECIFIED if the error did not come from the Fewer Service itself.
+	Reason     pb.ErrorReason
+	Metadata   map[string]string
+	// Time the server asked the client to wait before retrying the call, or 0 if it did not.
+	RetryDelay time.Duration
+
+	status     *status.Status
+}
+
+// Method of the RPCError that returns the same message as the gRPC status error it was decoded
+//   from.
+func (e *RPCError) Error() string {
+	return e.status.Err().Error()
+}
+
+// Method of the RPCError that returns the gRPC status it was decoded from.
+func (e *RPCError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// Definition of the error of a call rejected for invalid fields in its request (a BadRequest
+//   detail).
+type ValidationError struct {
+	*RPCError
+	Violations []FieldViolation
+}
+
+// Definition of a field of a request rejected by the Fewer Service, and why.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Method of the ValidationError that returns the RPCError it wraps.
+func (e *ValidationError) Unwrap() error {
+	return e.RPCError
+}
+
+// Definition of the error of a call rejected for going over a limit or quota of the server (a
+//   QuotaFailure detail).
+type QuotaError struct {
+	*RPCError
+	Violations []QuotaViolation
+}
+
+// Definition of a quota gone over, with the subject it applies to (e.g., "server", or "client
+//   <host>") and the setting of the server it comes from.
+type QuotaViolation struct {
+	Subject     string
+	Description string
+}
+
+// Method of the QuotaError that returns the RPCError it wraps.
+func (e *QuotaError) Unwrap() error {
+	return e.RPCError
+}
+
+// Helper function that decodes the gRPC status error of a call into an *RPCError, *ValidationError
+//   or *QuotaError.  Errors that are not gRPC status errors (e.g., io.EOF) are returned as is.
+func decodeError(err error) error {
+	var decoded *RPCError
+	if err == nil || errors.As(err, &decoded) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	rpcErr := &RPCError{Code: st.Code(), Message: st.Message(), status: st}
+	var fieldViolations []FieldViolation
+	var quotaViolations []QuotaViolation
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain == errorDomain {
+				rpcErr.Reason = pb.ErrorReason(pb.ErrorReason_value[d.Reason])
+				rpcErr.Metadata = d.Metadata
+			}
+		case *errdetails.RetryInfo:
+			rpcErr.RetryDelay = d.RetryDelay.AsDuration()
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fieldViolations = append(fieldViolations, FieldViolation{Field: v.Field, Description: v.Description})
+			}
+		case *errdetails.QuotaFailure:
+			for _, v := range d.Violations {
+				quotaViolations = append(quotaViolations, QuotaViolation{Subject: v.Subject, Description: v.Description})
+			}
+		}
+	}
+
+	switch {
+	case len(fieldViolations) > 0:
+		return &ValidationError{RPCError: rpcErr, Violations: fieldViolations}
+	case len(quotaViolations) > 0:
+		return &QuotaError{RPCError: rpcErr, Violations: quotaViolations}
+	}
+	return rpcErr
+}
+
+// Unary interceptor of the core client that decodes the errors of its calls.
+func decodeErrorsUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return decodeError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// Stream interceptor of the core client that decodes the errors of its streams.
+func decodeErrorsStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	return decodingClientStream{stream}, nil
+}
+
+// Definition of a client stream whose receive errors, which carry the status of the call, are
+//   decoded.
+type decodingClientStream struct {
+	grpc.ClientStream
+}
+
+// Method of the decodingClientStream that receives the next response of the stream, decoding the
+//   error that ends it (see decodeError()).  io.EOF, returned once the stream ends successfully,
+//   is passed through as is.
+func (s decodingClientStream) RecvMsg(m any) error {
+	return decodeError(s.ClientStream.RecvMsg(m))
+}
@@ -0,0 +1,260 @@
+// Package testharness runs a Fewer Service server in-process, on an in-memory bufconn listener, and
+//   connects core clients to it, so that end-to-end tests exercise the real server and client code
+//   without opening network ports or log files.
+package testharness
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/client/internal"
+	"github.com/astronomical3/fewer_grpc/server/fewerserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Size of the in-memory buffer of every bufconn connection to the in-process server.
+const bufconnBufferSize = 1 << 20
+
+// Target dialed by clients of the in-process server.  The passthrough resolver hands the target to
+//   the bufconn dialer as is, instead of trying to resolve it.
+const bufconnTarget = "passthrough:///bufconn"
+
+// Server name that the TLS certificate of the in-process server is issued for, which clients
+//   verify against the authority of bufconnTarget.
+const bufconnServerName = "bufconn"
+
+// How long tests should wait for an entry that the server or client logs asynchronously (e.g., with
+//   WaitForLogged()) before giving up.
+const WaitTimeout = 5 * time.Second
+
+
+
+//*************************************************************************************************
+// Definition of an in-process Fewer Service server, along with a core client connected to it.  The
+//   server and clients log to recording loggers, whose entries tests can inspect and assert on.
+//   Connections to the server are secured with TLS, as bearer tokens are only sent over TLS.
+type Harness struct {
+	Server    *fewerserver.GeneralFewerServer
+	ServerLog *fewerserver.RecordingServerLogger
+	// Core client connected to the server, and the logger it logs to.
+	Client    *internal.CoreFewerSrvClient
+	ClientLog *internal.RecordingClientLogger
+
+	listener  *bufconn.Listener
+	// Self-signed certificate of the server, and the PEM file clients verify it against.
+	certificate tls.Certificate
+	caFile      string
+	// Closed once the network between clients and the server is cut.
+	cut       chan struct{}
+	cutOnce   sync.Once
+}
+
+// Function that starts a new in-process Fewer Service server, and connects a core client to it.
+//   Both are shut down when the test ends.
+func Start(tb testing.TB) *Harness {
+	tb.Helper()
+	return start(tb, func(serverLogger fewerserver.ServerLogger, lis net.Listener, options ...fewerserver.Option) (*fewerserver.GeneralFewerServer, error) {
+		return fewerserver.NewGeneralFewerServerWithLogger(serverLogger, lis, options...), nil
+	})
+}
+
+// Function that starts a new in-process Fewer Service server created from the settings of a
+//   server configuration (its listeners are ignored), and connects a core client to it.  Both are
+//   shut down when the test ends.
+func StartWithConfig(tb testing.TB, config fewerserver.ServerConfig) *Harness {
+	tb.Helper()
+	return start(tb, func(serverLogger fewerserver.ServerLogger, lis net.Listener, options ...fewerserver.Option) (*fewerserver.GeneralFewerServer, error) {
+		return fewerserver.NewGeneralFewerServerWithConfig(serverLogger, []net.Listener{lis}, config, options...)
+	})
+}
+
+// Helper function that starts the in-process server created by newServer, with the given options,
+//   and connects a core client to it.
+func start(tb testing.TB, newServer func(fewerserver.ServerLogger, net.Listener, ...fewerserver.Option) (*fewerserver.GeneralFewerServer, error)) *Harness {
+	tb.Helper()
+	h := &Harness{
+		ServerLog: fewerserver.NewRecordingServerLogger(),
+		listener:  bufconn.Listen(bufconnBufferSize),
+		cut:       make(chan struct{}),
+	}
+	h.certificate, h.caFile = newSelfSignedCertificate(tb)
+	serverCreds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{h.certificate}, MinVersion: tls.VersionTLS12})
+	var err error
+	if h.Server, err = newServer(h.ServerLog, h.listener, fewerserver.WithServerOptions(grpc.Creds(serverCreds))); err != nil {
+		tb.Fatalf("failed to create in-process server: %v", err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- h.Server.Serve() }()
+	tb.Cleanup(func() {
+		h.Server.Shutdown()
+		if err := <-served; err != nil {
+			tb.Errorf("in-process server failed to serve: %v", err)
+		}
+	})
+
+	h.ClientLog = internal.NewRecordingClientLogger()
+	h.Client = h.NewClient(tb, h.ClientLog)
+	return h
+}
+
+// Method of the Harness that connects a new core client, logging to clientLogger, to the in-process
+//   server.  Each configure function is called on the client before it connects (e.g., to set an
+//   auth token).  The client is closed when the test ends.
+func (h *Harness) NewClient(tb testing.TB, clientLogger internal.ClientLogger, configure ...func(*internal.CoreFewerSrvClient)) *internal.CoreFewerSrvClient {
+	tb.Helper()
+	client := internal.NewCoreFewerSrvClient(bufconnTarget, 0, clientLogger, false)
+	client.SetTLS(h.caFile)
+	client.AddOptions(internal.WithDialOptions(grpc.WithContextDialer(h.dial)))
+	for _, fn := range configure {
+		fn(client)
+	}
+	if err := client.ConnectToServer(); err != nil {
+		tb.Fatalf("failed to connect core client to in-process server: %v", err)
+	}
+	tb.Cleanup(client.Close)
+	return client
+}
+
+// Method of the Harness that returns a raw client stub to the in-process server, for tests that
+//   need to drive an RPC message by message.  Its connection is closed when the test ends.
+func (h *Harness) NewStub(tb testing.TB) pb.FewerServiceClient {
+	tb.Helper()
+	conn, err := grpc.NewClient(
+		bufconnTarget,
+		grpc.WithContextDialer(h.dial),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: h.rootCAs(), MinVersion: tls.VersionTLS12})),
+	)
+	if err != nil {
+		tb.Fatalf("failed to connect client stub to in-process server: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	return pb.NewFewerServiceClient(conn)
+}
+
+// Method of the Harness that cuts the network between clients and the in-process server, the way
+//   a NAT or firewall dropping a connection would: connections stay open, but whatever either side
+//   sends is silently lost.  Only keepalive pings can then tell that the connections are dead.
+func (h *Harness) CutNetwork() {
+	h.cutOnce.Do(func() { close(h.cut) })
+}
+
+// Internal method of the Harness that returns a pool holding the certificate of the in-process
+//   server, for clients to verify it against.
+func (h *Harness) rootCAs() *x509.CertPool {
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(h.certificate.Leaf)
+	return rootCAs
+}
+
+// Internal method of the Harness that opens a new in-memory connection to the in-process server.
+func (h *Harness) dial(ctx context.Context, _ string) (net.Conn, error) {
+	conn, err := h.listener.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &cuttableConn{Conn: conn, cut: h.cut, closed: make(chan struct{})}, nil
+}
+
+
+// Helper function that issues a self-signed TLS certificate for the in-process server, and writes
+//   it to a PEM file in a temporary directory of the test.  Returns the certificate and its file.
+func newSelfSignedCertificate(tb testing.TB) (tls.Certificate, string) {
+	tb.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("failed to generate TLS key of in-process server: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: bufconnServerName},
+		DNSNames:              []string{bufconnServerName},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatalf("failed to issue TLS certificate of in-process server: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("failed to parse TLS certificate of in-process server: %v", err)
+	}
+
+	caFile := filepath.Join(tb.TempDir(), "server.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		tb.Fatalf("failed to write TLS certificate of in-process server: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, caFile
+}
+
+
+
+//*************************************************************************************************
+// Definition of the client end of an in-memory connection, which drops everything sent either way
+//   once the network is cut, including the server closing its end.
+type cuttableConn struct {
+	net.Conn
+	cut       <-chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Method of the cuttableConn that reads what the server sent, dropping it once the network is cut.
+//   Once cut, the connection never ends from the server's side; only closing it ends reads.
+func (c *cuttableConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if !c.isCut() {
+			return n, err
+		}
+		if err != nil {
+			<-c.closed
+			return 0, err
+		}
+	}
+}
+
+// Method of the cuttableConn that closes the client end of the connection.
+func (c *cuttableConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// Method of the cuttableConn that sends to the server, unless the network is cut.
+func (c *cuttableConn) Write(b []byte) (int, error) {
+	if c.isCut() {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+// Internal method of the cuttableConn that tells whether the network is cut.
+func (c *cuttableConn) isCut() bool {
+	select {
+	case <-c.cut:
+		return true
+	default:
+		return false
+	}
+}
@@ -9,6 +9,8 @@ package fewer
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -20,15 +22,217 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Policy applied to a SubscribeAggregates() subscriber whose buffer of undelivered events is full.
+type SlowConsumerPolicy int32
+
+const (
+	// Same as SLOW_CONSUMER_POLICY_DROP.
+	SlowConsumerPolicy_SLOW_CONSUMER_POLICY_UNSPECIFIED SlowConsumerPolicy = 0
+	// Drop the new events until the subscriber catches up.  The number of dropped events is
+	//   reported in the next event delivered to the subscriber.
+	SlowConsumerPolicy_SLOW_CONSUMER_POLICY_DROP SlowConsumerPolicy = 1
+	// End the subscription with a RESOURCE_EXHAUSTED status.
+	SlowConsumerPolicy_SLOW_CONSUMER_POLICY_DISCONNECT SlowConsumerPolicy = 2
+)
+
+// Enum value maps for SlowConsumerPolicy.
+var (
+	SlowConsumerPolicy_name = map[int32]string{
+		0: "SLOW_CONSUMER_POLICY_UNSPECIFIED",
+		1: "SLOW_CONSUMER_POLICY_DROP",
+		2: "SLOW_CONSUMER_POLICY_DISCONNECT",
+	}
+	SlowConsumerPolicy_value = map[string]int32{
+		"SLOW_CONSUMER_POLICY_UNSPECIFIED": 0,
+		"SLOW_CONSUMER_POLICY_DROP":        1,
+		"SLOW_CONSUMER_POLICY_DISCONNECT":  2,
+	}
+)
+
+func (x SlowConsumerPolicy) Enum() *SlowConsumerPolicy {
+	p := new(SlowConsumerPolicy)
+	*p = x
+	return p
+}
+
+func (x SlowConsumerPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SlowConsumerPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_fewer_fewer_proto_enumTypes[0].Descriptor()
+}
+
+func (SlowConsumerPolicy) Type() protoreflect.EnumType {
+	return &file_fewer_fewer_proto_enumTypes[0]
+}
+
+func (x SlowConsumerPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SlowConsumerPolicy.Descriptor instead.
+func (SlowConsumerPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{0}
+}
+
+// Reason of an error returned by the Fewer Service.  Every error status of the service carries a
+//
+//	google.rpc.ErrorInfo detail in the "fewer.astronomical3.github.com" domain, whose reason is the
+//	name of one of these values (e.g., "RATE_LIMITED").  Depending on the error, the status also
+//	carries a google.rpc.BadRequest, google.rpc.QuotaFailure or google.rpc.RetryInfo detail.
+type ErrorReason int32
+
+const (
+	ErrorReason_ERROR_REASON_UNSPECIFIED ErrorReason = 0
+	// The call carried no bearer token, or one the server does not accept (UNAUTHENTICATED).  Also
+	//   used for the admin tokens of the FewerAdmin service.
+	ErrorReason_MISSING_TOKEN ErrorReason = 1
+	ErrorReason_INVALID_TOKEN ErrorReason = 2
+	// Too many aggregation streams are open on the server, or from the client (RESOURCE_EXHAUSTED).
+	ErrorReason_TOO_MANY_STREAMS            ErrorReason = 3
+	ErrorReason_TOO_MANY_STREAMS_PER_CLIENT ErrorReason = 4
+	// The call sent more inputs than a single call may carry (RESOURCE_EXHAUSTED).
+	ErrorReason_TOO_MANY_INPUTS ErrorReason = 5
+	// A request was larger than the maximum message size of the server (RESOURCE_EXHAUSTED).
+	ErrorReason_MESSAGE_TOO_LARGE ErrorReason = 6
+	// The stream was idle, or open, for longer than the server allows (DEADLINE_EXCEEDED).
+	ErrorReason_STREAM_IDLE     ErrorReason = 7
+	ErrorReason_STREAM_TOO_LONG ErrorReason = 8
+	// The client sent inputs faster than its rate limit (RESOURCE_EXHAUSTED).  The status carries
+	//   how long to wait before retrying.
+	ErrorReason_RATE_LIMITED ErrorReason = 9
+	// The server does not persist aggregates, so they cannot be queried (FAILED_PRECONDITION).
+	ErrorReason_AGGREGATES_NOT_PERSISTED ErrorReason = 10
+	// The page token of a query is not one the server handed out (INVALID_ARGUMENT).
+	ErrorReason_INVALID_PAGE_TOKEN ErrorReason = 11
+	// The aggregate store of the server failed (INTERNAL).
+	ErrorReason_STORE_FAILURE ErrorReason = 12
+	// The server is shutting down (UNAVAILABLE).
+	ErrorReason_SHUTTING_DOWN ErrorReason = 13
+	// The subscriber could not keep up with the aggregate events (RESOURCE_EXHAUSTED).
+	ErrorReason_SLOW_SUBSCRIBER ErrorReason = 14
+	// The call was interrupted, as its client cancelled it or went away, or as its stream failed
+	//   while receiving from or sending to the client.  The status has the code of the underlying
+	//   error.
+	ErrorReason_CALL_INTERRUPTED ErrorReason = 15
+	// The server failed unexpectedly while handling the call (INTERNAL).
+	ErrorReason_INTERNAL_ERROR ErrorReason = 16
+	// The reducer requested in the metadata of the call is unknown, or not allowed by the server
+	//   (INVALID_ARGUMENT).
+	ErrorReason_REDUCER_NOT_ALLOWED ErrorReason = 17
+	// An input broke the validation rules of its stream, whose invalid input policy is to fail
+	//   (INVALID_ARGUMENT).
+	ErrorReason_INVALID_INPUT ErrorReason = 18
+	// The validation rules sent in the metadata of the call are invalid (INVALID_ARGUMENT).
+	ErrorReason_INVALID_VALIDATION_RULES ErrorReason = 19
+	// The FewerAdmin service is turned off, as the server has no admin tokens (PERMISSION_DENIED).
+	ErrorReason_ADMIN_DISABLED ErrorReason = 20
+	// No aggregation stream with the given ID is open on the server (NOT_FOUND).
+	ErrorReason_STREAM_NOT_FOUND ErrorReason = 21
+	// The server is draining, and does not take new aggregation calls (UNAVAILABLE).
+	ErrorReason_DRAINING ErrorReason = 23
+	// A single request carried more inputs than the burst of the client's rate limit, so that it
+	//   could never be taken in reject mode, however long the client waited (INVALID_ARGUMENT).
+	ErrorReason_INPUTS_OVER_BURST ErrorReason = 24
+)
+
+// Enum value maps for ErrorReason.
+var (
+	ErrorReason_name = map[int32]string{
+		0:  "ERROR_REASON_UNSPECIFIED",
+		1:  "MISSING_TOKEN",
+		2:  "INVALID_TOKEN",
+		3:  "TOO_MANY_STREAMS",
+		4:  "TOO_MANY_STREAMS_PER_CLIENT",
+		5:  "TOO_MANY_INPUTS",
+		6:  "MESSAGE_TOO_LARGE",
+		7:  "STREAM_IDLE",
+		8:  "STREAM_TOO_LONG",
+		9:  "RATE_LIMITED",
+		10: "AGGREGATES_NOT_PERSISTED",
+		11: "INVALID_PAGE_TOKEN",
+		12: "STORE_FAILURE",
+		13: "SHUTTING_DOWN",
+		14: "SLOW_SUBSCRIBER",
+		15: "CALL_INTERRUPTED",
+		16: "INTERNAL_ERROR",
+		17: "REDUCER_NOT_ALLOWED",
+		18: "INVALID_INPUT",
+		19: "INVALID_VALIDATION_RULES",
+		20: "ADMIN_DISABLED",
+		21: "STREAM_NOT_FOUND",
+		23: "DRAINING",
+		24: "INPUTS_OVER_BURST",
+	}
+	ErrorReason_value = map[string]int32{
+		"ERROR_REASON_UNSPECIFIED":    0,
+		"MISSING_TOKEN":               1,
+		"INVALID_TOKEN":               2,
+		"TOO_MANY_STREAMS":            3,
+		"TOO_MANY_STREAMS_PER_CLIENT": 4,
+		"TOO_MANY_INPUTS":             5,
+		"MESSAGE_TOO_LARGE":           6,
+		"STREAM_IDLE":                 7,
+		"STREAM_TOO_LONG":             8,
+		"RATE_LIMITED":                9,
+		"AGGREGATES_NOT_PERSISTED":    10,
+		"INVALID_PAGE_TOKEN":          11,
+		"STORE_FAILURE":               12,
+		"SHUTTING_DOWN":               13,
+		"SLOW_SUBSCRIBER":             14,
+		"CALL_INTERRUPTED":            15,
+		"INTERNAL_ERROR":              16,
+		"REDUCER_NOT_ALLOWED":         17,
+		"INVALID_INPUT":               18,
+		"INVALID_VALIDATION_RULES":    19,
+		"ADMIN_DISABLED":              20,
+		"STREAM_NOT_FOUND":            21,
+		"DRAINING":                    23,
+		"INPUTS_OVER_BURST":           24,
+	}
+)
+
+func (x ErrorReason) Enum() *ErrorReason {
+	p := new(ErrorReason)
+	*p = x
+	return p
+}
+
+func (x ErrorReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_fewer_fewer_proto_enumTypes[1].Descriptor()
+}
+
+func (ErrorReason) Type() protoreflect.EnumType {
+	return &file_fewer_fewer_proto_enumTypes[1]
+}
+
+func (x ErrorReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorReason.Descriptor instead.
+func (ErrorReason) EnumDescriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{1}
+}
+
 // Message that a client sends over to the Fewer Service, representing some data to aggregate
 //
-//	with a few other aggregates sent at a particular point in time.
+//	with a few other aggregates sent at a particular point in time.  A request carries either a
+//	single number in input_num, or several numbers packed into input_nums, which the service
+//	aggregates in order as if they had been sent one by one.  If input_nums is not empty,
+//	input_num is ignored.
 type NumberRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	InputNum int32 `protobuf:"varint,1,opt,name=input_num,json=inputNum,proto3" json:"input_num,omitempty"`
+	InputNum  int32   `protobuf:"varint,1,opt,name=input_num,json=inputNum,proto3" json:"input_num,omitempty"`
+	InputNums []int32 `protobuf:"varint,2,rep,packed,name=input_nums,json=inputNums,proto3" json:"input_nums,omitempty"`
 }
 
 func (x *NumberRequest) Reset() {
@@ -68,6 +272,13 @@ func (x *NumberRequest) GetInputNum() int32 {
 	return 0
 }
 
+func (x *NumberRequest) GetInputNums() []int32 {
+	if x != nil {
+		return x.InputNums
+	}
+	return nil
+}
+
 // Message that the Fewer Service responds with after aggregating some individual messages of
 //
 //	data (in this case, NumberResult messages) representing an aggregate result.  In this case,
@@ -79,6 +290,15 @@ type NumberResponse struct {
 	unknownFields protoimpl.UnknownFields
 
 	Result int32 `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	// Position of the aggregate among the aggregates of its stream (counted from 0), and the
+	//   positions of the first and last stream inputs it covers (counted from 1).
+	WindowIndex int64 `protobuf:"varint,2,opt,name=window_index,json=windowIndex,proto3" json:"window_index,omitempty"`
+	FirstInput  int64 `protobuf:"varint,3,opt,name=first_input,json=firstInput,proto3" json:"first_input,omitempty"`
+	LastInput   int64 `protobuf:"varint,4,opt,name=last_input,json=lastInput,proto3" json:"last_input,omitempty"`
+	// Number of inputs of the window that broke the validation rules of the stream, and were left
+	//   out of the aggregate, or clamped to its bounds, as the invalid input policy asked.
+	SkippedInputs int64 `protobuf:"varint,5,opt,name=skipped_inputs,json=skippedInputs,proto3" json:"skipped_inputs,omitempty"`
+	ClampedInputs int64 `protobuf:"varint,6,opt,name=clamped_inputs,json=clampedInputs,proto3" json:"clamped_inputs,omitempty"`
 }
 
 func (x *NumberResponse) Reset() {
@@ -118,72 +338,1724 @@ func (x *NumberResponse) GetResult() int32 {
 	return 0
 }
 
-var File_fewer_fewer_proto protoreflect.FileDescriptor
+func (x *NumberResponse) GetWindowIndex() int64 {
+	if x != nil {
+		return x.WindowIndex
+	}
+	return 0
+}
 
-var file_fewer_fewer_proto_rawDesc = []byte{
-	0x0a, 0x11, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2f, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x12, 0x05, 0x66, 0x65, 0x77, 0x65, 0x72, 0x22, 0x2c, 0x0a, 0x0d, 0x4e, 0x75,
-	0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x69,
-	0x6e, 0x70, 0x75, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08,
-	0x69, 0x6e, 0x70, 0x75, 0x74, 0x4e, 0x75, 0x6d, 0x22, 0x28, 0x0a, 0x0e, 0x4e, 0x75, 0x6d, 0x62,
-	0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75,
-	0x6c, 0x74, 0x32, 0x58, 0x0a, 0x0c, 0x46, 0x65, 0x77, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x12, 0x48, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
-	0x74, 0x65, 0x73, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x14, 0x2e, 0x66, 0x65, 0x77, 0x65,
-	0x72, 0x2e, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x15, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x42, 0x2b, 0x5a, 0x29,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x73, 0x74, 0x72, 0x6f,
-	0x6e, 0x6f, 0x6d, 0x69, 0x63, 0x61, 0x6c, 0x33, 0x2f, 0x66, 0x65, 0x77, 0x65, 0x72, 0x5f, 0x67,
-	0x72, 0x70, 0x63, 0x2f, 0x66, 0x65, 0x77, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+func (x *NumberResponse) GetFirstInput() int64 {
+	if x != nil {
+		return x.FirstInput
+	}
+	return 0
 }
 
-var (
-	file_fewer_fewer_proto_rawDescOnce sync.Once
-	file_fewer_fewer_proto_rawDescData = file_fewer_fewer_proto_rawDesc
-)
+func (x *NumberResponse) GetLastInput() int64 {
+	if x != nil {
+		return x.LastInput
+	}
+	return 0
+}
 
-func file_fewer_fewer_proto_rawDescGZIP() []byte {
-	file_fewer_fewer_proto_rawDescOnce.Do(func() {
-		file_fewer_fewer_proto_rawDescData = protoimpl.X.CompressGZIP(file_fewer_fewer_proto_rawDescData)
-	})
-	return file_fewer_fewer_proto_rawDescData
+func (x *NumberResponse) GetSkippedInputs() int64 {
+	if x != nil {
+		return x.SkippedInputs
+	}
+	return 0
 }
 
-var file_fewer_fewer_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_fewer_fewer_proto_goTypes = []any{
-	(*NumberRequest)(nil),  // 0: fewer.NumberRequest
-	(*NumberResponse)(nil), // 1: fewer.NumberResponse
+func (x *NumberResponse) GetClampedInputs() int64 {
+	if x != nil {
+		return x.ClampedInputs
+	}
+	return 0
 }
-var file_fewer_fewer_proto_depIdxs = []int32{
-	0, // 0: fewer.FewerService.GetAggregatesStream:input_type -> fewer.NumberRequest
-	1, // 1: fewer.FewerService.GetAggregatesStream:output_type -> fewer.NumberResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+// Message that the Fewer Service periodically sends back to a client to acknowledge how many
+//
+//	NumberRequest messages it has processed so far on the stream.  Clients use it to keep only
+//	a limited number of unacknowledged inputs in flight.
+type InputAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProcessedInputs int64 `protobuf:"varint,1,opt,name=processed_inputs,json=processedInputs,proto3" json:"processed_inputs,omitempty"`
 }
 
-func init() { file_fewer_fewer_proto_init() }
-func file_fewer_fewer_proto_init() {
-	if File_fewer_fewer_proto != nil {
-		return
+func (x *InputAck) Reset() {
+	*x = InputAck{}
+	mi := &file_fewer_fewer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InputAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputAck) ProtoMessage() {}
+
+func (x *InputAck) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputAck.ProtoReflect.Descriptor instead.
+func (*InputAck) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InputAck) GetProcessedInputs() int64 {
+	if x != nil {
+		return x.ProcessedInputs
+	}
+	return 0
+}
+
+// Message sent on the GetAggregatesStream() response stream.  It is tagged with the kind of
+//
+//	payload it carries: an aggregate result, an acknowledgement of processed inputs, or the
+//	summary of the whole stream, which is the last message of a stream that ends successfully.
+//	The summary is also set as the "fewer-summary-bin" trailer of the stream, whether it ends
+//	successfully or not.
+type AggregatesStreamResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Payload:
+	//	*AggregatesStreamResponse_Aggregate
+	//	*AggregatesStreamResponse_Ack
+	//	*AggregatesStreamResponse_Summary
+	Payload isAggregatesStreamResponse_Payload `protobuf_oneof:"payload"`
+}
+
+func (x *AggregatesStreamResponse) Reset() {
+	*x = AggregatesStreamResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregatesStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregatesStreamResponse) ProtoMessage() {}
+
+func (x *AggregatesStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregatesStreamResponse.ProtoReflect.Descriptor instead.
+func (*AggregatesStreamResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *AggregatesStreamResponse) GetPayload() isAggregatesStreamResponse_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (x *AggregatesStreamResponse) GetAggregate() *NumberResponse {
+	if x, ok := x.GetPayload().(*AggregatesStreamResponse_Aggregate); ok {
+		return x.Aggregate
+	}
+	return nil
+}
+
+func (x *AggregatesStreamResponse) GetAck() *InputAck {
+	if x, ok := x.GetPayload().(*AggregatesStreamResponse_Ack); ok {
+		return x.Ack
+	}
+	return nil
+}
+
+func (x *AggregatesStreamResponse) GetSummary() *AggregationSummary {
+	if x, ok := x.GetPayload().(*AggregatesStreamResponse_Summary); ok {
+		return x.Summary
+	}
+	return nil
+}
+
+type isAggregatesStreamResponse_Payload interface {
+	isAggregatesStreamResponse_Payload()
+}
+
+type AggregatesStreamResponse_Aggregate struct {
+	Aggregate *NumberResponse `protobuf:"bytes,1,opt,name=aggregate,proto3,oneof"`
+}
+
+type AggregatesStreamResponse_Ack struct {
+	Ack *InputAck `protobuf:"bytes,2,opt,name=ack,proto3,oneof"`
+}
+
+type AggregatesStreamResponse_Summary struct {
+	Summary *AggregationSummary `protobuf:"bytes,3,opt,name=summary,proto3,oneof"`
+}
+
+func (*AggregatesStreamResponse_Aggregate) isAggregatesStreamResponse_Payload() {}
+
+func (*AggregatesStreamResponse_Ack) isAggregatesStreamResponse_Payload() {}
+
+func (*AggregatesStreamResponse_Summary) isAggregatesStreamResponse_Payload() {}
+
+// Message describing one aggregate that the Fewer Service sent back to a client on a
+//
+//	GetAggregatesStream() stream, as recorded by the server's aggregate store.
+type StoredAggregate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId string `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Key      string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// Position of the aggregate among the aggregates of its stream (counted from 0), and the
+	//   positions of the first and last stream inputs it covers (counted from 1).
+	WindowIndex     int64                  `protobuf:"varint,3,opt,name=window_index,json=windowIndex,proto3" json:"window_index,omitempty"`
+	FirstInput      int64                  `protobuf:"varint,4,opt,name=first_input,json=firstInput,proto3" json:"first_input,omitempty"`
+	LastInput       int64                  `protobuf:"varint,5,opt,name=last_input,json=lastInput,proto3" json:"last_input,omitempty"`
+	Reducer         string                 `protobuf:"bytes,6,opt,name=reducer,proto3" json:"reducer,omitempty"`
+	Value           int64                  `protobuf:"varint,7,opt,name=value,proto3" json:"value,omitempty"`
+	Partial         bool                   `protobuf:"varint,8,opt,name=partial,proto3" json:"partial,omitempty"`
+	StreamStartedAt *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=stream_started_at,json=streamStartedAt,proto3" json:"stream_started_at,omitempty"`
+	WindowStartedAt *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=window_started_at,json=windowStartedAt,proto3" json:"window_started_at,omitempty"`
+	EmittedAt       *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=emitted_at,json=emittedAt,proto3" json:"emitted_at,omitempty"`
+	Tenant          string                 `protobuf:"bytes,12,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	SkippedInputs   int64                  `protobuf:"varint,13,opt,name=skipped_inputs,json=skippedInputs,proto3" json:"skipped_inputs,omitempty"`
+	ClampedInputs   int64                  `protobuf:"varint,14,opt,name=clamped_inputs,json=clampedInputs,proto3" json:"clamped_inputs,omitempty"`
+}
+
+func (x *StoredAggregate) Reset() {
+	*x = StoredAggregate{}
+	mi := &file_fewer_fewer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredAggregate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredAggregate) ProtoMessage() {}
+
+func (x *StoredAggregate) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredAggregate.ProtoReflect.Descriptor instead.
+func (*StoredAggregate) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StoredAggregate) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *StoredAggregate) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *StoredAggregate) GetWindowIndex() int64 {
+	if x != nil {
+		return x.WindowIndex
+	}
+	return 0
+}
+
+func (x *StoredAggregate) GetFirstInput() int64 {
+	if x != nil {
+		return x.FirstInput
+	}
+	return 0
+}
+
+func (x *StoredAggregate) GetLastInput() int64 {
+	if x != nil {
+		return x.LastInput
+	}
+	return 0
+}
+
+func (x *StoredAggregate) GetReducer() string {
+	if x != nil {
+		return x.Reducer
+	}
+	return ""
+}
+
+func (x *StoredAggregate) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *StoredAggregate) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *StoredAggregate) GetStreamStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StreamStartedAt
+	}
+	return nil
+}
+
+func (x *StoredAggregate) GetWindowStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WindowStartedAt
+	}
+	return nil
+}
+
+func (x *StoredAggregate) GetEmittedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EmittedAt
+	}
+	return nil
+}
+
+func (x *StoredAggregate) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *StoredAggregate) GetSkippedInputs() int64 {
+	if x != nil {
+		return x.SkippedInputs
+	}
+	return 0
+}
+
+func (x *StoredAggregate) GetClampedInputs() int64 {
+	if x != nil {
+		return x.ClampedInputs
+	}
+	return 0
+}
+
+// Message that a client sends to the QueryAggregates() RPC to read back persisted aggregates.
+//
+//	Empty filters match every aggregate.
+type QueryAggregatesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId string `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Key      string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// Only aggregates emitted at or after emitted_after, and before emitted_before, are returned.
+	EmittedAfter  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=emitted_after,json=emittedAfter,proto3" json:"emitted_after,omitempty"`
+	EmittedBefore *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=emitted_before,json=emittedBefore,proto3" json:"emitted_before,omitempty"`
+	// Maximum number of aggregates per streamed page.  The server picks a default when unset.
+	PageSize int32 `protobuf:"varint,5,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// Token of the page to resume from, as returned in a previous QueryAggregatesResponse.
+	PageToken string `protobuf:"bytes,6,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Maximum number of aggregates to return in total.  0 means no limit.
+	Limit int32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *QueryAggregatesRequest) Reset() {
+	*x = QueryAggregatesRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryAggregatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryAggregatesRequest) ProtoMessage() {}
+
+func (x *QueryAggregatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryAggregatesRequest.ProtoReflect.Descriptor instead.
+func (*QueryAggregatesRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *QueryAggregatesRequest) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *QueryAggregatesRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *QueryAggregatesRequest) GetEmittedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EmittedAfter
+	}
+	return nil
+}
+
+func (x *QueryAggregatesRequest) GetEmittedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EmittedBefore
+	}
+	return nil
+}
+
+func (x *QueryAggregatesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *QueryAggregatesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *QueryAggregatesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// Message that the QueryAggregates() RPC streams back, holding one page of matching aggregates.
+//
+//	next_page_token can be sent in a later QueryAggregatesRequest to resume right after this
+//	page, and is empty once there are no more matching aggregates.
+type QueryAggregatesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Aggregates    []*StoredAggregate `protobuf:"bytes,1,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
+	NextPageToken string             `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+}
+
+func (x *QueryAggregatesResponse) Reset() {
+	*x = QueryAggregatesResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryAggregatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryAggregatesResponse) ProtoMessage() {}
+
+func (x *QueryAggregatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryAggregatesResponse.ProtoReflect.Descriptor instead.
+func (*QueryAggregatesResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *QueryAggregatesResponse) GetAggregates() []*StoredAggregate {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
+func (x *QueryAggregatesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Message that a client sends to the SubscribeAggregates() RPC to watch the aggregates that the
+//
+//	Fewer Service emits on GetAggregatesStream() streams opened by other clients.  Empty filters
+//	match every stream.
+type SubscribeAggregatesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key    string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Tenant string `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// Number of events buffered for the subscriber before the slow consumer policy applies.
+	//   The server picks a default when unset.
+	BufferSize         uint32             `protobuf:"varint,3,opt,name=buffer_size,json=bufferSize,proto3" json:"buffer_size,omitempty"`
+	SlowConsumerPolicy SlowConsumerPolicy `protobuf:"varint,4,opt,name=slow_consumer_policy,json=slowConsumerPolicy,proto3,enum=fewer.SlowConsumerPolicy" json:"slow_consumer_policy,omitempty"`
+}
+
+func (x *SubscribeAggregatesRequest) Reset() {
+	*x = SubscribeAggregatesRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeAggregatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeAggregatesRequest) ProtoMessage() {}
+
+func (x *SubscribeAggregatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeAggregatesRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeAggregatesRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SubscribeAggregatesRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SubscribeAggregatesRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *SubscribeAggregatesRequest) GetBufferSize() uint32 {
+	if x != nil {
+		return x.BufferSize
+	}
+	return 0
+}
+
+func (x *SubscribeAggregatesRequest) GetSlowConsumerPolicy() SlowConsumerPolicy {
+	if x != nil {
+		return x.SlowConsumerPolicy
+	}
+	return SlowConsumerPolicy_SLOW_CONSUMER_POLICY_UNSPECIFIED
+}
+
+// Message that the SubscribeAggregates() RPC streams back for every aggregate emitted on a
+//
+//	matching stream.
+type AggregateEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId  string          `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Key       string          `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Tenant    string          `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Aggregate *NumberResponse `protobuf:"bytes,4,opt,name=aggregate,proto3" json:"aggregate,omitempty"`
+	// Whether the aggregate is a residual sum of a batch that was not full at end of stream.
+	Partial   bool                   `protobuf:"varint,5,opt,name=partial,proto3" json:"partial,omitempty"`
+	EmittedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=emitted_at,json=emittedAt,proto3" json:"emitted_at,omitempty"`
+	// Number of events dropped for this subscriber since the previous delivered event.
+	DroppedBefore uint64 `protobuf:"varint,7,opt,name=dropped_before,json=droppedBefore,proto3" json:"dropped_before,omitempty"`
+}
+
+func (x *AggregateEvent) Reset() {
+	*x = AggregateEvent{}
+	mi := &file_fewer_fewer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateEvent) ProtoMessage() {}
+
+func (x *AggregateEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateEvent.ProtoReflect.Descriptor instead.
+func (*AggregateEvent) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AggregateEvent) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *AggregateEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *AggregateEvent) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *AggregateEvent) GetAggregate() *NumberResponse {
+	if x != nil {
+		return x.Aggregate
+	}
+	return nil
+}
+
+func (x *AggregateEvent) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *AggregateEvent) GetEmittedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EmittedAt
+	}
+	return nil
+}
+
+func (x *AggregateEvent) GetDroppedBefore() uint64 {
+	if x != nil {
+		return x.DroppedBefore
+	}
+	return 0
+}
+
+// Message that a client sends to the AggregateBatch() RPC, holding all of the numbers to aggregate
+//
+//	at once.
+type AggregateBatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InputNums []int32 `protobuf:"varint,1,rep,packed,name=input_nums,json=inputNums,proto3" json:"input_nums,omitempty"`
+}
+
+func (x *AggregateBatchRequest) Reset() {
+	*x = AggregateBatchRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateBatchRequest) ProtoMessage() {}
+
+func (x *AggregateBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateBatchRequest.ProtoReflect.Descriptor instead.
+func (*AggregateBatchRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AggregateBatchRequest) GetInputNums() []int32 {
+	if x != nil {
+		return x.InputNums
+	}
+	return nil
+}
+
+// Message that the AggregateBatch() RPC responds with, holding every aggregate of the batch (the
+//
+//	same aggregates GetAggregatesStream() would have streamed back), and a summary of the batch.
+type AggregateBatchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*NumberResponse   `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Summary *AggregationSummary `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`
+}
+
+func (x *AggregateBatchResponse) Reset() {
+	*x = AggregateBatchResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateBatchResponse) ProtoMessage() {}
+
+func (x *AggregateBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateBatchResponse.ProtoReflect.Descriptor instead.
+func (*AggregateBatchResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AggregateBatchResponse) GetResults() []*NumberResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *AggregateBatchResponse) GetSummary() *AggregationSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+// Message summarizing all the numbers aggregated by one call of an aggregation RPC.  It is the
+//
+//	single response of the AggregateUpload() RPC, and the last message of a GetAggregatesStream()
+//	stream.
+type AggregationSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId     string `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	TotalInputs  int64  `protobuf:"varint,2,opt,name=total_inputs,json=totalInputs,proto3" json:"total_inputs,omitempty"`
+	TotalBatches int64  `protobuf:"varint,3,opt,name=total_batches,json=totalBatches,proto3" json:"total_batches,omitempty"`
+	// Sum of all aggregates, i.e., of all numbers received.
+	GrandTotal int64 `protobuf:"varint,4,opt,name=grand_total,json=grandTotal,proto3" json:"grand_total,omitempty"`
+	// Whether the last aggregate is a residual sum of a batch that was not full.
+	PartialLastBatch bool `protobuf:"varint,5,opt,name=partial_last_batch,json=partialLastBatch,proto3" json:"partial_last_batch,omitempty"`
+	// Number of inputs that broke the validation rules of the call, and were skipped or clamped.
+	SkippedInputs int64 `protobuf:"varint,6,opt,name=skipped_inputs,json=skippedInputs,proto3" json:"skipped_inputs,omitempty"`
+	ClampedInputs int64 `protobuf:"varint,7,opt,name=clamped_inputs,json=clampedInputs,proto3" json:"clamped_inputs,omitempty"`
+	// Smallest and largest input aggregated (after clamping, and leaving out skipped inputs), or 0
+	//   if no input was aggregated.
+	MinInput int32 `protobuf:"varint,8,opt,name=min_input,json=minInput,proto3" json:"min_input,omitempty"`
+	MaxInput int32 `protobuf:"varint,9,opt,name=max_input,json=maxInput,proto3" json:"max_input,omitempty"`
+	// Time from the start of the call to the summary.
+	Duration *durationpb.Duration `protobuf:"bytes,10,opt,name=duration,proto3" json:"duration,omitempty"`
+}
+
+func (x *AggregationSummary) Reset() {
+	*x = AggregationSummary{}
+	mi := &file_fewer_fewer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregationSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregationSummary) ProtoMessage() {}
+
+func (x *AggregationSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregationSummary.ProtoReflect.Descriptor instead.
+func (*AggregationSummary) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AggregationSummary) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *AggregationSummary) GetTotalInputs() int64 {
+	if x != nil {
+		return x.TotalInputs
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetTotalBatches() int64 {
+	if x != nil {
+		return x.TotalBatches
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetGrandTotal() int64 {
+	if x != nil {
+		return x.GrandTotal
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetPartialLastBatch() bool {
+	if x != nil {
+		return x.PartialLastBatch
+	}
+	return false
+}
+
+func (x *AggregationSummary) GetSkippedInputs() int64 {
+	if x != nil {
+		return x.SkippedInputs
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetClampedInputs() int64 {
+	if x != nil {
+		return x.ClampedInputs
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetMinInput() int32 {
+	if x != nil {
+		return x.MinInput
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetMaxInput() int32 {
+	if x != nil {
+		return x.MaxInput
+	}
+	return 0
+}
+
+func (x *AggregationSummary) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+// Message that an operator sends to the ListStreams() RPC of the FewerAdmin service.
+type ListStreamsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListStreamsRequest) Reset() {
+	*x = ListStreamsRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStreamsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStreamsRequest) ProtoMessage() {}
+
+func (x *ListStreamsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStreamsRequest.ProtoReflect.Descriptor instead.
+func (*ListStreamsRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{12}
+}
+
+// Message describing an aggregation stream (a GetAggregatesStream() or AggregateUpload() stream)
+//
+//	open on the server, as it is at the time of the call.
+type ActiveStream struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId string `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	// Name of the RPC of the stream (e.g., "GetAggregatesStream").
+	Method string `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	// Address of the client of the stream.
+	Peer      string                 `protobuf:"bytes,3,opt,name=peer,proto3" json:"peer,omitempty"`
+	Key       string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	Tenant    string                 `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	StartedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// Number of inputs received on the stream so far, and sum of the inputs of the current batch,
+	//   which has not been sent back yet.
+	InputsSeen int64 `protobuf:"varint,7,opt,name=inputs_seen,json=inputsSeen,proto3" json:"inputs_seen,omitempty"`
+	PartialSum int64 `protobuf:"varint,8,opt,name=partial_sum,json=partialSum,proto3" json:"partial_sum,omitempty"`
+	// Reducer the inputs of a batch are aggregated with, and number of batches emitted so far.
+	Reducer string `protobuf:"bytes,9,opt,name=reducer,proto3" json:"reducer,omitempty"`
+	Batches int64  `protobuf:"varint,10,opt,name=batches,proto3" json:"batches,omitempty"`
+}
+
+func (x *ActiveStream) Reset() {
+	*x = ActiveStream{}
+	mi := &file_fewer_fewer_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActiveStream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActiveStream) ProtoMessage() {}
+
+func (x *ActiveStream) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActiveStream.ProtoReflect.Descriptor instead.
+func (*ActiveStream) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ActiveStream) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *ActiveStream) GetInputsSeen() int64 {
+	if x != nil {
+		return x.InputsSeen
+	}
+	return 0
+}
+
+func (x *ActiveStream) GetPartialSum() int64 {
+	if x != nil {
+		return x.PartialSum
+	}
+	return 0
+}
+
+func (x *ActiveStream) GetReducer() string {
+	if x != nil {
+		return x.Reducer
+	}
+	return ""
+}
+
+func (x *ActiveStream) GetBatches() int64 {
+	if x != nil {
+		return x.Batches
+	}
+	return 0
+}
+
+// Message that the ListStreams() RPC responds with, holding every open aggregation stream, oldest
+//
+//	first, and whether the server is draining.
+type ListStreamsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Streams  []*ActiveStream `protobuf:"bytes,1,rep,name=streams,proto3" json:"streams,omitempty"`
+	Draining bool            `protobuf:"varint,2,opt,name=draining,proto3" json:"draining,omitempty"`
+}
+
+func (x *ListStreamsResponse) Reset() {
+	*x = ListStreamsResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStreamsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStreamsResponse) ProtoMessage() {}
+
+func (x *ListStreamsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStreamsResponse.ProtoReflect.Descriptor instead.
+func (*ListStreamsResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListStreamsResponse) GetStreams() []*ActiveStream {
+	if x != nil {
+		return x.Streams
+	}
+	return nil
+}
+
+func (x *ListStreamsResponse) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+// Message that an operator sends to the CancelStream() RPC to end an open aggregation stream.  Its
+//
+//	client gets a CANCELLED status, with the reason in the "fewer-cancel-reason" trailer.
+type CancelStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StreamId string `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Reason   string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *CancelStreamRequest) Reset() {
+	*x = CancelStreamRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelStreamRequest) ProtoMessage() {}
+
+func (x *CancelStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelStreamRequest.ProtoReflect.Descriptor instead.
+func (*CancelStreamRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CancelStreamRequest) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *CancelStreamRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Message that the CancelStream() RPC responds with, describing the stream as it was when it was
+//
+//	cancelled.
+type CancelStreamResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Stream *ActiveStream `protobuf:"bytes,1,opt,name=stream,proto3" json:"stream,omitempty"`
+}
+
+func (x *CancelStreamResponse) Reset() {
+	*x = CancelStreamResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelStreamResponse) ProtoMessage() {}
+
+func (x *CancelStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelStreamResponse.ProtoReflect.Descriptor instead.
+func (*CancelStreamResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CancelStreamResponse) GetStream() *ActiveStream {
+	if x != nil {
+		return x.Stream
+	}
+	return nil
+}
+
+// Message that an operator sends to the Drain() RPC.  A draining server rejects new aggregation
+//
+//	calls, and lets the open streams run to their end.  If shutdown is set, the server then shuts
+//	down gracefully.
+type DrainRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Shutdown bool `protobuf:"varint,1,opt,name=shutdown,proto3" json:"shutdown,omitempty"`
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_fewer_fewer_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DrainRequest) GetShutdown() bool {
+	if x != nil {
+		return x.Shutdown
+	}
+	return false
+}
+
+// Message that the Drain() RPC responds with, holding the number of aggregation streams that were
+//
+//	still open when the server started draining.
+type DrainResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	OpenStreams int32 `protobuf:"varint,1,opt,name=open_streams,json=openStreams,proto3" json:"open_streams,omitempty"`
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_fewer_fewer_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fewer_fewer_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_fewer_fewer_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DrainResponse) GetOpenStreams() int32 {
+	if x != nil {
+		return x.OpenStreams
+	}
+	return 0
+}
+
+var File_fewer_fewer_proto protoreflect.FileDescriptor
+
+var file_fewer_fewer_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2f, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x05, 0x66, 0x65, 0x77, 0x65, 0x72, 0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x4b, 0x0a, 0x0d, 0x4e,
+	0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x69, 0x6e, 0x70, 0x75, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x08, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x4e, 0x75, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x6e, 0x70,
+	0x75, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x09, 0x69,
+	0x6e, 0x70, 0x75, 0x74, 0x4e, 0x75, 0x6d, 0x73, 0x22, 0xd9, 0x01, 0x0a, 0x0e, 0x4e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x72, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x77, 0x69, 0x6e, 0x64, 0x6f,
+	0x77, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f,
+	0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x66, 0x69, 0x72,
+	0x73, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f,
+	0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c, 0x61, 0x73,
+	0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x6b, 0x69, 0x70, 0x70, 0x65,
+	0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d,
+	0x73, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x25, 0x0a,
+	0x0e, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65, 0x64, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x73, 0x22, 0x35, 0x0a, 0x08, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x41, 0x63, 0x6b,
+	0x12, 0x29, 0x0a, 0x10, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64, 0x5f, 0x69, 0x6e,
+	0x70, 0x75, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x63,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x22, 0xb8, 0x01, 0x0a, 0x18,
+	0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x09, 0x61, 0x67, 0x67, 0x72,
+	0x65, 0x67, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x66, 0x65,
+	0x77, 0x65, 0x72, 0x2e, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x48, 0x00, 0x52, 0x09, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x12,
+	0x23, 0x0a, 0x03, 0x61, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x66,
+	0x65, 0x77, 0x65, 0x72, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x41, 0x63, 0x6b, 0x48, 0x00, 0x52,
+	0x03, 0x61, 0x63, 0x6b, 0x12, 0x35, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x41, 0x67,
+	0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79,
+	0x48, 0x00, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x42, 0x09, 0x0a, 0x07, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x9e, 0x04, 0x0a, 0x0f, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x64, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x77, 0x69, 0x6e,
+	0x64, 0x6f, 0x77, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0b, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1f, 0x0a, 0x0b,
+	0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x66, 0x69, 0x72, 0x73, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x1d, 0x0a,
+	0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x18, 0x0a, 0x07,
+	0x72, 0x65, 0x64, 0x75, 0x63, 0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72,
+	0x65, 0x64, 0x75, 0x63, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70,
+	0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x46, 0x0a, 0x11, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0f, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x46,
+	0x0a, 0x11, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64,
+	0x5f, 0x61, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0f, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x53, 0x74, 0x61,
+	0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x39, 0x0a, 0x0a, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65,
+	0x64, 0x5f, 0x61, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x41,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x6b, 0x69,
+	0x70, 0x70, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18, 0x0d, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0d, 0x73, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73,
+	0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75,
+	0x74, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65,
+	0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x22, 0x9d, 0x02, 0x0a, 0x16, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x3f, 0x0a, 0x0d, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x66, 0x74,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x0c, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x41, 0x66, 0x74,
+	0x65, 0x72, 0x12, 0x41, 0x0a, 0x0e, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x5f, 0x62, 0x65,
+	0x66, 0x6f, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0d, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x42,
+	0x65, 0x66, 0x6f, 0x72, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69,
+	0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65,
+	0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x79, 0x0a, 0x17, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x36, 0x0a, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x64, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x52, 0x0a,
+	0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x65,
+	0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78, 0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x22, 0xb4, 0x01, 0x0a, 0x1a, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
+	0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x62,
+	0x75, 0x66, 0x66, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0a, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x4b, 0x0a, 0x14,
+	0x73, 0x6c, 0x6f, 0x77, 0x5f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x5f, 0x70, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x66, 0x65, 0x77,
+	0x65, 0x72, 0x2e, 0x53, 0x6c, 0x6f, 0x77, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x12, 0x73, 0x6c, 0x6f, 0x77, 0x43, 0x6f, 0x6e, 0x73, 0x75,
+	0x6d, 0x65, 0x72, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x22, 0x88, 0x02, 0x0a, 0x0e, 0x41, 0x67,
+	0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x74,
+	0x65, 0x6e, 0x61, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x65, 0x6e,
+	0x61, 0x6e, 0x74, 0x12, 0x33, 0x0a, 0x09, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x4e,
+	0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09, 0x61,
+	0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x74,
+	0x69, 0x61, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x61, 0x6c, 0x12, 0x39, 0x0a, 0x0a, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x09, 0x65, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x25, 0x0a,
+	0x0e, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x42, 0x65,
+	0x66, 0x6f, 0x72, 0x65, 0x22, 0x36, 0x0a, 0x15, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74,
+	0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a,
+	0x0a, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x05, 0x52, 0x09, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x4e, 0x75, 0x6d, 0x73, 0x22, 0x7e, 0x0a, 0x16,
+	0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e,
+	0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x07,
+	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x33, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61,
+	0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72,
+	0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x75, 0x6d, 0x6d,
+	0x61, 0x72, 0x79, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x22, 0x87, 0x03, 0x0a,
+	0x12, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x75, 0x6d, 0x6d,
+	0x61, 0x72, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64,
+	0x12, 0x21, 0x0a, 0x0c, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x49, 0x6e, 0x70,
+	0x75, 0x74, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x62, 0x61, 0x74,
+	0x63, 0x68, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x42, 0x61, 0x74, 0x63, 0x68, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x67, 0x72, 0x61, 0x6e,
+	0x64, 0x5f, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x67,
+	0x72, 0x61, 0x6e, 0x64, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x2c, 0x0a, 0x12, 0x70, 0x61, 0x72,
+	0x74, 0x69, 0x61, 0x6c, 0x5f, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x62, 0x61, 0x74, 0x63, 0x68, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x4c, 0x61,
+	0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x6b, 0x69, 0x70, 0x70,
+	0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0d, 0x73, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x63, 0x6c, 0x61, 0x6d, 0x70, 0x65, 0x64, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x69, 0x6e, 0x5f, 0x69, 0x6e, 0x70,
+	0x75, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x6d, 0x69, 0x6e, 0x49, 0x6e, 0x70,
+	0x75, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12,
+	0x35, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x64, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x14, 0x0a, 0x12, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xb2, 0x02, 0x0a,
+	0x0c, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1b, 0x0a,
+	0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65,
+	0x74, 0x68, 0x6f, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x65, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x70, 0x65, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x65, 0x6e, 0x61,
+	0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74,
+	0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x69,
+	0x6e, 0x70, 0x75, 0x74, 0x73, 0x5f, 0x73, 0x65, 0x65, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x53, 0x65, 0x65, 0x6e, 0x12, 0x1f, 0x0a, 0x0b,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x73, 0x75, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x75, 0x6d, 0x12, 0x18, 0x0a,
+	0x07, 0x72, 0x65, 0x64, 0x75, 0x63, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x72, 0x65, 0x64, 0x75, 0x63, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x61, 0x74, 0x63, 0x68,
+	0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x62, 0x61, 0x74, 0x63, 0x68, 0x65,
+	0x73, 0x22, 0x60, 0x0a, 0x13, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x07, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x66, 0x65, 0x77, 0x65,
+	0x72, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x07,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x72, 0x61, 0x69, 0x6e,
+	0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x72, 0x61, 0x69, 0x6e,
+	0x69, 0x6e, 0x67, 0x22, 0x4a, 0x0a, 0x13, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22,
+	0x43, 0x0a, 0x14, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e,
+	0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x06, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x22, 0x2a, 0x0a, 0x0c, 0x44, 0x72, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e,
+	0x22, 0x32, 0x0a, 0x0d, 0x44, 0x72, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x70, 0x65, 0x6e, 0x5f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x6f, 0x70, 0x65, 0x6e, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x73, 0x2a, 0x7e, 0x0a, 0x12, 0x53, 0x6c, 0x6f, 0x77, 0x43, 0x6f, 0x6e, 0x73,
+	0x75, 0x6d, 0x65, 0x72, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x24, 0x0a, 0x20, 0x53, 0x4c,
+	0x4f, 0x57, 0x5f, 0x43, 0x4f, 0x4e, 0x53, 0x55, 0x4d, 0x45, 0x52, 0x5f, 0x50, 0x4f, 0x4c, 0x49,
+	0x43, 0x59, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00,
+	0x12, 0x1d, 0x0a, 0x19, 0x53, 0x4c, 0x4f, 0x57, 0x5f, 0x43, 0x4f, 0x4e, 0x53, 0x55, 0x4d, 0x45,
+	0x52, 0x5f, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x44, 0x52, 0x4f, 0x50, 0x10, 0x01, 0x12,
+	0x23, 0x0a, 0x1f, 0x53, 0x4c, 0x4f, 0x57, 0x5f, 0x43, 0x4f, 0x4e, 0x53, 0x55, 0x4d, 0x45, 0x52,
+	0x5f, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x44, 0x49, 0x53, 0x43, 0x4f, 0x4e, 0x4e, 0x45,
+	0x43, 0x54, 0x10, 0x02, 0x2a, 0xb8, 0x04, 0x0a, 0x0b, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x18, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x52, 0x45,
+	0x41, 0x53, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44,
+	0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4d, 0x49, 0x53, 0x53, 0x49, 0x4e, 0x47, 0x5f, 0x54, 0x4f,
+	0x4b, 0x45, 0x4e, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44,
+	0x5f, 0x54, 0x4f, 0x4b, 0x45, 0x4e, 0x10, 0x02, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x4f, 0x4f, 0x5f,
+	0x4d, 0x41, 0x4e, 0x59, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x53, 0x10, 0x03, 0x12, 0x1f,
+	0x0a, 0x1b, 0x54, 0x4f, 0x4f, 0x5f, 0x4d, 0x41, 0x4e, 0x59, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41,
+	0x4d, 0x53, 0x5f, 0x50, 0x45, 0x52, 0x5f, 0x43, 0x4c, 0x49, 0x45, 0x4e, 0x54, 0x10, 0x04, 0x12,
+	0x13, 0x0a, 0x0f, 0x54, 0x4f, 0x4f, 0x5f, 0x4d, 0x41, 0x4e, 0x59, 0x5f, 0x49, 0x4e, 0x50, 0x55,
+	0x54, 0x53, 0x10, 0x05, 0x12, 0x15, 0x0a, 0x11, 0x4d, 0x45, 0x53, 0x53, 0x41, 0x47, 0x45, 0x5f,
+	0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x41, 0x52, 0x47, 0x45, 0x10, 0x06, 0x12, 0x0f, 0x0a, 0x0b, 0x53,
+	0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x49, 0x44, 0x4c, 0x45, 0x10, 0x07, 0x12, 0x13, 0x0a, 0x0f,
+	0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x4f, 0x4e, 0x47, 0x10,
+	0x08, 0x12, 0x10, 0x0a, 0x0c, 0x52, 0x41, 0x54, 0x45, 0x5f, 0x4c, 0x49, 0x4d, 0x49, 0x54, 0x45,
+	0x44, 0x10, 0x09, 0x12, 0x1c, 0x0a, 0x18, 0x41, 0x47, 0x47, 0x52, 0x45, 0x47, 0x41, 0x54, 0x45,
+	0x53, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x50, 0x45, 0x52, 0x53, 0x49, 0x53, 0x54, 0x45, 0x44, 0x10,
+	0x0a, 0x12, 0x16, 0x0a, 0x12, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x5f, 0x50, 0x41, 0x47,
+	0x45, 0x5f, 0x54, 0x4f, 0x4b, 0x45, 0x4e, 0x10, 0x0b, 0x12, 0x11, 0x0a, 0x0d, 0x53, 0x54, 0x4f,
+	0x52, 0x45, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x55, 0x52, 0x45, 0x10, 0x0c, 0x12, 0x11, 0x0a, 0x0d,
+	0x53, 0x48, 0x55, 0x54, 0x54, 0x49, 0x4e, 0x47, 0x5f, 0x44, 0x4f, 0x57, 0x4e, 0x10, 0x0d, 0x12,
+	0x13, 0x0a, 0x0f, 0x53, 0x4c, 0x4f, 0x57, 0x5f, 0x53, 0x55, 0x42, 0x53, 0x43, 0x52, 0x49, 0x42,
+	0x45, 0x52, 0x10, 0x0e, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x41, 0x4c, 0x4c, 0x5f, 0x49, 0x4e, 0x54,
+	0x45, 0x52, 0x52, 0x55, 0x50, 0x54, 0x45, 0x44, 0x10, 0x0f, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e,
+	0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x10, 0x12, 0x17,
+	0x0a, 0x13, 0x52, 0x45, 0x44, 0x55, 0x43, 0x45, 0x52, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x41, 0x4c,
+	0x4c, 0x4f, 0x57, 0x45, 0x44, 0x10, 0x11, 0x12, 0x11, 0x0a, 0x0d, 0x49, 0x4e, 0x56, 0x41, 0x4c,
+	0x49, 0x44, 0x5f, 0x49, 0x4e, 0x50, 0x55, 0x54, 0x10, 0x12, 0x12, 0x1c, 0x0a, 0x18, 0x49, 0x4e,
+	0x56, 0x41, 0x4c, 0x49, 0x44, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x52, 0x55, 0x4c, 0x45, 0x53, 0x10, 0x13, 0x12, 0x12, 0x0a, 0x0e, 0x41, 0x44, 0x4d, 0x49,
+	0x4e, 0x5f, 0x44, 0x49, 0x53, 0x41, 0x42, 0x4c, 0x45, 0x44, 0x10, 0x14, 0x12, 0x14, 0x0a, 0x10,
+	0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x46, 0x4f, 0x55, 0x4e, 0x44,
+	0x10, 0x15, 0x12, 0x0c, 0x0a, 0x08, 0x44, 0x52, 0x41, 0x49, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x17,
+	0x12, 0x15, 0x0a, 0x11, 0x49, 0x4e, 0x50, 0x55, 0x54, 0x53, 0x5f, 0x4f, 0x56, 0x45, 0x52, 0x5f,
+	0x42, 0x55, 0x52, 0x53, 0x54, 0x10, 0x18, 0x22, 0x04, 0x08, 0x16, 0x10, 0x16, 0x2a, 0x10, 0x53,
+	0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x4c, 0x45, 0x44, 0x32,
+	0xa6, 0x03, 0x0a, 0x0c, 0x46, 0x65, 0x77, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x12, 0x52, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x73, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x14, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e,
+	0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e,
+	0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x28, 0x01, 0x30, 0x01, 0x12, 0x54, 0x0a, 0x0f, 0x51, 0x75, 0x65, 0x72, 0x79, 0x41, 0x67, 0x67,
+	0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x53, 0x0a, 0x13, 0x53, 0x75,
+	0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x73, 0x12, 0x21, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72,
+	0x69, 0x62, 0x65, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x41, 0x67, 0x67,
+	0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12,
+	0x4f, 0x0a, 0x0e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x12, 0x1c, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67,
+	0x61, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1d, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74,
+	0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x46, 0x0a, 0x0f, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x55, 0x70, 0x6c,
+	0x6f, 0x61, 0x64, 0x12, 0x14, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x4e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x66, 0x65, 0x77, 0x65,
+	0x72, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x22, 0x00, 0x28, 0x01, 0x32, 0xd5, 0x01, 0x0a, 0x0a, 0x46, 0x65, 0x77,
+	0x65, 0x72, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x12, 0x46, 0x0a, 0x0b, 0x4c, 0x69, 0x73, 0x74, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x12, 0x19, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1a, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
+	0x49, 0x0a, 0x0c, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12,
+	0x1a, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x66, 0x65,
+	0x77, 0x65, 0x72, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x34, 0x0a, 0x05, 0x44, 0x72,
+	0x61, 0x69, 0x6e, 0x12, 0x13, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72, 0x2e, 0x44, 0x72, 0x61, 0x69,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x66, 0x65, 0x77, 0x65, 0x72,
+	0x2e, 0x44, 0x72, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x42, 0x2b, 0x5a, 0x29, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61,
+	0x73, 0x74, 0x72, 0x6f, 0x6e, 0x6f, 0x6d, 0x69, 0x63, 0x61, 0x6c, 0x33, 0x2f, 0x66, 0x65, 0x77,
+	0x65, 0x72, 0x5f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x66, 0x65, 0x77, 0x65, 0x72, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_fewer_fewer_proto_rawDescOnce sync.Once
+	file_fewer_fewer_proto_rawDescData = file_fewer_fewer_proto_rawDesc
+)
+
+func file_fewer_fewer_proto_rawDescGZIP() []byte {
+	file_fewer_fewer_proto_rawDescOnce.Do(func() {
+		file_fewer_fewer_proto_rawDescData = protoimpl.X.CompressGZIP(file_fewer_fewer_proto_rawDescData)
+	})
+	return file_fewer_fewer_proto_rawDescData
+}
+
+var file_fewer_fewer_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_fewer_fewer_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_fewer_fewer_proto_goTypes = []any{
+	(SlowConsumerPolicy)(0),            // 0: fewer.SlowConsumerPolicy
+	(ErrorReason)(0),                   // 1: fewer.ErrorReason
+	(*NumberRequest)(nil),              // 2: fewer.NumberRequest
+	(*NumberResponse)(nil),             // 3: fewer.NumberResponse
+	(*InputAck)(nil),                   // 4: fewer.InputAck
+	(*AggregatesStreamResponse)(nil),   // 5: fewer.AggregatesStreamResponse
+	(*StoredAggregate)(nil),            // 6: fewer.StoredAggregate
+	(*QueryAggregatesRequest)(nil),     // 7: fewer.QueryAggregatesRequest
+	(*QueryAggregatesResponse)(nil),    // 8: fewer.QueryAggregatesResponse
+	(*SubscribeAggregatesRequest)(nil), // 9: fewer.SubscribeAggregatesRequest
+	(*AggregateEvent)(nil),             // 10: fewer.AggregateEvent
+	(*AggregateBatchRequest)(nil),      // 11: fewer.AggregateBatchRequest
+	(*AggregateBatchResponse)(nil),     // 12: fewer.AggregateBatchResponse
+	(*AggregationSummary)(nil),         // 13: fewer.AggregationSummary
+	(*ListStreamsRequest)(nil),         // 14: fewer.ListStreamsRequest
+	(*ActiveStream)(nil),               // 15: fewer.ActiveStream
+	(*ListStreamsResponse)(nil),        // 16: fewer.ListStreamsResponse
+	(*CancelStreamRequest)(nil),        // 17: fewer.CancelStreamRequest
+	(*CancelStreamResponse)(nil),       // 18: fewer.CancelStreamResponse
+	(*DrainRequest)(nil),               // 19: fewer.DrainRequest
+	(*DrainResponse)(nil),              // 20: fewer.DrainResponse
+	(*timestamppb.Timestamp)(nil),      // 21: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),        // 22: google.protobuf.Duration
+}
+var file_fewer_fewer_proto_depIdxs = []int32{
+	3,  // 0: fewer.AggregatesStreamResponse.aggregate:type_name -> fewer.NumberResponse
+	4,  // 1: fewer.AggregatesStreamResponse.ack:type_name -> fewer.InputAck
+	13, // 2: fewer.AggregatesStreamResponse.summary:type_name -> fewer.AggregationSummary
+	21, // 3: fewer.StoredAggregate.stream_started_at:type_name -> google.protobuf.Timestamp
+	21, // 4: fewer.StoredAggregate.window_started_at:type_name -> google.protobuf.Timestamp
+	21, // 5: fewer.StoredAggregate.emitted_at:type_name -> google.protobuf.Timestamp
+	21, // 6: fewer.QueryAggregatesRequest.emitted_after:type_name -> google.protobuf.Timestamp
+	21, // 7: fewer.QueryAggregatesRequest.emitted_before:type_name -> google.protobuf.Timestamp
+	6,  // 8: fewer.QueryAggregatesResponse.aggregates:type_name -> fewer.StoredAggregate
+	0,  // 9: fewer.SubscribeAggregatesRequest.slow_consumer_policy:type_name -> fewer.SlowConsumerPolicy
+	3,  // 10: fewer.AggregateEvent.aggregate:type_name -> fewer.NumberResponse
+	21, // 11: fewer.AggregateEvent.emitted_at:type_name -> google.protobuf.Timestamp
+	3,  // 12: fewer.AggregateBatchResponse.results:type_name -> fewer.NumberResponse
+	13, // 13: fewer.AggregateBatchResponse.summary:type_name -> fewer.AggregationSummary
+	22, // 14: fewer.AggregationSummary.duration:type_name -> google.protobuf.Duration
+	21, // 15: fewer.ActiveStream.started_at:type_name -> google.protobuf.Timestamp
+	15, // 16: fewer.ListStreamsResponse.streams:type_name -> fewer.ActiveStream
+	15, // 17: fewer.CancelStreamResponse.stream:type_name -> fewer.ActiveStream
+	2,  // 18: fewer.FewerService.GetAggregatesStream:input_type -> fewer.NumberRequest
+	7,  // 19: fewer.FewerService.QueryAggregates:input_type -> fewer.QueryAggregatesRequest
+	9,  // 20: fewer.FewerService.SubscribeAggregates:input_type -> fewer.SubscribeAggregatesRequest
+	11, // 21: fewer.FewerService.AggregateBatch:input_type -> fewer.AggregateBatchRequest
+	2,  // 22: fewer.FewerService.AggregateUpload:input_type -> fewer.NumberRequest
+	14, // 23: fewer.FewerAdmin.ListStreams:input_type -> fewer.ListStreamsRequest
+	17, // 24: fewer.FewerAdmin.CancelStream:input_type -> fewer.CancelStreamRequest
+	19, // 25: fewer.FewerAdmin.Drain:input_type -> fewer.DrainRequest
+	5,  // 26: fewer.FewerService.GetAggregatesStream:output_type -> fewer.AggregatesStreamResponse
+	8,  // 27: fewer.FewerService.QueryAggregates:output_type -> fewer.QueryAggregatesResponse
+	10, // 28: fewer.FewerService.SubscribeAggregates:output_type -> fewer.AggregateEvent
+	12, // 29: fewer.FewerService.AggregateBatch:output_type -> fewer.AggregateBatchResponse
+	13, // 30: fewer.FewerService.AggregateUpload:output_type -> fewer.AggregationSummary
+	16, // 31: fewer.FewerAdmin.ListStreams:output_type -> fewer.ListStreamsResponse
+	18, // 32: fewer.FewerAdmin.CancelStream:output_type -> fewer.CancelStreamResponse
+	20, // 33: fewer.FewerAdmin.Drain:output_type -> fewer.DrainResponse
+	26, // [26:34] is the sub-list for method output_type
+	18, // [18:26] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_fewer_fewer_proto_init() }
+func file_fewer_fewer_proto_init() {
+	if File_fewer_fewer_proto != nil {
+		return
+	}
+	file_fewer_fewer_proto_msgTypes[3].OneofWrappers = []any{
+		(*AggregatesStreamResponse_Aggregate)(nil),
+		(*AggregatesStreamResponse_Ack)(nil),
+		(*AggregatesStreamResponse_Summary)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_fewer_fewer_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   19,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_fewer_fewer_proto_goTypes,
 		DependencyIndexes: file_fewer_fewer_proto_depIdxs,
+		EnumInfos:         file_fewer_fewer_proto_enumTypes,
 		MessageInfos:      file_fewer_fewer_proto_msgTypes,
 	}.Build()
 	File_fewer_fewer_proto = out.File
@@ -20,6 +20,10 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	FewerService_GetAggregatesStream_FullMethodName = "/fewer.FewerService/GetAggregatesStream"
+	FewerService_QueryAggregates_FullMethodName     = "/fewer.FewerService/QueryAggregates"
+	FewerService_SubscribeAggregates_FullMethodName = "/fewer.FewerService/SubscribeAggregates"
+	FewerService_AggregateBatch_FullMethodName      = "/fewer.FewerService/AggregateBatch"
+	FewerService_AggregateUpload_FullMethodName     = "/fewer.FewerService/AggregateUpload"
 )
 
 // FewerServiceClient is the client API for FewerService service.
@@ -31,7 +35,11 @@ const (
 //	The service will return fewer NumberResponse messages than it will receive
 //	NumberRequest messages, hence the term "Fewer Service".
 type FewerServiceClient interface {
-	GetAggregatesStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NumberRequest, NumberResponse], error)
+	GetAggregatesStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NumberRequest, AggregatesStreamResponse], error)
+	QueryAggregates(ctx context.Context, in *QueryAggregatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryAggregatesResponse], error)
+	SubscribeAggregates(ctx context.Context, in *SubscribeAggregatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregateEvent], error)
+	AggregateBatch(ctx context.Context, in *AggregateBatchRequest, opts ...grpc.CallOption) (*AggregateBatchResponse, error)
+	AggregateUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[NumberRequest, AggregationSummary], error)
 }
 
 type fewerServiceClient struct {
@@ -42,18 +50,79 @@ func NewFewerServiceClient(cc grpc.ClientConnInterface) FewerServiceClient {
 	return &fewerServiceClient{cc}
 }
 
-func (c *fewerServiceClient) GetAggregatesStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NumberRequest, NumberResponse], error) {
+func (c *fewerServiceClient) GetAggregatesStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NumberRequest, AggregatesStreamResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &FewerService_ServiceDesc.Streams[0], FewerService_GetAggregatesStream_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &grpc.GenericClientStream[NumberRequest, NumberResponse]{ClientStream: stream}
+	x := &grpc.GenericClientStream[NumberRequest, AggregatesStreamResponse]{ClientStream: stream}
 	return x, nil
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type FewerService_GetAggregatesStreamClient = grpc.BidiStreamingClient[NumberRequest, NumberResponse]
+type FewerService_GetAggregatesStreamClient = grpc.BidiStreamingClient[NumberRequest, AggregatesStreamResponse]
+
+func (c *fewerServiceClient) QueryAggregates(ctx context.Context, in *QueryAggregatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryAggregatesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FewerService_ServiceDesc.Streams[1], FewerService_QueryAggregates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[QueryAggregatesRequest, QueryAggregatesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_QueryAggregatesClient = grpc.ServerStreamingClient[QueryAggregatesResponse]
+
+func (c *fewerServiceClient) SubscribeAggregates(ctx context.Context, in *SubscribeAggregatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregateEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FewerService_ServiceDesc.Streams[2], FewerService_SubscribeAggregates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeAggregatesRequest, AggregateEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_SubscribeAggregatesClient = grpc.ServerStreamingClient[AggregateEvent]
+
+func (c *fewerServiceClient) AggregateBatch(ctx context.Context, in *AggregateBatchRequest, opts ...grpc.CallOption) (*AggregateBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregateBatchResponse)
+	err := c.cc.Invoke(ctx, FewerService_AggregateBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fewerServiceClient) AggregateUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[NumberRequest, AggregationSummary], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FewerService_ServiceDesc.Streams[3], FewerService_AggregateUpload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[NumberRequest, AggregationSummary]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_AggregateUploadClient = grpc.ClientStreamingClient[NumberRequest, AggregationSummary]
 
 // FewerServiceServer is the server API for FewerService service.
 // All implementations must embed UnimplementedFewerServiceServer
@@ -64,7 +133,11 @@ type FewerService_GetAggregatesStreamClient = grpc.BidiStreamingClient[NumberReq
 //	The service will return fewer NumberResponse messages than it will receive
 //	NumberRequest messages, hence the term "Fewer Service".
 type FewerServiceServer interface {
-	GetAggregatesStream(grpc.BidiStreamingServer[NumberRequest, NumberResponse]) error
+	GetAggregatesStream(grpc.BidiStreamingServer[NumberRequest, AggregatesStreamResponse]) error
+	QueryAggregates(*QueryAggregatesRequest, grpc.ServerStreamingServer[QueryAggregatesResponse]) error
+	SubscribeAggregates(*SubscribeAggregatesRequest, grpc.ServerStreamingServer[AggregateEvent]) error
+	AggregateBatch(context.Context, *AggregateBatchRequest) (*AggregateBatchResponse, error)
+	AggregateUpload(grpc.ClientStreamingServer[NumberRequest, AggregationSummary]) error
 	mustEmbedUnimplementedFewerServiceServer()
 }
 
@@ -75,9 +148,21 @@ type FewerServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedFewerServiceServer struct{}
 
-func (UnimplementedFewerServiceServer) GetAggregatesStream(grpc.BidiStreamingServer[NumberRequest, NumberResponse]) error {
+func (UnimplementedFewerServiceServer) GetAggregatesStream(grpc.BidiStreamingServer[NumberRequest, AggregatesStreamResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method GetAggregatesStream not implemented")
 }
+func (UnimplementedFewerServiceServer) QueryAggregates(*QueryAggregatesRequest, grpc.ServerStreamingServer[QueryAggregatesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method QueryAggregates not implemented")
+}
+func (UnimplementedFewerServiceServer) SubscribeAggregates(*SubscribeAggregatesRequest, grpc.ServerStreamingServer[AggregateEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAggregates not implemented")
+}
+func (UnimplementedFewerServiceServer) AggregateBatch(context.Context, *AggregateBatchRequest) (*AggregateBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AggregateBatch not implemented")
+}
+func (UnimplementedFewerServiceServer) AggregateUpload(grpc.ClientStreamingServer[NumberRequest, AggregationSummary]) error {
+	return status.Errorf(codes.Unimplemented, "method AggregateUpload not implemented")
+}
 func (UnimplementedFewerServiceServer) mustEmbedUnimplementedFewerServiceServer() {}
 func (UnimplementedFewerServiceServer) testEmbeddedByValue()                      {}
 
@@ -100,11 +185,58 @@ func RegisterFewerServiceServer(s grpc.ServiceRegistrar, srv FewerServiceServer)
 }
 
 func _FewerService_GetAggregatesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(FewerServiceServer).GetAggregatesStream(&grpc.GenericServerStream[NumberRequest, NumberResponse]{ServerStream: stream})
+	return srv.(FewerServiceServer).GetAggregatesStream(&grpc.GenericServerStream[NumberRequest, AggregatesStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_GetAggregatesStreamServer = grpc.BidiStreamingServer[NumberRequest, AggregatesStreamResponse]
+
+func _FewerService_QueryAggregates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryAggregatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FewerServiceServer).QueryAggregates(m, &grpc.GenericServerStream[QueryAggregatesRequest, QueryAggregatesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_QueryAggregatesServer = grpc.ServerStreamingServer[QueryAggregatesResponse]
+
+func _FewerService_SubscribeAggregates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAggregatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FewerServiceServer).SubscribeAggregates(m, &grpc.GenericServerStream[SubscribeAggregatesRequest, AggregateEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FewerService_SubscribeAggregatesServer = grpc.ServerStreamingServer[AggregateEvent]
+
+func _FewerService_AggregateBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FewerServiceServer).AggregateBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FewerService_AggregateBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FewerServiceServer).AggregateBatch(ctx, req.(*AggregateBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FewerService_AggregateUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FewerServiceServer).AggregateUpload(&grpc.GenericServerStream[NumberRequest, AggregationSummary]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type FewerService_GetAggregatesStreamServer = grpc.BidiStreamingServer[NumberRequest, NumberResponse]
+type FewerService_AggregateUploadServer = grpc.ClientStreamingServer[NumberRequest, AggregationSummary]
 
 // FewerService_ServiceDesc is the grpc.ServiceDesc for FewerService service.
 // It's only intended for direct use with grpc.RegisterService,
@@ -112,7 +244,12 @@ type FewerService_GetAggregatesStreamServer = grpc.BidiStreamingServer[NumberReq
 var FewerService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "fewer.FewerService",
 	HandlerType: (*FewerServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AggregateBatch",
+			Handler:    _FewerService_AggregateBatch_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "GetAggregatesStream",
@@ -120,6 +257,211 @@ var FewerService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "QueryAggregates",
+			Handler:       _FewerService_QueryAggregates_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAggregates",
+			Handler:       _FewerService_SubscribeAggregates_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AggregateUpload",
+			Handler:       _FewerService_AggregateUpload_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "fewer/fewer.proto",
+}
+
+const (
+	FewerAdmin_ListStreams_FullMethodName  = "/fewer.FewerAdmin/ListStreams"
+	FewerAdmin_CancelStream_FullMethodName = "/fewer.FewerAdmin/CancelStream"
+	FewerAdmin_Drain_FullMethodName        = "/fewer.FewerAdmin/Drain"
+)
+
+// FewerAdminClient is the client API for FewerAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Service through which operators watch and manage the aggregation streams of a Fewer Service
+//
+//	server.  It is registered alongside the Fewer Service, and its calls must present one of the
+//	admin tokens of the server in the "fewer-admin-authorization" metadata ("Bearer <token>").
+//	While the server has no admin tokens, every call to it is denied.
+type FewerAdminClient interface {
+	ListStreams(ctx context.Context, in *ListStreamsRequest, opts ...grpc.CallOption) (*ListStreamsResponse, error)
+	CancelStream(ctx context.Context, in *CancelStreamRequest, opts ...grpc.CallOption) (*CancelStreamResponse, error)
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error)
+}
+
+type fewerAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFewerAdminClient(cc grpc.ClientConnInterface) FewerAdminClient {
+	return &fewerAdminClient{cc}
+}
+
+func (c *fewerAdminClient) ListStreams(ctx context.Context, in *ListStreamsRequest, opts ...grpc.CallOption) (*ListStreamsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListStreamsResponse)
+	err := c.cc.Invoke(ctx, FewerAdmin_ListStreams_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fewerAdminClient) CancelStream(ctx context.Context, in *CancelStreamRequest, opts ...grpc.CallOption) (*CancelStreamResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelStreamResponse)
+	err := c.cc.Invoke(ctx, FewerAdmin_CancelStream_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fewerAdminClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainResponse)
+	err := c.cc.Invoke(ctx, FewerAdmin_Drain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FewerAdminServer is the server API for FewerAdmin service.
+// All implementations must embed UnimplementedFewerAdminServer
+// for forward compatibility.
+//
+// Service through which operators watch and manage the aggregation streams of a Fewer Service
+//
+//	server.  It is registered alongside the Fewer Service, and its calls must present one of the
+//	admin tokens of the server in the "fewer-admin-authorization" metadata ("Bearer <token>").
+//	While the server has no admin tokens, every call to it is denied.
+type FewerAdminServer interface {
+	ListStreams(context.Context, *ListStreamsRequest) (*ListStreamsResponse, error)
+	CancelStream(context.Context, *CancelStreamRequest) (*CancelStreamResponse, error)
+	Drain(context.Context, *DrainRequest) (*DrainResponse, error)
+	mustEmbedUnimplementedFewerAdminServer()
+}
+
+// UnimplementedFewerAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFewerAdminServer struct{}
+
+func (UnimplementedFewerAdminServer) ListStreams(context.Context, *ListStreamsRequest) (*ListStreamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStreams not implemented")
+}
+func (UnimplementedFewerAdminServer) CancelStream(context.Context, *CancelStreamRequest) (*CancelStreamResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelStream not implemented")
+}
+func (UnimplementedFewerAdminServer) Drain(context.Context, *DrainRequest) (*DrainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedFewerAdminServer) mustEmbedUnimplementedFewerAdminServer() {}
+func (UnimplementedFewerAdminServer) testEmbeddedByValue()                    {}
+
+// UnsafeFewerAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FewerAdminServer will
+// result in compilation errors.
+type UnsafeFewerAdminServer interface {
+	mustEmbedUnimplementedFewerAdminServer()
+}
+
+func RegisterFewerAdminServer(s grpc.ServiceRegistrar, srv FewerAdminServer) {
+	// If the following call pancis, it indicates UnimplementedFewerAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FewerAdmin_ServiceDesc, srv)
+}
+
+func _FewerAdmin_ListStreams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStreamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FewerAdminServer).ListStreams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FewerAdmin_ListStreams_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FewerAdminServer).ListStreams(ctx, req.(*ListStreamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FewerAdmin_CancelStream_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelStreamRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FewerAdminServer).CancelStream(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FewerAdmin_CancelStream_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FewerAdminServer).CancelStream(ctx, req.(*CancelStreamRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FewerAdmin_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FewerAdminServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FewerAdmin_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FewerAdminServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FewerAdmin_ServiceDesc is the grpc.ServiceDesc for FewerAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FewerAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fewer.FewerAdmin",
+	HandlerType: (*FewerAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListStreams",
+			Handler:    _FewerAdmin_ListStreams_Handler,
+		},
+		{
+			MethodName: "CancelStream",
+			Handler:    _FewerAdmin_CancelStream_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _FewerAdmin_Drain_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "fewer/fewer.proto",
 }
@@ -0,0 +1,284 @@
+// Package logging is the structured logging package shared by the Fewer Service client and server
+//   applications.  It is built on log/slog, so that entries carry any number of attributes, and
+//   writes every entry to one or more destinations (the terminal, log files, or any io.Writer) in a
+//   configurable format, above a level that can be changed while the application runs.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the formats entries can be written in.  logfmt writes key=value pairs, json writes one
+//   JSON object per entry, and text writes a human-readable line per entry.
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+	FormatText   = "text"
+)
+
+// Names of the subdirectories of a log directory that hold the log files of production and of
+//   development/test applications, respectively.
+const (
+	ProductionLogSubdir = "production"
+	DevTestLogSubdir    = "devtest"
+)
+
+// Names of the destinations that write to the terminal rather than to a log file.
+const (
+	StdoutDestination = "stdout"
+	StderrDestination = "stderr"
+)
+
+
+
+//*************************************************************************************************
+// Definition of the settings of a Logger.
+type Config struct {
+	// Minimum level of the entries written: "debug", "info", "warn" or "error" (default "info").
+	Level        string
+	// Format of the entries: "logfmt", "json" or "text" (default "logfmt").
+	Format       string
+	// Destinations the entries are written to: "stdout", "stderr", or paths of log files, which
+	//   are created (along with their directories) if missing, and appended to.
+	Destinations []string
+	// Rotation and retention settings of the log files among the destinations.
+	Rotation     RotationConfig
+	// Extra writers the entries are also written to, for destinations that are not files (e.g.,
+	//   buffers in tests).
+	Writers      []io.Writer
+}
+
+// Definition of a structured logger writing to every destination of its Config.  The embedded
+//   *slog.Logger provides the logging methods (Debug(), Info(), Warn(), Error(), With(), ...).
+type Logger struct {
+	*slog.Logger
+	level *slog.LevelVar
+	files []*RotatingFile
+
+	// Stops reopening the log files on signals, if ReopenOnSignal() was called.
+	stopReopen func()
+}
+
+// Constructor function that creates a new Logger from the given settings, opening its log files.
+func New(config Config) (*Logger, error) {
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(level)
+
+	format := config.Format
+	if format == "" {
+		format = FormatLogfmt
+	}
+	if format != FormatLogfmt && format != FormatJSON && format != FormatText {
+		return nil, fmt.Errorf("unknown log format %q (expected logfmt, json or text)", config.Format)
+	}
+
+	logger := &Logger{level: levelVar}
+	writers := append([]io.Writer(nil), config.Writers...)
+	for _, destination := range config.Destinations {
+		switch destination {
+		case StdoutDestination:
+			writers = append(writers, os.Stdout)
+		case StderrDestination:
+			writers = append(writers, os.Stderr)
+		case "":
+		default:
+			file, err := OpenRotatingFile(destination, config.Rotation)
+			if err != nil {
+				logger.Close()
+				return nil, fmt.Errorf("could not open log file: %w", err)
+			}
+			logger.files = append(logger.files, file)
+			writers = append(writers, file)
+		}
+	}
+
+	handlers := make(fanoutHandler, 0, len(writers))
+	for _, w := range writers {
+		handlers = append(handlers, newHandler(w, format, levelVar))
+	}
+	logger.Logger = slog.New(handlers)
+	return logger, nil
+}
+
+// Method of the Logger that changes the minimum level of the entries it writes.  Safe to call while
+//   the Logger is in use.
+func (l *Logger) SetLevel(level slog.Level) {
+	l.level.Set(level)
+}
+
+// Method of the Logger that returns the minimum level of the entries it writes.
+func (l *Logger) Level() slog.Level {
+	return l.level.Level()
+}
+
+// Method of the Logger that closes and reopens its log files at their paths, so that an external
+//   tool (e.g., logrotate) can move them away without entries being lost.
+func (l *Logger) Reopen() error {
+	var errs []error
+	for _, file := range l.files {
+		errs = append(errs, file.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// Method of the Logger that reopens its log files whenever the process receives one of the given
+//   signals (typically SIGHUP, sent by logrotate once it moved the files away), until the Logger is
+//   closed.  Every reopening is logged.
+func (l *Logger) ReopenOnSignal(signals ...os.Signal) {
+	if l.stopReopen != nil || len(signals) == 0 {
+		return
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, signals...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sig := <-sigChan:
+				if err := l.Reopen(); err != nil {
+					l.Error("Failed to reopen log files", "signal", sig.String(), "error", err)
+				} else {
+					l.Info("Reopened log files", "signal", sig.String())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	l.stopReopen = func() {
+		signal.Stop(sigChan)
+		close(done)
+		<-stopped
+	}
+}
+
+// Method of the Logger that closes its log files.  Entries logged afterwards are only written to
+//   its other destinations.
+func (l *Logger) Close() error {
+	if l.stopReopen != nil {
+		l.stopReopen()
+		l.stopReopen = nil
+	}
+	var errs []error
+	for _, file := range l.files {
+		errs = append(errs, file.Close())
+	}
+	l.files = nil
+	return errors.Join(errs...)
+}
+
+// Function that parses the name of a level ("debug", "info", "warn" or "error", in any case).  An
+//   empty name is the "info" level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", name)
+	}
+	return level, nil
+}
+
+// Function that returns the path of a log file: logFile itself if it is an absolute path, and
+//   otherwise logFile inside the production or development/test subdirectory of logDir.
+func LogFilePath(logDir, logFile string, isProd bool) string {
+	if filepath.IsAbs(logFile) {
+		return logFile
+	}
+	subdir := DevTestLogSubdir
+	if isProd {
+		subdir = ProductionLogSubdir
+	}
+	return filepath.Join(logDir, subdir, logFile)
+}
+
+// Function that splits a comma-separated list of destinations (e.g., the value of a command-line
+//   flag), dropping empty entries.
+func SplitDestinations(list string) []string {
+	var destinations []string
+	for _, destination := range strings.Split(list, ",") {
+		if destination = strings.TrimSpace(destination); destination != "" {
+			destinations = append(destinations, destination)
+		}
+	}
+	return destinations
+}
+
+// Helper function that creates the handler writing entries of the given format to w.  Timestamps
+//   are written in UTC.
+func newHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	options := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+				a.Value = slog.TimeValue(a.Value.Time().UTC())
+			}
+			return a
+		},
+	}
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, options)
+	case FormatText:
+		return newTextHandler(w, level)
+	default:
+		// slog's text handler writes entries as logfmt key=value pairs.
+		return slog.NewTextHandler(w, options)
+	}
+}
+
+
+
+//*************************************************************************************************
+// Definition of a handler passing every entry on to several handlers, one per destination.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test of the entries written in each format, with the attributes of the entry, of the logger, and
+//   of a group.
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: FormatLogfmt, want: []string{"level=WARN", `msg="Leftover data not reported"`, "rpc=pb.FewerService_GetAggregatesStream", "stream.id=abc", "stream.sum=7"}},
+		{format: FormatText, want: []string{"WARN  Leftover data not reported", " rpc=pb.FewerService_GetAggregatesStream", " stream.id=abc", " stream.sum=7"}},
+		{format: FormatJSON, want: []string{`"level":"WARN"`, `"msg":"Leftover data not reported"`, `"rpc":"pb.FewerService_GetAggregatesStream"`, `"stream":{"id":"abc","sum":7}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(Config{Format: tt.format, Writers: []io.Writer{&buf}})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			logger.With("rpc", "pb.FewerService_GetAggregatesStream").WithGroup("stream").Warn("Leftover data not reported", "id", "abc", "sum", 7)
+			line := buf.String()
+			if strings.Count(line, "\n") != 1 {
+				t.Fatalf("wrote %q, want exactly one line", line)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(line, want) {
+					t.Errorf("wrote %q, want it to contain %q", line, want)
+				}
+			}
+			if tt.format == FormatJSON {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Errorf("wrote invalid JSON %q: %v", line, err)
+				}
+			}
+		})
+	}
+}
+
+// Test of the level filter, and of changing the level of a logger in use.
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(Config{Level: "WARN", Writers: []io.Writer{&buf}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	logger.Info("dropped")
+	logger.Error("kept")
+	logger.SetLevel(slog.LevelDebug)
+	logger.Debug("kept too")
+	if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "msg=kept") || !strings.Contains(got, `msg="kept too"`) {
+		t.Errorf("wrote %q, want only the entries at or above the level", got)
+	}
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("Level() = %v, want DEBUG", logger.Level())
+	}
+}
+
+// Test of writing every entry to a log file and another writer at once.
+func TestDestinations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	var buf bytes.Buffer
+	logger, err := New(Config{Destinations: SplitDestinations(" " + path + ", ,"), Writers: []io.Writer{&buf}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	logger.Info("to both", "method", "GeneralFewerServer_Serve")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(written) != buf.String() || !strings.Contains(buf.String(), `msg="to both" method=GeneralFewerServer_Serve`) {
+		t.Errorf("file got %q and writer got %q, want the same entry", written, buf.String())
+	}
+}
+
+// Test of creating missing log directories, with production and development/test log files kept
+//   apart.
+func TestLogFilePath(t *testing.T) {
+	logDir := filepath.Join(t.TempDir(), "serverlogs")
+	for _, tt := range []struct {
+		logFile string
+		isProd  bool
+		want    string
+	}{
+		{logFile: "server.log", isProd: true, want: filepath.Join(logDir, "production", "server.log")},
+		{logFile: "server_devtest.log", isProd: false, want: filepath.Join(logDir, "devtest", "server_devtest.log")},
+		{logFile: filepath.Join(logDir, "custom", "fewer.log"), isProd: true, want: filepath.Join(logDir, "custom", "fewer.log")},
+	} {
+		path := LogFilePath(logDir, tt.logFile, tt.isProd)
+		if path != tt.want {
+			t.Errorf("LogFilePath(%q, %v) = %q, want %q", tt.logFile, tt.isProd, path, tt.want)
+		}
+		logger, err := New(Config{Destinations: []string{path}})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		logger.Info("created")
+		logger.Close()
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("log file %q was not created: %v", path, err)
+		}
+	}
+}
+
+// Test of rejecting invalid settings.
+func TestInvalidConfig(t *testing.T) {
+	// A log file cannot be created under a path that is a regular file.
+	notADir := filepath.Join(t.TempDir(), "server.log")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	for _, config := range []Config{
+		{Level: "verbose"},
+		{Format: "xml"},
+		{Destinations: []string{filepath.Join(notADir, "server.log")}},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", config)
+		}
+	}
+}
+
@@ -0,0 +1,238 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout of the timestamp appended to the name of a rotated log file (e.g., server.log becomes
+//   server.log.20250131T120000.000000000).  Timestamps of this layout sort in time order.
+const backupTimeLayout = "20060102T150405.000000000"
+
+// Extension added to the name of a rotated log file once it is compressed.
+const compressedExtension = ".gz"
+
+
+
+//*************************************************************************************************
+// Definition of the rotation and retention settings of a log file.  The zero value never rotates.
+type RotationConfig struct {
+	// Size (in bytes) past which the log file is rotated.  0 turns off size-based rotation.
+	MaxSize    int64
+	// Time since the log file was opened past which it is rotated.  0 turns off age-based rotation.
+	MaxAge     time.Duration
+	// Number of rotated log files kept; older ones are deleted.  0 keeps all of them.
+	MaxBackups int
+	// Whether rotated log files are compressed with gzip.
+	Compress   bool
+}
+
+// Definition of a log file that is appended to, and rotated once it grows past its maximum size or
+//   age: it is renamed with a timestamp suffix (and compressed, if configured), a new log file is
+//   started, and the oldest rotated files past the retention count are deleted.  It can also be
+//   reopened, after an external tool (e.g., logrotate) moved it away.  It is safe for concurrent use.
+type RotatingFile struct {
+	path     string
+	config   RotationConfig
+	// Returns the current time; replaced in tests.
+	now      func() time.Time
+
+	// The open log file, its size, and the time it was opened.  All are guarded by mu.
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// Compression and pruning of rotated files run in the background, one at a time.
+	maintenance   sync.Mutex
+	maintenanceWG sync.WaitGroup
+}
+
+// Constructor function that opens (creating it and its directory if missing) the log file at path,
+//   to be rotated according to config.
+func OpenRotatingFile(path string, config RotationConfig) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f := &RotatingFile{path: path, config: config, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Method of the RotatingFile that appends p to the log file, rotating it first if p would take it
+//   past its maximum size, or if it is past its maximum age.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Method of the RotatingFile that rotates the log file right away, unless it is empty.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if f.size == 0 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// Method of the RotatingFile that closes and reopens the log file at its path.  Used after an
+//   external tool moved the log file away, so that logging continues in a new file at the path.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Method of the RotatingFile that closes the log file, and waits for the compression and pruning
+//   of rotated files to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.maintenanceWG.Wait()
+	return err
+}
+
+// Internal method of the RotatingFile that opens the log file at its path for appending.  Must be
+//   called with mu held.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// Internal method of the RotatingFile that reports whether the log file must be rotated before
+//   writing n more bytes to it.  An empty log file is never rotated.  Must be called with mu held.
+func (f *RotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+int64(n) > f.config.MaxSize {
+		return true
+	}
+	return f.config.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.config.MaxAge
+}
+
+// Internal method of the RotatingFile that renames the log file with a timestamp suffix, and starts
+//   a new one.  Compression and pruning of rotated files continue in the background.  Must be called
+//   with mu held.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	backup := f.path + "." + f.now().UTC().Format(backupTimeLayout)
+	if err := os.Rename(f.path, backup); err != nil {
+		// Keep logging to the current file rather than losing entries.
+		if openErr := f.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.maintenanceWG.Add(1)
+	go func() {
+		defer f.maintenanceWG.Done()
+		f.maintenance.Lock()
+		defer f.maintenance.Unlock()
+		if f.config.Compress {
+			compressFile(backup)
+		}
+		f.prune()
+	}()
+	return nil
+}
+
+// Internal method of the RotatingFile that deletes the oldest rotated log files past its retention
+//   count.
+func (f *RotatingFile) prune() {
+	if f.config.MaxBackups <= 0 {
+		return
+	}
+	backups := f.backups()
+	for _, backup := range backups[min(f.config.MaxBackups, len(backups)):] {
+		os.Remove(backup)
+	}
+}
+
+// Internal method of the RotatingFile that returns the paths of its rotated log files, newest first.
+func (f *RotatingFile) backups() []string {
+	matches, _ := filepath.Glob(f.path + ".*")
+	var backups []string
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, f.path+"."), compressedExtension)
+		if _, err := time.Parse(backupTimeLayout, suffix); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// Helper function that compresses a rotated log file with gzip, replacing it with a file of the same
+//   name ending in .gz.  The rotated log file is left as is if compression fails.
+func compressFile(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+compressedExtension, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	err = errors.Join(err, zw.Close(), dst.Close())
+	if err != nil {
+		os.Remove(path + compressedExtension)
+		return
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function that opens a RotatingFile whose clock only moves when the test advances it.
+func openTestRotatingFile(t *testing.T, path string, config RotationConfig) (*RotatingFile, *time.Time) {
+	t.Helper()
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	f := &RotatingFile{path: path, config: config, now: func() time.Time { return now }}
+	if err := f.open(); err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	return f, &now
+}
+
+// Helper function that reads a log file, decompressing it if it ends in .gz.
+func readLogFile(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, compressedExtension) {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("gzip.NewReader(%s) error = %v", path, err)
+		}
+		r = zr
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error = %v", path, err)
+	}
+	return string(content)
+}
+
+// Test of size-based rotation, keeping only the newest rotated files.
+func TestRotatingFileMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, now := openTestRotatingFile(t, path, RotationConfig{MaxSize: 10, MaxBackups: 2})
+	for _, line := range []string{"entry 1\n", "entry 2\n", "entry 3\n", "entry 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		*now = now.Add(time.Second)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := readLogFile(t, path); got != "entry 4\n" {
+		t.Errorf("current log file holds %q, want the last entry", got)
+	}
+	backups := f.backups()
+	if len(backups) != 2 {
+		t.Fatalf("kept rotated files %v, want 2", backups)
+	}
+	if got := readLogFile(t, backups[0]) + readLogFile(t, backups[1]); got != "entry 3\nentry 2\n" {
+		t.Errorf("rotated files hold %q, want the two previous entries, newest first", got)
+	}
+}
+
+// Test of age-based rotation, with compression of the rotated file.
+func TestRotatingFileMaxAgeCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.log")
+	f, now := openTestRotatingFile(t, path, RotationConfig{MaxAge: time.Hour, Compress: true})
+	f.Write([]byte("old entry\n"))
+	*now = now.Add(30 * time.Minute)
+	f.Write([]byte("still young\n"))
+	*now = now.Add(30 * time.Minute)
+	f.Write([]byte("new entry\n"))
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	backups := f.backups()
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".20250131T130000.000000000"+compressedExtension) {
+		t.Fatalf("rotated files %v, want one compressed file named after the rotation time", backups)
+	}
+	if got := readLogFile(t, backups[0]); got != "old entry\nstill young\n" {
+		t.Errorf("rotated file holds %q, want the entries of the first hour", got)
+	}
+	if got := readLogFile(t, path); got != "new entry\n" {
+		t.Errorf("current log file holds %q, want the entry after rotation", got)
+	}
+}
+
+// Test of reopening the log file after an external tool moved it away, as logrotate does before
+//   sending SIGHUP.
+func TestLoggerReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+	logger, err := New(Config{Destinations: []string{path}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer logger.Close()
+	logger.Info("before rotation")
+	moved := filepath.Join(dir, "server.log.1")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	logger.Info("still in moved file")
+	if err := logger.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	logger.Info("after rotation")
+
+	if got := readLogFile(t, moved); !strings.Contains(got, "before rotation") || !strings.Contains(got, "still in moved file") || strings.Contains(got, "after rotation") {
+		t.Errorf("moved log file holds %q, want only the entries before reopening", got)
+	}
+	if got := readLogFile(t, path); !strings.Contains(got, "after rotation") || strings.Contains(got, "before rotation") {
+		t.Errorf("reopened log file holds %q, want only the entries after reopening", got)
+	}
+}
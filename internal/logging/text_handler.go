@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout of the timestamps written by the text format.
+const textTimeLayout = "2006-01-02 15:04:05.000"
+
+
+
+//*************************************************************************************************
+// Definition of the handler of the "text" format, which writes every entry as one human-readable
+//   line: the time, the level and the message, followed by the attributes as key=value pairs.
+//   Attributes of groups are written with the group names as key prefixes (e.g., "stream.id=...").
+type textHandler struct {
+	// Guards writes to w, and is shared by every handler derived from the same destination.
+	mu          *sync.Mutex
+	w           io.Writer
+	level       slog.Leveler
+	// Attributes added with WithAttrs(), already formatted, and prefix of the keys of the attributes
+	//   added from now on.
+	attrs       string
+	groupPrefix string
+}
+
+// Constructor function that creates a textHandler writing entries of at least the given level to w.
+func newTextHandler(w io.Writer, level slog.Leveler) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	if !record.Time.IsZero() {
+		b.WriteString(record.Time.UTC().Format(textTimeLayout))
+		b.WriteByte(' ')
+	}
+	level := record.Level.String()
+	b.WriteString(level)
+	b.WriteString(strings.Repeat(" ", max(1, 6-len(level))))
+	b.WriteString(record.Message)
+	b.WriteString(h.attrs)
+	record.Attrs(func(a slog.Attr) bool {
+		appendTextAttr(&b, h.groupPrefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		appendTextAttr(&b, h.groupPrefix, a)
+	}
+	derived := *h
+	derived.attrs += b.String()
+	return &derived
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.groupPrefix += name + "."
+	return &derived
+}
+
+// Helper function that appends one attribute (or, for a group, all of its attributes) to b, as
+//   space-separated key=value pairs.  Values that contain spaces, quotes or equal signs are quoted.
+func appendTextAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, groupAttr := range a.Value.Group() {
+			appendTextAttr(b, prefix, groupAttr)
+		}
+		return
+	}
+
+	var value string
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().UTC().Format(time.RFC3339Nano)
+	} else {
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+	b.WriteString(value)
+}
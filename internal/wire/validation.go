@@ -0,0 +1,63 @@
+package wire
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Metadata keys through which a client sets the validation rules of an aggregation stream, batch
+//   or upload when it opens it: the bounds inputs must stay within, a comma-separated list of
+//   disallowed inputs, and the policy applied to inputs that break these rules.
+const MinInputMetadataKey = "fewer-min-input"
+const MaxInputMetadataKey = "fewer-max-input"
+const DisallowedInputsMetadataKey = "fewer-disallowed-inputs"
+const InvalidInputPolicyMetadataKey = "fewer-invalid-input-policy"
+
+// Policies applied to inputs that break the validation rules of their stream: fail the stream with
+//   an InvalidArgument status error, skip them (leaving them out of their aggregate), or clamp them
+//   to the nearest bound.  Disallowed inputs within the bounds cannot be clamped, and are skipped.
+const (
+	InvalidInputFail  = "fail"
+	InvalidInputSkip  = "skip"
+	InvalidInputClamp = "clamp"
+)
+
+
+
+//*************************************************************************************************
+// Definition of the validation rules of an aggregation stream, batch or upload, set by its client
+//   when opening it.  Empty rules accept every input.  Inputs are integers, so they are never NaN
+//   or infinite.
+type ValidationRules struct {
+	// Bounds inputs must stay within.  nil leaves that end open.
+	Min        *int32
+	Max        *int32
+	// Inputs that are never valid.
+	Disallowed []int32
+	// Policy applied to invalid inputs (InvalidInputFail, InvalidInputSkip or InvalidInputClamp).
+	//   Empty for the default, InvalidInputFail.
+	Policy     string
+}
+
+// Method of the ValidationRules that returns the metadata key/value pairs carrying them, in the
+//   form of metadata.Pairs().  Rules that are not set are left out.
+func (r ValidationRules) MetadataPairs() []string {
+	var pairs []string
+	if r.Min != nil {
+		pairs = append(pairs, MinInputMetadataKey, strconv.Itoa(int(*r.Min)))
+	}
+	if r.Max != nil {
+		pairs = append(pairs, MaxInputMetadataKey, strconv.Itoa(int(*r.Max)))
+	}
+	if len(r.Disallowed) > 0 {
+		disallowed := make([]string, len(r.Disallowed))
+		for i, inputNum := range r.Disallowed {
+			disallowed[i] = strconv.Itoa(int(inputNum))
+		}
+		pairs = append(pairs, DisallowedInputsMetadataKey, strings.Join(disallowed, ","))
+	}
+	if r.Policy != "" {
+		pairs = append(pairs, InvalidInputPolicyMetadataKey, r.Policy)
+	}
+	return pairs
+}
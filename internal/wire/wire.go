@@ -0,0 +1,36 @@
+// Package wire holds the conventions of the Fewer Service that the client and server applications
+//   must agree on beyond the messages of fewer.proto: the metadata keys their calls are labelled
+//   and tuned with, and the values these keys take.  Both sides import them from here, so that
+//   they cannot drift apart.
+package wire
+
+// Metadata key through which a client advertises the maximum number of unacknowledged inputs it
+//   will keep in flight on a GetAggregatesStream() stream, so that the service acknowledges inputs
+//   often enough for the window to keep moving.
+const MaxInFlightMetadataKey = "fewer-max-in-flight"
+
+// Metadata keys through which a client can label an aggregation stream with a key and a tenant.
+//   Every aggregate emitted on the stream carries these labels, and SubscribeAggregates()
+//   subscribers can filter on them.
+const StreamKeyMetadataKey = "fewer-key"
+const TenantMetadataKey = "fewer-tenant"
+
+// Trailer metadata key through which the Fewer Service sends the serialized AggregationSummary of a
+//   GetAggregatesStream() stream, whether the stream ends successfully or not.
+const SummaryTrailerKey = "fewer-summary-bin"
+
+// Trailer metadata key through which the Fewer Service sends the reason an aggregation stream was
+//   cancelled for through the FewerAdmin service, along with its summary.
+const CancelReasonTrailerKey = "fewer-cancel-reason"
+
+// Metadata key through which a client picks the reducer applied to every batch of inputs of an
+//   aggregation stream, among those allowed by the server (default SumReducer).
+const ReducerMetadataKey = "fewer-reducer"
+
+// Names of the reducers of the Fewer Service: the sum, the smallest or the largest of the inputs of
+//   a batch.
+const (
+	SumReducer = "sum"
+	MinReducer = "min"
+	MaxReducer = "max"
+)
@@ -5,14 +5,19 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"strconv"
+	"syscall"
 
+	"github.com/astronomical3/fewer_grpc/internal/logging"
 	"github.com/astronomical3/fewer_grpc/server/internal"
 )
 
-// Provide a name of the production-level or development-level server activity log file.
-//   The filename is relative to the fewer_grpc/server/serverlogs/ directory path.
-const serverLogProdFilename = "server.log"
-const serverLogDevFilename = "server_devtest.log"
+// Definition of the --config flag of the 'go run [fewer_grpc/server/]app.go' command.
+var configPath = flag.String("config", os.Getenv(internal.ServerConfigPathEnvVar), "path of the YAML server configuration file (default $"+internal.ServerConfigPathEnvVar+", or built-in defaults)")
+
+// Definition of the --print-config flag of the 'go run [fewer_grpc/server/]app.go' command.
+var printConfig = flag.Bool("print-config", false, "print the effective server configuration, merged from defaults, configuration file, "+internal.ServerConfigEnvPrefix+"* environment variables and flags, then exit")
 
 // Definition of the --address flag of the 'go run [fewer_grpc/server/]app.go' command.
 var address = flag.String("address", "localhost", "address of server to serve on")
@@ -20,31 +25,197 @@ var address = flag.String("address", "localhost", "address of server to serve on
 // Definition of the --port flag of the 'go run [fewer_grpc/server/]app.go' command.
 var port = flag.Int("port", 50051, "port of server to serve on")
 
+// Definition of the --listeners flag of the 'go run [fewer_grpc/server/]app.go' command.
+var listenersFlag = flag.String("listeners", "", "comma-separated listeners to serve on at once, each host:port, tcp4://host:port, tcp6://[host]:port or unix:///path/to/socket (overrides --address and --port)")
+
+// Definition of the --unixSocketMode flag of the 'go run [fewer_grpc/server/]app.go' command.
+var unixSocketMode = flag.String("unixSocketMode", internal.DefaultUnixSocketMode, "permissions of the socket files of Unix domain socket listeners, in octal")
+
+// Definition of the --keepaliveTime flag of the 'go run [fewer_grpc/server/]app.go' command.
+var keepaliveTime = flag.Duration("keepaliveTime", internal.DefaultKeepaliveTime, "time after which the server pings a client connection it has heard nothing from")
+
+// Definition of the --keepaliveTimeout flag of the 'go run [fewer_grpc/server/]app.go' command.
+var keepaliveTimeout = flag.Duration("keepaliveTimeout", internal.DefaultKeepaliveTimeout, "time the server waits for a keepalive ping to be answered before closing the connection as dead")
+
+// Definition of the --keepaliveMinPingInterval flag of the 'go run [fewer_grpc/server/]app.go' command.
+var keepaliveMinPingInterval = flag.Duration("keepaliveMinPingInterval", internal.DefaultKeepaliveMinPingInterval, "minimum time between the keepalive pings of a client (clients pinging more often are disconnected)")
+
+// Definition of the --keepalivePermitWithoutStream flag of the 'go run [fewer_grpc/server/]app.go' command.
+var keepalivePermitWithoutStream = flag.Bool("keepalivePermitWithoutStream", true, "let clients send keepalive pings while they have no open calls")
+
+// Definition of the --maxConnectionIdle flag of the 'go run [fewer_grpc/server/]app.go' command.
+var maxConnectionIdle = flag.Duration("maxConnectionIdle", 0, "time after which a client connection without open calls is closed (0 for never)")
+
+// Definition of the --maxConnectionAge flag of the 'go run [fewer_grpc/server/]app.go' command.
+var maxConnectionAge = flag.Duration("maxConnectionAge", 0, "time after which a client connection is closed, once its open calls finish (0 for never)")
+
+// Definition of the --maxConnectionAgeGrace flag of the 'go run [fewer_grpc/server/]app.go' command.
+var maxConnectionAgeGrace = flag.Duration("maxConnectionAgeGrace", 0, "time the open calls of a connection past its maximum age are given to finish (0 for no limit)")
+
 // Definition of the --prod flag of the 'go run [fewer_grpc/server/]app.go' command.
 var prod = flag.Bool("prod", true, "indicates whether server is production server or development server")
 
+// Definition of the --ackInterval flag of the 'go run [fewer_grpc/server/]app.go' command.
+var ackInterval = flag.Int("ackInterval", internal.DefaultAckInterval, "number of inputs processed between acknowledgements sent back to clients")
+
+// Definition of the --sink flag of the 'go run [fewer_grpc/server/]app.go' command.
+var sink = flag.String("sink", "none", "backend to persist emitted aggregates to (none, jsonl or bolt)")
+
+// Definition of the --sinkPath flag of the 'go run [fewer_grpc/server/]app.go' command.
+var sinkPath = flag.String("sinkPath", "aggregates.jsonl", "path of the file the aggregate sink backend writes to")
+
+// Definition of the --logDir flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logDir = flag.String("logDir", internal.DefaultServerLogDir, "directory whose production/ or devtest/ subdirectory holds the server log file (created if missing)")
+
+// Definition of the --logFile flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logFile = flag.String("logFile", "", "name of the server log file inside the log directory, or absolute path of the server log file (default server.log for production, server_devtest.log for development)")
+
+// Definition of the --logLevel flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logLevel = flag.String("logLevel", "", "minimum level of logged activity: debug, info, warn or error (default info for production, debug for development)")
+
+// Definition of the --logFormat flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logFormat = flag.String("logFormat", logging.FormatLogfmt, "format of logged activity (logfmt, json or text)")
+
+// Definition of the --logOutputs flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logOutputs = flag.String("logOutputs", "", "comma-separated destinations of logged activity: stdout, stderr or log file paths (default stdout and the server log file)")
+
+// Definition of the --logMaxSize flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logMaxSize = flag.Int64("logMaxSize", 0, "size in megabytes past which the server log file is rotated (0 turns off size-based rotation)")
+
+// Definition of the --logMaxAge flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logMaxAge = flag.Duration("logMaxAge", 0, "age past which the server log file is rotated (0 turns off age-based rotation)")
+
+// Definition of the --logMaxBackups flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logMaxBackups = flag.Int("logMaxBackups", 0, "number of rotated server log files kept (0 keeps all of them)")
+
+// Definition of the --logCompress flag of the 'go run [fewer_grpc/server/]app.go' command.
+var logCompress = flag.Bool("logCompress", false, "compress rotated server log files with gzip")
+
 func main() {
 	// Load and parse the values of the flags provided in the 'go run' command.
 	flag.Parse()
-	
-	// Create a listener, lis, that will listen to requests to the address and port
-	//   provided by the 'go run' command flags.
-	addrString := fmt.Sprintf("%s:%d", *address, *port)
-	lis, err := net.Listen("tcp", addrString)
+
+	// Load the server configuration: defaults, overlaid by the configuration file, the FEWER_*
+	//   environment variables, and the flags given explicitly in the 'go run' command.
+	config, err := internal.LoadServerConfig(*configPath, os.LookupEnv, applyFlags)
+	if err != nil {
+		log.Fatalf("fewer_grpc/server/app.go: invalid server configuration: %v", err)
+	}
+	if *printConfig {
+		if err := config.WriteYAML(os.Stdout); err != nil {
+			log.Fatalf("fewer_grpc/server/app.go: failed to print server configuration: %v", err)
+		}
+		return
+	}
+
+	// Use the listeners passed by systemd socket activation, if any.  Otherwise, open the listeners
+	//   of the configuration (TCP addresses and/or Unix domain sockets).  The server will listen to
+	//   requests on all of them at once.
+	listeners, err := internal.SystemdListeners()
 	if err != nil {
-		log.Fatalf("fewer_grpc/server/app.go: net.Listen failed to listen: %v", err)
+		log.Fatalf("fewer_grpc/server/app.go: failed to use sockets passed by systemd: %v", err)
+	}
+	socketActivated := len(listeners) > 0
+	if !socketActivated {
+		listeners, err = config.Listen()
+		if err != nil {
+			log.Fatalf("fewer_grpc/server/app.go: failed to listen: %v", err)
+		}
 	}
 
 	// Create a new GeneralFewerServer object.
-	var serverLogFilename string
-	if *prod {
-		serverLogFilename = serverLogProdFilename
-	} else {
-		serverLogFilename = serverLogDevFilename
+	serverLogger, err := internal.NewServerLoggingObject(config.LogConfig())
+	if err != nil {
+		log.Fatalf("fewer_grpc/server/app.go: failed to set up server logging: %v", err)
 	}
-	genServer := internal.NewGeneralFewerServer(serverLogFilename, lis, *prod)
+	// Reopen the server log file on SIGHUP, so that it can be rotated by an external tool (e.g., logrotate).
+	serverLogger.Logger().ReopenOnSignal(syscall.SIGHUP)
+	genServer, err := internal.NewGeneralFewerServerWithConfig(serverLogger, listeners, config)
+	if err != nil {
+		log.Fatalf("fewer_grpc/server/app.go: failed to set up server: %v", err)
+	}
+
+	if socketActivated {
+		serverLogger.ServerLogInfo("method", "main", fmt.Sprintf("Serving on %d sockets passed by systemd, instead of the configured listeners", len(listeners)))
+	}
+	// Report readiness, watchdog keep-alives and shutdown to systemd, if it started the server.
+	genServer.SetSystemdNotifier(internal.NewSystemdNotifierFromEnv())
+
+	// Open the aggregate sink, if one was requested, and attach it to the server.
+	aggregateSink, err := internal.NewAggregateSink(config.Aggregation.Sink, config.Aggregation.SinkPath)
+	if err != nil {
+		log.Fatalf("fewer_grpc/server/app.go: failed to open aggregate sink: %v", err)
+	}
+	if aggregateSink != nil {
+		genServer.SetAggregateSink(aggregateSink)
+	}
+
+	// Reload the configuration on SIGHUP, the same way it was loaded at startup.
+	genServer.SetConfigLoader(func() (internal.ServerConfig, error) {
+		return internal.LoadServerConfig(*configPath, os.LookupEnv, applyFlags)
+	})
 
 	// Have the GeneralFewerServer object serve clients.  This method also handles
 	//   shutdowns or server failures.
 	genServer.ListenAndServe()
-}
\ No newline at end of file
+}
+
+// Function that overrides the settings of the server configuration with the flags given explicitly
+//   in the 'go run' command.  Flags left out keep the settings of the configuration file and
+//   environment variables.
+func applyFlags(config *internal.ServerConfig) {
+	listenersGiven := false
+	flag.Visit(func(f *flag.Flag) { listenersGiven = listenersGiven || f.Name == "listeners" })
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "address", "port":
+			if !listenersGiven {
+				config.Listeners = []string{net.JoinHostPort(*address, strconv.Itoa(*port))}
+			}
+		case "listeners":
+			config.Listeners = logging.SplitDestinations(*listenersFlag)
+		case "unixSocketMode":
+			config.UnixSocketMode = *unixSocketMode
+		case "keepaliveTime":
+			config.Keepalive.Time = *keepaliveTime
+		case "keepaliveTimeout":
+			config.Keepalive.Timeout = *keepaliveTimeout
+		case "keepaliveMinPingInterval":
+			config.Keepalive.MinPingInterval = *keepaliveMinPingInterval
+		case "keepalivePermitWithoutStream":
+			config.Keepalive.PermitWithoutStream = *keepalivePermitWithoutStream
+		case "maxConnectionIdle":
+			config.Keepalive.MaxConnectionIdle = *maxConnectionIdle
+		case "maxConnectionAge":
+			config.Keepalive.MaxConnectionAge = *maxConnectionAge
+		case "maxConnectionAgeGrace":
+			config.Keepalive.MaxConnectionAgeGrace = *maxConnectionAgeGrace
+		case "prod":
+			config.Prod = *prod
+		case "ackInterval":
+			config.Aggregation.AckInterval = *ackInterval
+		case "sink":
+			config.Aggregation.Sink = *sink
+		case "sinkPath":
+			config.Aggregation.SinkPath = *sinkPath
+		case "logDir":
+			config.Logging.Dir = *logDir
+		case "logFile":
+			config.Logging.File = *logFile
+		case "logLevel":
+			config.Logging.Level = *logLevel
+		case "logFormat":
+			config.Logging.Format = *logFormat
+		case "logOutputs":
+			config.Logging.Outputs = logging.SplitDestinations(*logOutputs)
+		case "logMaxSize":
+			config.Logging.MaxSize = *logMaxSize
+		case "logMaxAge":
+			config.Logging.MaxAge = *logMaxAge
+		case "logMaxBackups":
+			config.Logging.MaxBackups = *logMaxBackups
+		case "logCompress":
+			config.Logging.Compress = *logCompress
+		}
+	})
+}
@@ -0,0 +1,64 @@
+// Package fewerserver exposes the general Fewer Service server outside of the server application,
+//   so that it can be run in-process by other packages of the module (e.g., the end-to-end test
+//   harness of the client), which cannot import the server's internal package themselves.
+package fewerserver
+
+import (
+	"net"
+
+	"github.com/astronomical3/fewer_grpc/server/internal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// The general gRPC server hosting the Fewer Service, and the logging interface it logs through.
+type GeneralFewerServer = internal.GeneralFewerServer
+type ServerLogger = internal.ServerLogger
+
+// In-memory server activity logger capturing every entry for tests, and the entries it captures.
+type RecordingServerLogger = internal.RecordingServerLogger
+type ServerLogRecord = internal.ServerLogRecord
+
+// Option of a server, given to its constructors.
+type Option = internal.Option
+
+// Settings of the server, which can be applied to a serving server with its ApplyConfig() method.
+type ServerConfig = internal.ServerConfig
+
+// Number of inputs the Fewer Service adds together into each aggregate.
+const DefaultBatchSize = internal.DefaultBatchSize
+
+// Return the default settings of the server.
+func DefaultServerConfig() ServerConfig {
+	return internal.DefaultServerConfig()
+}
+
+// Create an empty in-memory server activity logger.
+func NewRecordingServerLogger() *RecordingServerLogger {
+	return internal.NewRecordingServerLogger()
+}
+
+// Create a new general gRPC server serving on lis, logging to the given server logging object.
+func NewGeneralFewerServerWithLogger(serverLogger ServerLogger, lis net.Listener, options ...Option) *GeneralFewerServer {
+	return internal.NewGeneralFewerServerWithLogger(serverLogger, lis, options...)
+}
+
+// Create a new general gRPC server from the settings of a server configuration, serving on every
+//   one of the given listeners, and logging to the given server logging object.
+func NewGeneralFewerServerWithConfig(serverLogger ServerLogger, listeners []net.Listener, config ServerConfig, options ...Option) (*GeneralFewerServer, error) {
+	return internal.NewGeneralFewerServerWithConfig(serverLogger, listeners, config, options...)
+}
+
+// Return options adding interceptors, stats handlers or gRPC server options to a server.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return internal.WithUnaryInterceptors(interceptors...)
+}
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return internal.WithStreamInterceptors(interceptors...)
+}
+func WithStatsHandlers(handlers ...stats.Handler) Option {
+	return internal.WithStatsHandlers(handlers...)
+}
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return internal.WithServerOptions(opts...)
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/codes"
+)
+
+
+
+//*************************************************************************************************
+// Definition of the FewerAdmin service, registered alongside the Fewer Service, through which
+//   operators list the open aggregation streams of the server, cancel them, and drain the server.
+//   Its calls are authenticated with the admin tokens of the server (see newAdminTokenAuthenticator()).
+type FewerAdminService struct {
+	pb.UnimplementedFewerAdminServer
+	serverLogger ServerLogger
+	// General server whose Fewer Service is administered.
+	fs           *GeneralFewerServer
+}
+
+// Constructor function for creating a new instance of the FewerAdmin service, administering the
+//   Fewer Service of the given general server.
+func newFewerAdminService(fs *GeneralFewerServer) *FewerAdminService {
+	return &FewerAdminService{serverLogger: fs.serverLogger, fs: fs}
+}
+
+// Implementation of the ListStreams() RPC, which describes every open aggregation stream, oldest
+//   first: its client, labels, start time, and how far its aggregation got.
+func (a *FewerAdminService) ListStreams(ctx context.Context, req *pb.ListStreamsRequest) (*pb.ListStreamsResponse, error) {
+	limiter := a.fs.srv.limiter
+	resp := &pb.ListStreamsResponse{Draining: limiter.isDraining()}
+	for _, ls := range limiter.activeStreams() {
+		resp.Streams = append(resp.Streams, ls.describe())
+	}
+	a.serverLogger.ServerLogInfo("rpc", "pb.FewerAdmin_ListStreams", fmt.Sprintf("Listing %d open aggregation streams", len(resp.Streams)))
+	return resp, nil
+}
+
+// Implementation of the CancelStream() RPC, which ends an open aggregation stream with a Canceled
+//   status error, the reason of the request being in its trailer.  A stream that is not open is
+//   reported with a NotFound status error.
+func (a *FewerAdminService) CancelStream(ctx context.Context, req *pb.CancelStreamRequest) (*pb.CancelStreamResponse, error) {
+	ls, ok := a.fs.srv.limiter.cancelStream(req.StreamId, req.Reason)
+	if !ok {
+		a.serverLogger.ServerLogWarn("rpc", "pb.FewerAdmin_CancelStream", fmt.Sprintf("Cannot cancel stream %q, as it is not open", req.StreamId))
+		return nil, statusError(
+			codes.NotFound,
+			pb.ErrorReason_STREAM_NOT_FOUND,
+			fmt.Sprintf("no aggregation stream %q is open", req.StreamId),
+			map[string]string{"stream_id": req.StreamId},
+		)
+	}
+	return &pb.CancelStreamResponse{Stream: ls.describe()}, nil
+}
+
+// Implementation of the Drain() RPC, which has the server reject new aggregation calls while its
+//   open streams run to their end, and then shut down gracefully if the request asks for it.
+func (a *FewerAdminService) Drain(ctx context.Context, req *pb.DrainRequest) (*pb.DrainResponse, error) {
+	open := a.fs.Drain(req.Shutdown)
+	return &pb.DrainResponse{OpenStreams: int32(open)}, nil
+}
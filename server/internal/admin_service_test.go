@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Helper function that returns a context presenting the given admin token to the FewerAdmin service.
+func adminContext(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), AdminAuthorizationMetadataKey, "Bearer "+token)
+}
+
+// Test that the FewerAdmin service is turned off without admin tokens, and only takes calls
+//   presenting one of them once it has some, apart from the tokens of Fewer Service clients.
+func TestAdminServiceAuth(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, conn := newBufconnGeneralFewerServerConn(t, serverLogger, loadTestServerConfig(t, map[string]string{
+		"FEWER_AUTH_TOKENS": "s3cret",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+	admin := pb.NewFewerAdminClient(conn)
+
+	_, err := admin.ListStreams(adminContext("s3cret"), &pb.ListStreamsRequest{})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.PermissionDenied || info.Reason != "ADMIN_DISABLED" {
+		t.Errorf("ListStreams() without admin tokens error = %v (reason %s), want PermissionDenied ADMIN_DISABLED", err, info.Reason)
+	}
+
+	config := fs.Config()
+	config.Auth.AdminTokens = []string{"adm1n"}
+	if err := fs.ApplyConfig(config); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	serverLogger.AssertLogged(t, "info", "Changed auth.admin_tokens from 0 tokens to 1 tokens")
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantReason string
+	}{
+		{"no token", context.Background(), "MISSING_TOKEN"},
+		{"client token", adminContext("s3cret"), "INVALID_TOKEN"},
+		{"admin token as client token", metadata.AppendToOutgoingContext(context.Background(), AuthorizationMetadataKey, "Bearer adm1n"), "MISSING_TOKEN"},
+		{"admin token", adminContext("adm1n"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := admin.ListStreams(tt.ctx, &pb.ListStreamsRequest{})
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("ListStreams() error = %v, want none", err)
+				}
+				return
+			}
+			if info := errorInfoOf(t, err); status.Code(err) != codes.Unauthenticated || info.Reason != tt.wantReason {
+				t.Errorf("ListStreams() error = %v (reason %s), want Unauthenticated %s", err, info.Reason, tt.wantReason)
+			}
+		})
+	}
+
+	// The admin token does not let a client in.
+	_, err = pb.NewFewerServiceClient(conn).AggregateBatch(adminContext("adm1n"), &pb.AggregateBatchRequest{InputNums: []int32{1}})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("AggregateBatch() with admin token error = %v, want Unauthenticated", err)
+	}
+}
+
+// Test that the open aggregation streams are listed with how far their aggregation got, and that
+//   cancelling one ends it with a Canceled status error, its reason being in its trailer.
+func TestAdminListAndCancelStreams(t *testing.T) {
+	fs, conn := newBufconnGeneralFewerServerConn(t, NewRecordingServerLogger(), loadTestServerConfig(t, map[string]string{
+		"FEWER_AUTH_ADMIN_TOKENS": "adm1n",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+	admin := pb.NewFewerAdminClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), StreamKeyMetadataKey, "sensor-1")
+	stream, err := pb.NewFewerServiceClient(conn).GetAggregatesStream(ctx)
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3, 4}})
+
+	// Wait for the server to aggregate the inputs.
+	var listed *pb.ActiveStream
+	deadline := time.Now().Add(time.Second)
+	for listed.GetInputsSeen() != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("listed stream = %v, want one with 4 inputs seen", listed)
+		}
+		resp, err := admin.ListStreams(adminContext("adm1n"), &pb.ListStreamsRequest{})
+		if err != nil {
+			t.Fatalf("ListStreams() error = %v", err)
+		}
+		if len(resp.Streams) == 1 {
+			listed = resp.Streams[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if listed.Method != "GetAggregatesStream" || listed.Key != "sensor-1" || listed.PartialSum != 4 || listed.Batches != 1 ||
+		listed.Reducer != SumReducer || listed.Peer == "" || listed.StartedAt == nil {
+		t.Errorf("listed stream = %v, want a GetAggregatesStream keyed sensor-1 with a partial sum of 4 after 1 batch", listed)
+	}
+
+	resp, err := admin.CancelStream(adminContext("adm1n"), &pb.CancelStreamRequest{StreamId: listed.StreamId, Reason: "maintenance"})
+	if err != nil || resp.Stream.StreamId != listed.StreamId {
+		t.Fatalf("CancelStream() = %v, %v, want the cancelled stream", resp, err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("cancelled stream error = %v, want Canceled", err)
+	}
+	if reason := stream.Trailer().Get(CancelReasonTrailerKey); len(reason) != 1 || reason[0] != "maintenance" {
+		t.Errorf("cancel reason trailer of cancelled stream = %v, want maintenance", reason)
+	}
+	if trailer := summaryTrailerOf(t, stream); trailer.TotalInputs != 4 {
+		t.Errorf("summary trailer of cancelled stream = %v, want 4 inputs", trailer)
+	}
+
+	waitForOpenStreams(t, fs, 0)
+	_, err = admin.CancelStream(adminContext("adm1n"), &pb.CancelStreamRequest{StreamId: listed.StreamId})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.NotFound || info.Reason != "STREAM_NOT_FOUND" {
+		t.Errorf("CancelStream() of ended stream error = %v (reason %s), want NotFound STREAM_NOT_FOUND", err, info.Reason)
+	}
+}
+
+// Test that a draining server rejects new aggregation calls and reports the Fewer Service as not
+//   serving, lets its open streams end, and is then asked to stop.
+func TestAdminDrain(t *testing.T) {
+	fs, conn := newBufconnGeneralFewerServerConn(t, NewRecordingServerLogger(), loadTestServerConfig(t, map[string]string{
+		"FEWER_AUTH_ADMIN_TOKENS": "adm1n",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+	client := pb.NewFewerServiceClient(conn)
+
+	stream, err := client.GetAggregatesStream(context.Background())
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	waitForOpenStreams(t, fs, 1)
+
+	resp, err := pb.NewFewerAdminClient(conn).Drain(adminContext("adm1n"), &pb.DrainRequest{Shutdown: true})
+	if err != nil || resp.OpenStreams != 1 {
+		t.Fatalf("Drain() = %v, %v, want 1 open stream", resp, err)
+	}
+	_, err = client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1}})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.Unavailable || info.Reason != "DRAINING" {
+		t.Errorf("AggregateBatch() while draining error = %v (reason %s), want Unavailable DRAINING", err, info.Reason)
+	}
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.FewerService_ServiceDesc.ServiceName})
+	if err != nil || health.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health of Fewer Service while draining = %v, %v, want NOT_SERVING", health, err)
+	}
+
+	// The open stream still runs to its end, after which the server is asked to stop.
+	select {
+	case <-fs.StopRequested():
+		t.Fatalf("server asked to stop while a stream is still open")
+	default:
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3}})
+	stream.CloseSend()
+	var aggregates int
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		if resp.GetAggregate() != nil {
+			aggregates++
+		}
+	}
+	if aggregates != 1 {
+		t.Errorf("aggregates received on draining server = %d, want 1", aggregates)
+	}
+	select {
+	case <-fs.StopRequested():
+	case <-time.After(time.Second):
+		t.Errorf("server not asked to stop once drained")
+	}
+}
@@ -0,0 +1,471 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astronomical3/fewer_grpc/internal/wire"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Names of the reducers the Fewer Service can apply to the batches of inputs of a stream (see the
+//   wire package).  Recorded along with every aggregate, so that persisted batches stay meaningful.
+const SumReducer = wire.SumReducer
+const MinReducer = wire.MinReducer
+const MaxReducer = wire.MaxReducer
+
+
+
+//************************************************************************************************
+// Definition of a record describing one aggregate (batch result) that the Fewer Service sent back
+//   to a client.
+type AggregateRecord struct {
+	// ID of the GetAggregatesStream() stream the aggregate was produced on.
+	StreamID        string          `json:"stream_id"`
+	// Key the inputs of the aggregate were grouped under.  Inputs are keyed per stream, with
+	//   the key the client labelled the stream with (empty if it did not).
+	Key             string          `json:"key,omitempty"`
+	// Tenant the stream was opened for, if the client labelled it with one.
+	Tenant          string          `json:"tenant,omitempty"`
+	// Window of stream inputs that the aggregate covers.
+	Window          AggregateWindow `json:"window"`
+	// Name of the reducer used to produce the aggregate (e.g., "sum").
+	Reducer         string          `json:"reducer"`
+	// Aggregate value sent back to the client.
+	Value           int64           `json:"value"`
+	// Whether the aggregate is a residual sum of a batch that was not full at end of stream.
+	Partial         bool            `json:"partial"`
+	// Time the stream was opened, the window's first input was received, and the aggregate
+	//   was sent back to the client.
+	StreamStartedAt time.Time       `json:"stream_started_at"`
+	WindowStartedAt time.Time       `json:"window_started_at"`
+	EmittedAt       time.Time       `json:"emitted_at"`
+}
+
+// Definition of the window of stream inputs that an aggregate covers.  Input positions are
+//   counted from 1, in the order the inputs were received on the stream.
+type AggregateWindow struct {
+	// Position of the aggregate among the aggregates of its stream, counted from 0.
+	Index         int64 `json:"index"`
+	FirstInput    int64 `json:"first_input"`
+	LastInput     int64 `json:"last_input"`
+	// Number of inputs of the window that broke the validation rules of the stream, and were
+	//   skipped or clamped.
+	SkippedInputs int64 `json:"skipped_inputs,omitempty"`
+	ClampedInputs int64 `json:"clamped_inputs,omitempty"`
+}
+
+
+
+//************************************************************************************************
+// Definition of an AggregateSink interface for backends that persist every aggregate the Fewer
+//   Service emits, giving an audit trail of the batches sent back to clients.
+type AggregateSink interface {
+	RecordAggregate(record AggregateRecord) error
+	Close() error
+}
+
+// Definition of an AggregateStore interface for sinks that can also read back the aggregates they
+//   have persisted.  QueryAggregates returns one page of records matching the query, along with
+//   the token of the next page (empty once there are no more matching records).  A page token the
+//   store did not hand out is reported with an error wrapping ErrInvalidPageToken.
+type AggregateStore interface {
+	AggregateSink
+	QueryAggregates(query AggregateQuery) ([]AggregateRecord, string, error)
+}
+
+// Error wrapped by the errors of AggregateStore queries given an invalid page token.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Definition of a query over persisted aggregate records.  Empty filters match every record.
+type AggregateQuery struct {
+	StreamID      string
+	Key           string
+	Tenant        string
+	// Only records emitted at or after EmittedAfter, and before EmittedBefore, match.  Zero
+	//   times leave that end of the range open.
+	EmittedAfter  time.Time
+	EmittedBefore time.Time
+	// Maximum number of records to return in the page (0 for no maximum).
+	PageSize      int
+	// Token returned by a previous query to resume from.  Empty to start from the beginning.
+	PageToken     string
+}
+
+// Method of the AggregateQuery that reports whether a record matches the query's filters.
+func (q AggregateQuery) matches(record AggregateRecord) bool {
+	if q.StreamID != "" && record.StreamID != q.StreamID {
+		return false
+	}
+	if q.Key != "" && record.Key != q.Key {
+		return false
+	}
+	if q.Tenant != "" && record.Tenant != q.Tenant {
+		return false
+	}
+	if !q.EmittedAfter.IsZero() && record.EmittedAt.Before(q.EmittedAfter) {
+		return false
+	}
+	if !q.EmittedBefore.IsZero() && !record.EmittedAt.Before(q.EmittedBefore) {
+		return false
+	}
+	return true
+}
+
+
+
+//************************************************************************************************
+// Definition of an append-only sink that writes every aggregate record as one JSON object per
+//   line of a file.
+type JSONLinesAggregateSink struct {
+	// Guards writes to the file, as several streams may emit aggregates at the same time.
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// Constructor function that creates or opens (in append mode) a JSON-lines aggregate file.
+func NewJSONLinesAggregateSink(path string) (*JSONLinesAggregateSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesAggregateSink{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Method of the JSONLinesAggregateSink that appends one aggregate record to the file.
+func (s *JSONLinesAggregateSink) RecordAggregate(record AggregateRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(record)
+}
+
+// Method of the JSONLinesAggregateSink that reads back one page of matching records from the
+//   file.  Page tokens are byte offsets into the file, so paging resumes at the first matching
+//   record after the previous page.
+func (s *JSONLinesAggregateSink) QueryAggregates(query AggregateQuery) ([]AggregateRecord, string, error) {
+	var offset int64
+	if query.PageToken != "" {
+		var err error
+		offset, err = strconv.ParseInt(query.PageToken, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("%w %q", ErrInvalidPageToken, query.PageToken)
+		}
+	}
+
+	// Only read up to the size of the file when the query started, which holds the complete lines
+	//   written so far, so that records can keep being appended while the file is scanned.
+	s.mu.Lock()
+	info, err := s.file.Stat()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	var records []AggregateRecord
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, max(info.Size()-offset, 0)))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, "", nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		lineOffset := offset
+		offset += int64(len(line))
+		var record AggregateRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, "", fmt.Errorf("corrupt aggregate record at offset %d: %v", lineOffset, err)
+		}
+		if !query.matches(record) {
+			continue
+		}
+		// Only hand out a next page token if a record matches after this page, which the next
+		//   page starts at.
+		if query.PageSize > 0 && len(records) == query.PageSize {
+			return records, strconv.FormatInt(lineOffset, 10), nil
+		}
+		records = append(records, record)
+	}
+}
+
+// Method of the JSONLinesAggregateSink for closing the aggregate file properly.
+func (s *JSONLinesAggregateSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+
+
+//************************************************************************************************
+// Definition of a sink that stores aggregate records in an embedded bbolt key-value database
+//   file.  Records are stored in a single bucket, keyed by their emission time followed by a
+//   sequence number, so that they are kept in the order they were emitted.  Records are written by
+//   a background writer, which commits every record queued up in the meantime in one transaction,
+//   so that streams emitting aggregates do not wait on a disk sync for each of them.
+type BoltAggregateSink struct {
+	db       *bolt.DB
+	// Guards the requests channel against being closed while records are queued on it.
+	mu       sync.RWMutex
+	closed   bool
+	// Requests queued up for the background writer, and channel closed once it has stopped.
+	requests chan boltWriteRequest
+	done     chan struct{}
+	// Error of the last failed write, reported by the next call to RecordAggregate() or Close().
+	errMu    sync.Mutex
+	err      error
+}
+
+// Definition of a request to the background writer of a BoltAggregateSink: either a record to
+//   write, or a flush, whose channel is closed once every record queued before it is written.
+type boltWriteRequest struct {
+	record  AggregateRecord
+	flushed chan struct{}
+}
+
+// Name of the bucket that BoltAggregateSink stores aggregate records in.
+var boltAggregatesBucket = []byte("aggregates")
+
+// Number of requests a BoltAggregateSink can queue up for its background writer before
+//   RecordAggregate() blocks, and maximum number of records written in one transaction.
+const boltWriteQueueSize = 1024
+const boltMaxWriteBatch = 1024
+
+// Constructor function that creates or opens a bbolt aggregate database file, and starts its
+//   background writer.
+func NewBoltAggregateSink(path string) (*BoltAggregateSink, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltAggregatesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &BoltAggregateSink{
+		db:       db,
+		requests: make(chan boltWriteRequest, boltWriteQueueSize),
+		done:     make(chan struct{}),
+	}
+	go s.write()
+	return s, nil
+}
+
+// Method of the BoltAggregateSink that queues one aggregate record to be stored in the database.
+//   The record is written in the background; if an earlier write failed, its error is returned.
+func (s *BoltAggregateSink) RecordAggregate(record AggregateRecord) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return bolt.ErrDatabaseNotOpen
+	}
+	s.requests <- boltWriteRequest{record: record}
+	return s.takeErr()
+}
+
+// Internal method of the BoltAggregateSink that returns the error of the last failed write, if
+//   any, and clears it.
+func (s *BoltAggregateSink) takeErr() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	err := s.err
+	s.err = nil
+	if err != nil {
+		return fmt.Errorf("could not write earlier aggregate records: %w", err)
+	}
+	return nil
+}
+
+// Internal method of the BoltAggregateSink that waits until every record queued so far is
+//   written, so that queries read back what was recorded before them.
+func (s *BoltAggregateSink) flush() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	flushed := make(chan struct{})
+	s.requests <- boltWriteRequest{flushed: flushed}
+	<-flushed
+}
+
+// Internal method of the BoltAggregateSink run by its background writer until the requests
+//   channel is closed.  Every record queued up while a transaction was being committed is written
+//   in the next one, and flushes are answered once the records queued before them are written.
+func (s *BoltAggregateSink) write() {
+	defer close(s.done)
+	for request := range s.requests {
+		var records []AggregateRecord
+		var flushes []chan struct{}
+		for {
+			if request.flushed != nil {
+				flushes = append(flushes, request.flushed)
+			} else {
+				records = append(records, request.record)
+			}
+			if len(records) == boltMaxWriteBatch || len(s.requests) == 0 {
+				break
+			}
+			request = <-s.requests
+		}
+		if err := s.put(records); err != nil {
+			s.errMu.Lock()
+			s.err = err
+			s.errMu.Unlock()
+		}
+		for _, flushed := range flushes {
+			close(flushed)
+		}
+	}
+}
+
+// Internal method of the BoltAggregateSink that stores records in the database in one transaction.
+func (s *BoltAggregateSink) put(records []AggregateRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAggregatesBucket)
+		for _, record := range records {
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 16)
+			binary.BigEndian.PutUint64(key[:8], uint64(record.EmittedAt.UnixNano()))
+			binary.BigEndian.PutUint64(key[8:], seq)
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Method of the BoltAggregateSink that reads back one page of matching records from the
+//   database.  Page tokens are the hex-encoded keys of the first matching record of the next page.  As keys
+//   start with the emission time, the time range of the query limits the part of the bucket that
+//   is scanned.
+func (s *BoltAggregateSink) QueryAggregates(query AggregateQuery) ([]AggregateRecord, string, error) {
+	var start []byte
+	if query.PageToken != "" {
+		var err error
+		start, err = hex.DecodeString(query.PageToken)
+		if err != nil || len(start) != 16 {
+			return nil, "", fmt.Errorf("%w %q", ErrInvalidPageToken, query.PageToken)
+		}
+	} else if !query.EmittedAfter.IsZero() {
+		start = make([]byte, 16)
+		binary.BigEndian.PutUint64(start[:8], uint64(query.EmittedAfter.UnixNano()))
+	}
+	var end []byte
+	if !query.EmittedBefore.IsZero() {
+		end = make([]byte, 8)
+		binary.BigEndian.PutUint64(end, uint64(query.EmittedBefore.UnixNano()))
+	}
+
+	s.flush()
+	var records []AggregateRecord
+	var nextPageToken string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltAggregatesBucket).Cursor()
+		var key, value []byte
+		if start != nil {
+			key, value = cursor.Seek(start)
+		} else {
+			key, value = cursor.First()
+		}
+		for ; key != nil; key, value = cursor.Next() {
+			if end != nil && bytes.Compare(key[:8], end) >= 0 {
+				return nil
+			}
+			var record AggregateRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("corrupt aggregate record %x: %v", key, err)
+			}
+			if !query.matches(record) {
+				continue
+			}
+			// Only hand out a next page token if a record matches after this page, which the next
+			//   page starts at.
+			if query.PageSize > 0 && len(records) == query.PageSize {
+				nextPageToken = hex.EncodeToString(key)
+				return nil
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return records, nextPageToken, nil
+}
+
+// Method of the BoltAggregateSink for closing the database file properly, once the background
+//   writer has written every queued record.  The error of the last failed write is returned, if
+//   there was one.
+func (s *BoltAggregateSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.requests)
+	s.mu.Unlock()
+	<-s.done
+	return errors.Join(s.takeErr(), s.db.Close())
+}
+
+
+
+//************************************************************************************************
+// Constructor function that opens the aggregate sink backend with the given name ("jsonl" or
+//   "bolt") at the given path.  An empty or "none" backend name returns a nil sink, meaning
+//   aggregates are not persisted.
+func NewAggregateSink(backend, path string) (AggregateSink, error) {
+	switch backend {
+	case "", "none":
+		return nil, nil
+	case "jsonl":
+		sink, err := NewJSONLinesAggregateSink(path)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case "bolt":
+		sink, err := NewBoltAggregateSink(path)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	default:
+		return nil, fmt.Errorf("unknown aggregate sink backend %q", backend)
+	}
+}
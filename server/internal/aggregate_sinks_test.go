@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Backends of the AggregateStore tests, each run against the same table.
+var aggregateStoreBackends = []struct {
+	name string
+	open func(path string) (AggregateStore, error)
+}{
+	{"jsonl", func(path string) (AggregateStore, error) { return NewJSONLinesAggregateSink(path) }},
+	{"bolt", func(path string) (AggregateStore, error) { return NewBoltAggregateSink(path) }},
+}
+
+// Time the first of the testAggregateRecords() was emitted at.  The others follow a second apart.
+var testEmittedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// Helper function that returns the records of three streams, labelled with keys a and b and tenants
+//   t1 and t2, in the order they were emitted.
+func testAggregateRecords() []AggregateRecord {
+	labels := []struct {
+		streamID, key, tenant string
+		index                 int64
+	}{
+		{"s1", "a", "t1", 0},
+		{"s1", "a", "t1", 1},
+		{"s2", "b", "t1", 0},
+		{"s2", "b", "t1", 1},
+		{"s3", "a", "t2", 0},
+	}
+	var records []AggregateRecord
+	for i, label := range labels {
+		emittedAt := testEmittedAt.Add(time.Duration(i) * time.Second)
+		records = append(records, AggregateRecord{
+			StreamID:        label.streamID,
+			Key:             label.key,
+			Tenant:          label.tenant,
+			Window:          AggregateWindow{Index: label.index, FirstInput: 3*label.index + 1, LastInput: 3*label.index + 3},
+			Reducer:         SumReducer,
+			Value:           int64(i),
+			StreamStartedAt: testEmittedAt.Add(-time.Minute),
+			WindowStartedAt: emittedAt.Add(-time.Millisecond),
+			EmittedAt:       emittedAt,
+		})
+	}
+	return records
+}
+
+// Helper function that opens an AggregateStore of the given backend in a new directory, and
+//   records the testAggregateRecords() to it.  Returns the store and the path of its file.
+func newTestAggregateStore(t *testing.T, open func(path string) (AggregateStore, error)) (AggregateStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "aggregates")
+	store, err := open(path)
+	if err != nil {
+		t.Fatalf("opening aggregate store error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, record := range testAggregateRecords() {
+		if err := store.RecordAggregate(record); err != nil {
+			t.Fatalf("RecordAggregate() error = %v", err)
+		}
+	}
+	return store, path
+}
+
+// Helper function that names records by stream ID and window index (e.g., "s1/0").
+func recordNames(records []AggregateRecord) []string {
+	names := []string{}
+	for _, record := range records {
+		names = append(names, fmt.Sprintf("%s/%d", record.StreamID, record.Window.Index))
+	}
+	return names
+}
+
+// Test that the records of an AggregateStore are read back as they were recorded, once the store
+//   is closed and opened again, and that it no longer records aggregates once closed.
+func TestAggregateStoreRoundTrip(t *testing.T) {
+	for _, backend := range aggregateStoreBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, path := newTestAggregateStore(t, backend.open)
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if err := store.RecordAggregate(testAggregateRecords()[0]); err == nil {
+				t.Errorf("RecordAggregate() after Close() error = nil, want an error")
+			}
+
+			store, err := backend.open(path)
+			if err != nil {
+				t.Fatalf("reopening aggregate store error = %v", err)
+			}
+			defer store.Close()
+			records, nextPageToken, err := store.QueryAggregates(AggregateQuery{PageSize: 100})
+			if err != nil {
+				t.Fatalf("QueryAggregates() error = %v", err)
+			}
+			if !reflect.DeepEqual(records, testAggregateRecords()) || nextPageToken != "" {
+				t.Errorf("QueryAggregates() = %v, %q, want the recorded records %v and no next page", records, nextPageToken, testAggregateRecords())
+			}
+		})
+	}
+}
+
+// Test of the stream, key, tenant and time range filters of AggregateStore queries.
+func TestAggregateStoreFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query AggregateQuery
+		want  []string
+	}{
+		{"no filter", AggregateQuery{}, []string{"s1/0", "s1/1", "s2/0", "s2/1", "s3/0"}},
+		{"stream", AggregateQuery{StreamID: "s2"}, []string{"s2/0", "s2/1"}},
+		{"key", AggregateQuery{Key: "a"}, []string{"s1/0", "s1/1", "s3/0"}},
+		{"tenant", AggregateQuery{Tenant: "t2"}, []string{"s3/0"}},
+		{"key and tenant", AggregateQuery{Key: "a", Tenant: "t1"}, []string{"s1/0", "s1/1"}},
+		{"no match", AggregateQuery{StreamID: "s1", Key: "b"}, []string{}},
+		{
+			"time range",
+			AggregateQuery{EmittedAfter: testEmittedAt.Add(time.Second), EmittedBefore: testEmittedAt.Add(3 * time.Second)},
+			[]string{"s1/1", "s2/0"},
+		},
+		{"emitted after", AggregateQuery{EmittedAfter: testEmittedAt.Add(3500 * time.Millisecond)}, []string{"s3/0"}},
+		{"emitted before", AggregateQuery{EmittedBefore: testEmittedAt}, []string{}},
+	}
+	for _, backend := range aggregateStoreBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, _ := newTestAggregateStore(t, backend.open)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.query.PageSize = 100
+					records, _, err := store.QueryAggregates(tt.query)
+					if err != nil {
+						t.Fatalf("QueryAggregates() error = %v", err)
+					}
+					if names := recordNames(records); !reflect.DeepEqual(names, tt.want) {
+						t.Errorf("QueryAggregates() = %v, want %v", names, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+// Test of paging through the records matching AggregateStore queries: every page but the last one
+//   is full and hands out the token of the next one, including when the last page ends exactly at
+//   the end of the matching records.
+func TestAggregateStorePagination(t *testing.T) {
+	tests := []struct {
+		name  string
+		query AggregateQuery
+		want  [][]string
+	}{
+		{"page size 2", AggregateQuery{PageSize: 2}, [][]string{{"s1/0", "s1/1"}, {"s2/0", "s2/1"}, {"s3/0"}}},
+		{"page size 1", AggregateQuery{PageSize: 1}, [][]string{{"s1/0"}, {"s1/1"}, {"s2/0"}, {"s2/1"}, {"s3/0"}}},
+		{"page ending with the records", AggregateQuery{PageSize: 5}, [][]string{{"s1/0", "s1/1", "s2/0", "s2/1", "s3/0"}}},
+		{"page larger than the records", AggregateQuery{PageSize: 10}, [][]string{{"s1/0", "s1/1", "s2/0", "s2/1", "s3/0"}}},
+		{"page ending with the matching records", AggregateQuery{Key: "b", PageSize: 2}, [][]string{{"s2/0", "s2/1"}}},
+		{"filtered pages", AggregateQuery{Key: "a", PageSize: 2}, [][]string{{"s1/0", "s1/1"}, {"s3/0"}}},
+		{
+			"pages within a time range",
+			AggregateQuery{EmittedAfter: testEmittedAt.Add(time.Second), EmittedBefore: testEmittedAt.Add(4 * time.Second), PageSize: 1},
+			[][]string{{"s1/1"}, {"s2/0"}, {"s2/1"}},
+		},
+		{
+			"pages up to the end of a time range",
+			AggregateQuery{EmittedAfter: testEmittedAt.Add(2 * time.Second), PageSize: 3},
+			[][]string{{"s2/0", "s2/1", "s3/0"}},
+		},
+	}
+	for _, backend := range aggregateStoreBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, _ := newTestAggregateStore(t, backend.open)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					query := tt.query
+					var pages [][]string
+					for {
+						records, nextPageToken, err := store.QueryAggregates(query)
+						if err != nil {
+							t.Fatalf("QueryAggregates() error = %v", err)
+						}
+						pages = append(pages, recordNames(records))
+						if nextPageToken == "" || len(pages) > len(tt.want) {
+							break
+						}
+						query.PageToken = nextPageToken
+					}
+					if !reflect.DeepEqual(pages, tt.want) {
+						t.Errorf("pages of QueryAggregates() = %v, want %v", pages, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"sync"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/protobuf/proto"
+)
+
+// Default and maximum number of events buffered for a SubscribeAggregates() subscriber.
+const defaultSubscriberBufferSize = 64
+const maxSubscriberBufferSize = 4096
+
+
+
+//*************************************************************************************************
+// Definition of a subscriber to the aggregates emitted by the Fewer Service, as registered by the
+//   SubscribeAggregates() RPC.
+type aggregateSubscriber struct {
+	key    string
+	tenant string
+	policy pb.SlowConsumerPolicy
+	// Bounded buffer of events waiting to be delivered to the subscriber.
+	events chan *pb.AggregateEvent
+
+	// Number of events dropped since the last delivered event, and whether the subscriber was
+	//   disconnected for being too slow.  Both are guarded by mu.
+	mu           sync.Mutex
+	dropped      uint64
+	// Closed when the subscriber is disconnected for being too slow.
+	slow         chan struct{}
+	disconnected bool
+}
+
+// Method of the aggregateSubscriber that reports whether an event matches its filters.
+func (sub *aggregateSubscriber) matches(event *pb.AggregateEvent) bool {
+	return (sub.key == "" || sub.key == event.Key) && (sub.tenant == "" || sub.tenant == event.Tenant)
+}
+
+// Method of the aggregateSubscriber that hands an event to its buffer without blocking.  If the
+//   buffer is full, the slow consumer policy of the subscriber applies.
+func (sub *aggregateSubscriber) offer(event *pb.AggregateEvent) {
+	select {
+	case sub.events <- event:
+		return
+	default:
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.policy == pb.SlowConsumerPolicy_SLOW_CONSUMER_POLICY_DISCONNECT {
+		if !sub.disconnected {
+			sub.disconnected = true
+			close(sub.slow)
+		}
+		return
+	}
+	sub.dropped++
+}
+
+// Method of the aggregateSubscriber that prepares a buffered event for delivery, reporting how
+//   many events were dropped before it.  Events are shared between subscribers, so the event is
+//   copied before being changed.
+func (sub *aggregateSubscriber) prepare(event *pb.AggregateEvent) *pb.AggregateEvent {
+	sub.mu.Lock()
+	dropped := sub.dropped
+	sub.dropped = 0
+	sub.mu.Unlock()
+	if dropped == 0 {
+		return event
+	}
+	event = proto.Clone(event).(*pb.AggregateEvent)
+	event.DroppedBefore = dropped
+	return event
+}
+
+
+
+//*************************************************************************************************
+// Definition of a broadcaster that fans out every aggregate event the Fewer Service publishes to
+//   all matching subscribers.  Publishing never blocks on a subscriber.
+type aggregateBroadcaster struct {
+	mu          sync.RWMutex
+	nextID      uint64
+	subscribers map[uint64]*aggregateSubscriber
+	// Closed when the broadcaster is closed, ending every subscription.
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// Constructor function for creating a new aggregateBroadcaster without subscribers.
+func newAggregateBroadcaster() *aggregateBroadcaster {
+	return &aggregateBroadcaster{
+		subscribers: make(map[uint64]*aggregateSubscriber),
+		closed:      make(chan struct{}),
+	}
+}
+
+// Method of the aggregateBroadcaster that ends every current and future subscription.  Used on
+//   server shutdown, as subscriptions would otherwise never finish on their own.
+func (b *aggregateBroadcaster) close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+// Method of the aggregateBroadcaster that registers a new subscriber from a SubscribeAggregates()
+//   request.  It returns the subscriber, along with a function that unregisters it.
+func (b *aggregateBroadcaster) subscribe(req *pb.SubscribeAggregatesRequest) (*aggregateSubscriber, func()) {
+	bufferSize := int(req.BufferSize)
+	if bufferSize <= 0 {
+		bufferSize = defaultSubscriberBufferSize
+	} else if bufferSize > maxSubscriberBufferSize {
+		bufferSize = maxSubscriberBufferSize
+	}
+	sub := &aggregateSubscriber{
+		key:    req.Key,
+		tenant: req.Tenant,
+		policy: req.SlowConsumerPolicy,
+		events: make(chan *pb.AggregateEvent, bufferSize),
+		slow:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	return sub, func() {
+		b.mu.Lock()
+		delete(b.subscribers, id)
+		b.mu.Unlock()
+	}
+}
+
+// Method of the aggregateBroadcaster that hands an event to every matching subscriber.
+func (b *aggregateBroadcaster) publish(event *pb.AggregateEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subscribers {
+		if sub.matches(event) {
+			sub.offer(event)
+		}
+	}
+}
+
+// Method of the aggregateBroadcaster that returns the number of registered subscribers.
+func (b *aggregateBroadcaster) subscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Number of inputs the Fewer Service adds together into each aggregate.
+const DefaultBatchSize = 3
+
+// Reducers the aggregation engine can apply to the batches of inputs of a stream.
+var knownReducers = []string{SumReducer, MinReducer, MaxReducer}
+
+
+
+//*************************************************************************************************
+// Definition of the aggregation engine shared by every aggregation RPC of the Fewer Service.  It
+//   reduces inputs into batches of batchSize inputs with the reducer of the stream (adding them
+//   together by default), and hands back a record of every full batch, as well as of the residual
+//   batch left at the end of the inputs.  Inputs are validated against the validation rules of the
+//   stream first.  An aggregator is not safe for concurrent use; every stream, batch or upload
+//   uses its own.  Only its progress and summary can be read from other goroutines (e.g., by the
+//   FewerAdmin service) while it is in use.
+type aggregator struct {
+	batchSize       int
+	streamID        string
+	key             string
+	tenant          string
+	rules           validationRules
+	// Reducer applied to the inputs of every batch (SumReducer, MinReducer or MaxReducer).
+	reducer         string
+	streamStartedAt time.Time
+
+	// Guards what the progress and summary of the aggregator are made of against reads from other
+	//   goroutines.  Reads from the goroutine using the aggregator need no lock.
+	mu              sync.Mutex
+	// Number of inputs added so far, value of the reducer over the inputs of the current batch,
+	//   and whether an input of the current batch was aggregated into it.
+	inputs          int64
+	value           int32
+	batchAggregated bool
+	// Window of inputs covered by the current batch, and the time its first input was added.
+	window          AggregateWindow
+	windowStartedAt time.Time
+
+	// Totals over the whole stream, for its summary.
+	batches         int64
+	grandTotal      int64
+	partialFlushed  bool
+	skipped         int64
+	clamped         int64
+	// Smallest and largest input aggregated so far, and whether there was one.
+	minInput        int32
+	maxInput        int32
+	aggregated      bool
+	// Time the residual batch was flushed, which ends the duration of the stream.
+	flushedAt       time.Time
+}
+
+// Constructor function for creating a new aggregator for a stream with the given ID, labels,
+//   reducer and validation rules.
+func newAggregator(streamID, key, tenant, reducer string, batchSize int, rules validationRules) *aggregator {
+	return &aggregator{
+		batchSize:       batchSize,
+		streamID:        streamID,
+		key:             key,
+		tenant:          tenant,
+		rules:           rules,
+		reducer:         reducer,
+		streamStartedAt: time.Now().UTC(),
+		window:          AggregateWindow{Index: 0, FirstInput: 1},
+	}
+}
+
+// Method of the aggregator that validates one input and adds it to the current batch.  A skipped
+//   input takes up its place in the batch without being aggregated, and a clamped one aggregates
+//   the bound it was clamped to.  If the input is rejected, it is not added, and why it is invalid is
+//   returned as violation.  If adding the input fills the batch, the record of the batch is
+//   returned with full set to true, and a new batch is started.
+func (a *aggregator) add(inputNum int32) (record AggregateRecord, full bool, violation string) {
+	value, outcome, violation := a.rules.check(inputNum)
+	if outcome == inputRejected {
+		return AggregateRecord{}, false, violation
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inputs++
+	if a.inputs == a.window.FirstInput {
+		a.windowStartedAt = time.Now().UTC()
+	}
+	switch outcome {
+	case inputSkipped:
+		a.window.SkippedInputs++
+		a.skipped++
+	case inputClamped:
+		a.window.ClampedInputs++
+		a.clamped++
+		a.aggregate(value)
+	default:
+		a.aggregate(value)
+	}
+	if a.inputs%int64(a.batchSize) != 0 {
+		return AggregateRecord{}, false, violation
+	}
+	return a.emit(false), true, violation
+}
+
+// Internal method of the aggregator that applies the reducer of the stream to a validated value
+//   and the value of the current batch.
+func (a *aggregator) aggregate(value int32) {
+	switch a.reducer {
+	case MinReducer:
+		if !a.batchAggregated || value < a.value {
+			a.value = value
+		}
+	case MaxReducer:
+		if !a.batchAggregated || value > a.value {
+			a.value = value
+		}
+	default:
+		a.value += value
+	}
+	a.batchAggregated = true
+	if !a.aggregated || value < a.minInput {
+		a.minInput = value
+	}
+	if !a.aggregated || value > a.maxInput {
+		a.maxInput = value
+	}
+	a.aggregated = true
+}
+
+// Method of the aggregator that flushes the residual batch left once all inputs were added.  If
+//   the inputs did not end on a full batch, the record of the residual batch is returned with ok
+//   set to true.
+func (a *aggregator) flush() (record AggregateRecord, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushedAt = time.Now().UTC()
+	if a.inputs == 0 || a.inputs%int64(a.batchSize) == 0 {
+		return AggregateRecord{}, false
+	}
+	a.partialFlushed = true
+	return a.emit(true), true
+}
+
+// Internal method of the aggregator that closes the current batch, returning its record and
+//   starting the next batch.
+func (a *aggregator) emit(partial bool) AggregateRecord {
+	a.window.LastInput = a.inputs
+	record := AggregateRecord{
+		StreamID:        a.streamID,
+		Key:             a.key,
+		Tenant:          a.tenant,
+		Window:          a.window,
+		Reducer:         a.reducer,
+		Value:           int64(a.value),
+		Partial:         partial,
+		StreamStartedAt: a.streamStartedAt,
+		WindowStartedAt: a.windowStartedAt,
+		EmittedAt:       time.Now().UTC(),
+	}
+	a.batches++
+	a.grandTotal += int64(a.value)
+	a.value, a.batchAggregated = 0, false
+	a.window = AggregateWindow{Index: a.window.Index + 1, FirstInput: a.inputs + 1}
+	return record
+}
+
+// Method of the aggregator that takes back the record of a batch that could not be sent to the
+//   client, so that the summary of the stream only counts the batches the client received.  The
+//   inputs of the batch stay counted.
+func (a *aggregator) retract(record AggregateRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.batches--
+	a.grandTotal -= record.Value
+	if record.Partial {
+		a.partialFlushed = false
+	}
+}
+
+// Method of the aggregator that returns the current (not yet emitted) value of the batch.
+func (a *aggregator) currentValue() int32 {
+	return a.value
+}
+
+// Method of the aggregator that returns the number of inputs added so far, the value of the
+//   current batch, and the number of batches emitted so far.  Safe to call while the aggregator
+//   is in use.
+func (a *aggregator) progress() (inputs int64, value int32, batches int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inputs, a.value, a.batches
+}
+
+// Method of the aggregator that returns a summary of all inputs added so far.  Its duration runs
+//   until the residual batch was flushed, or until now if it was not.  Safe to call while the
+//   aggregator is in use.
+func (a *aggregator) summary() *pb.AggregationSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	endedAt := a.flushedAt
+	if endedAt.IsZero() {
+		endedAt = time.Now().UTC()
+	}
+	return &pb.AggregationSummary{
+		StreamId:         a.streamID,
+		TotalInputs:      a.inputs,
+		TotalBatches:     a.batches,
+		GrandTotal:       a.grandTotal,
+		PartialLastBatch: a.partialFlushed,
+		SkippedInputs:    a.skipped,
+		ClampedInputs:    a.clamped,
+		MinInput:         a.minInput,
+		MaxInput:         a.maxInput,
+		Duration:         durationpb.New(endedAt.Sub(a.streamStartedAt)),
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Metadata key through which a client presents its bearer token ("Bearer <token>") to the server.
+const AuthorizationMetadataKey = "authorization"
+
+// Metadata key through which an operator presents an admin token ("Bearer <token>") to the
+//   FewerAdmin service.  It is kept apart from the authorization metadata, so that a client token
+//   never grants admin access, and an admin client can present both.
+const AdminAuthorizationMetadataKey = "fewer-admin-authorization"
+
+// Scheme of the value of the authorization metadata.
+const bearerScheme = "Bearer "
+
+
+
+//*************************************************************************************************
+// Definition of the authenticator guarding one service of the server (the Fewer Service, or the
+//   FewerAdmin service) with bearer tokens, presented in the given metadata key.  While it has no
+//   tokens, every call is let through, unless tokens are required, in which case every call is
+//   denied; otherwise, calls to the service must present one of them.  Other services of the
+//   server (e.g., health checking) are not guarded.  Its tokens can be replaced while the server is
+//   serving.
+type tokenAuthenticator struct {
+	serverLogger ServerLogger
+	service      string
+	metadataKey  string
+	required     bool
+
+	mu     sync.RWMutex
+	tokens []string
+}
+
+// Constructor function for creating a new tokenAuthenticator without tokens, guarding the Fewer
+//   Service with the bearer tokens of its clients.
+func newTokenAuthenticator(serverLogger ServerLogger) *tokenAuthenticator {
+	return &tokenAuthenticator{
+		serverLogger: serverLogger,
+		service:      pb.FewerService_ServiceDesc.ServiceName,
+		metadataKey:  AuthorizationMetadataKey,
+	}
+}
+
+// Constructor function for creating a new tokenAuthenticator without tokens, guarding the
+//   FewerAdmin service with admin tokens.  The service is turned off until it has admin tokens.
+func newAdminTokenAuthenticator(serverLogger ServerLogger) *tokenAuthenticator {
+	return &tokenAuthenticator{
+		serverLogger: serverLogger,
+		service:      pb.FewerAdmin_ServiceDesc.ServiceName,
+		metadataKey:  AdminAuthorizationMetadataKey,
+		required:     true,
+	}
+}
+
+// Method of the tokenAuthenticator that replaces the tokens accepted from clients.  Calls already
+//   authenticated are not affected.
+func (a *tokenAuthenticator) setTokens(tokens []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens = append([]string(nil), tokens...)
+}
+
+// Method of the tokenAuthenticator that checks the bearer token sent in the metadata of a call to
+//   the given method.  Returns an Unauthenticated status error if the call must be rejected, or a
+//   PermissionDenied one if the service is turned off.
+func (a *tokenAuthenticator) authenticate(ctx context.Context, fullMethod string) error {
+	if !strings.HasPrefix(fullMethod, "/"+a.service+"/") {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.tokens) == 0 && a.required {
+		a.serverLogger.ServerLogWarn("rpc", fullMethod, "Denying call, as no admin tokens are configured (auth.admin_tokens)")
+		return statusError(codes.PermissionDenied, pb.ErrorReason_ADMIN_DISABLED, "admin service is turned off, as the server has no admin tokens", nil)
+	}
+	if len(a.tokens) == 0 {
+		return nil
+	}
+
+	value := incomingMetadataValue(ctx, a.metadataKey)
+	token, ok := strings.CutPrefix(value, bearerScheme)
+	if !ok || token == "" {
+		a.serverLogger.ServerLogWarn("rpc", fullMethod, "Rejecting call without a bearer token")
+		return statusError(codes.Unauthenticated, pb.ErrorReason_MISSING_TOKEN, "missing bearer token", nil)
+	}
+	for _, accepted := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(accepted)) == 1 {
+			return nil
+		}
+	}
+	a.serverLogger.ServerLogWarn("rpc", fullMethod, "Rejecting call with an unknown bearer token")
+	return statusError(codes.Unauthenticated, pb.ErrorReason_INVALID_TOKEN, "invalid bearer token", nil)
+}
+
+// Method of the tokenAuthenticator that is the unary interceptor rejecting unauthenticated calls.
+func (a *tokenAuthenticator) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authenticate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Method of the tokenAuthenticator that is the stream interceptor rejecting unauthenticated
+//   streams.
+func (a *tokenAuthenticator) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authenticate(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// Helper function that describes a list of tokens for the logs, without revealing them.
+func describeTokens(tokens []string) string {
+	return fmt.Sprintf("%d tokens", len(tokens))
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain of the ErrorInfo detail carried by every error status of the Fewer Service.
+const ErrorDomain = "fewer.astronomical3.github.com"
+
+// Helper function that returns a status error with the given code and message, carrying an
+//   ErrorInfo detail with the given reason and metadata, followed by the given details.  Clients
+//   can tell errors apart by their reason, rather than by parsing their message.
+func statusError(code codes.Code, reason pb.ErrorReason, message string, metadata map[string]string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, message)
+	details = append([]protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason.String(), Domain: ErrorDomain, Metadata: metadata}}, details...)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// Helper function that returns the QuotaFailure detail of an error over the quota of a subject
+//   (e.g., the server, or one of its clients).
+func quotaFailure(subject, description string) *errdetails.QuotaFailure {
+	return &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: description}},
+	}
+}
+
+// Helper function that returns the BadRequest detail of an error caused by a field of a request.
+func badRequest(field, description string) *errdetails.BadRequest {
+	return &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	}
+}
+
+// Helper function that returns the RetryInfo detail of an error worth retrying after delay.
+func retryInfo(delay time.Duration) *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(delay)}
+}
+
+// Helper function that returns the metadata of an ErrorInfo detail holding the limit that was
+//   reached.
+func limitMetadata(limit int64) map[string]string {
+	return map[string]string{"limit": strconv.FormatInt(limit, 10)}
+}
+
+// Helper function that converts an error received from, or sent to, the client of a stream into a
+//   status error with details, keeping its code and message.  A request rejected by gRPC for being
+//   larger than the maximum message size is reported as such.  The end of the stream (io.EOF), and
+//   errors that already carry details, are returned as is.
+func streamError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	st := status.Convert(err)
+	if len(st.Details()) > 0 {
+		return err
+	}
+	reason := pb.ErrorReason_CALL_INTERRUPTED
+	if st.Code() == codes.ResourceExhausted {
+		reason = pb.ErrorReason_MESSAGE_TOO_LARGE
+	}
+	return statusError(st.Code(), reason, st.Message(), nil)
+}
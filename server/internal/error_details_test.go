@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Helper function that returns the ErrorInfo detail of a status error, failing the test if it does
+//   not have one in the Fewer Service domain.
+func errorInfoOf(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return info
+		}
+	}
+	t.Fatalf("error %v has no ErrorInfo detail in domain %s", err, ErrorDomain)
+	return nil
+}
+
+// Helper function that returns the detail of type T of a status error, or nil if it has none.
+func detailOf[T any](err error) T {
+	var zero T
+	for _, detail := range status.Convert(err).Details() {
+		if d, ok := detail.(T); ok {
+			return d
+		}
+	}
+	return zero
+}
+
+// Test that the errors of the Fewer Service carry an ErrorInfo detail with their reason, along with
+//   the QuotaFailure, RetryInfo or BadRequest details that apply to them.
+func TestErrorDetails(t *testing.T) {
+	fs, client := newBufconnGeneralFewerServer(t, NewRecordingServerLogger(), loadTestServerConfig(t, map[string]string{
+		"FEWER_RATE_LIMITS_INPUTS_PER_SECOND": "2",
+		"FEWER_RATE_LIMITS_MODE":              "reject",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	// Without an aggregate store, aggregates cannot be queried.
+	query := func(pageToken string) error {
+		stream, err := client.QueryAggregates(context.Background(), &pb.QueryAggregatesRequest{PageToken: pageToken})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+	err := query("")
+	if info := errorInfoOf(t, err); status.Code(err) != codes.FailedPrecondition || info.Reason != "AGGREGATES_NOT_PERSISTED" {
+		t.Errorf("query without store error = %v (reason %s), want FailedPrecondition AGGREGATES_NOT_PERSISTED", err, info.Reason)
+	}
+
+	// An invalid page token is reported as a bad request field.
+	sink, err := NewJSONLinesAggregateSink(filepath.Join(t.TempDir(), "aggregates.jsonl"))
+	if err != nil {
+		t.Fatalf("NewJSONLinesAggregateSink() error = %v", err)
+	}
+	defer sink.Close()
+	fs.SetAggregateSink(sink)
+	err = query("not-a-token")
+	if info := errorInfoOf(t, err); status.Code(err) != codes.InvalidArgument || info.Reason != "INVALID_PAGE_TOKEN" {
+		t.Errorf("query with invalid page token error = %v (reason %s), want InvalidArgument INVALID_PAGE_TOKEN", err, info.Reason)
+	}
+	if badRequest := detailOf[*errdetails.BadRequest](err); len(badRequest.GetFieldViolations()) != 1 || badRequest.FieldViolations[0].Field != "page_token" {
+		t.Errorf("query with invalid page token BadRequest detail = %v, want a violation of page_token", badRequest)
+	}
+
+	// Inputs over the rate limit are rejected with the quota they went over, and how long to wait
+	//   before sending them again.
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}}); err != nil {
+		t.Fatalf("AggregateBatch() within the rate limit error = %v", err)
+	}
+	_, err = client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}})
+	info := errorInfoOf(t, err)
+	if status.Code(err) != codes.ResourceExhausted || info.Reason != "RATE_LIMITED" || info.Metadata["limit"] != "2" {
+		t.Errorf("rate limited AggregateBatch() error = %v (ErrorInfo %v), want ResourceExhausted RATE_LIMITED with limit 2", err, info)
+	}
+	if quota := detailOf[*errdetails.QuotaFailure](err); len(quota.GetViolations()) != 1 || quota.Violations[0].Description != "rate_limits.inputs_per_second" {
+		t.Errorf("rate limited AggregateBatch() QuotaFailure detail = %v, want a violation of rate_limits.inputs_per_second", quota)
+	}
+	if retry := detailOf[*errdetails.RetryInfo](err); retry.GetRetryDelay().AsDuration() <= 0 {
+		t.Errorf("rate limited AggregateBatch() RetryInfo detail = %v, want a positive retry delay", retry)
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 
 	pb "github.com/astronomical3/fewer_grpc/fewer"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -16,20 +22,37 @@ import (
 // Definition of the general gRPC server that will host the Fewer Service.
 type GeneralFewerServer struct {
 	// Added upon creation of the server
-	listener     net.Listener
+	listeners    []net.Listener
 
 	// Received later on in the setup.
 	grpcServer   *grpc.Server
 	serverLogger ServerLogger
 	srv          *FewerService
+	sink         AggregateSink
+	// gRPC health checking service, if it is registered.
+	health       *health.Server
+	// Authenticators of the calls made to the Fewer Service, and to the FewerAdmin service.
+	auth         *tokenAuthenticator
+	adminAuth    *tokenAuthenticator
+	// Recoverer turning panics of call handlers into failed calls.
+	recoverer    *panicRecoverer
+	// Notifier reporting the state of the server to systemd, if it was started by systemd.
+	notifier     *SystemdNotifier
+	// Channel closed once the server is asked to stop by a Drain() call of the FewerAdmin service.
+	stopOnce     sync.Once
+	stopRequest  chan struct{}
+
+	// Configuration the server currently runs with, and the function that loads it anew when the
+	//   server is asked to reload it.  Both are guarded by configMu.
+	configMu     sync.Mutex
+	config       ServerConfig
+	configLoader func() (ServerConfig, error)
 }
 
 // Create a new general gRPC server, and create a new server logging object depending on whether the server 
-//   will be production or development/test.
-func NewGeneralFewerServer(serverLogFilename string, lis net.Listener, isProd bool) *GeneralFewerServer {
-	// Obtain a new general gRPC server
-	grpcServer := grpc.NewServer()
-
+//   will be production or development/test.  The options add to the interceptors, stats handlers and
+//   options of the gRPC server.
+func NewGeneralFewerServer(serverLogFilename string, lis net.Listener, isProd bool, options ...Option) *GeneralFewerServer {
 	// Obtain a new server logging object depending on whether the server will be production- or 
 	//   development/test-grade.
 	var serverLogger ServerLogger
@@ -39,38 +62,334 @@ func NewGeneralFewerServer(serverLogFilename string, lis net.Listener, isProd bo
 		serverLogger = NewServerLoggingObjectDEV(serverLogFilename)
 	}
 
+	return NewGeneralFewerServerWithLogger(serverLogger, lis, options...)
+}
+
+// Create a new general gRPC server that logs to the given server logging object, such as an in-memory
+//   logger used by tests.  The Fewer Service, the FewerAdmin service and the gRPC reflection service
+//   are registered to it right away.
+func NewGeneralFewerServerWithLogger(serverLogger ServerLogger, lis net.Listener, options ...Option) *GeneralFewerServer {
+	return newGeneralFewerServer(serverLogger, []net.Listener{lis}, ObservabilityConfig{Reflection: true}, options...)
+}
+
+// Create a new general gRPC server from the settings of a server configuration, serving on every
+//   one of the given listeners (e.g., those opened by the configuration's Listen() method) at once,
+//   and logging to the given server logging object.  The options come on top of the settings of
+//   the configuration.  The aggregate sink of the configuration is not opened; see
+//   SetAggregateSink().
+func NewGeneralFewerServerWithConfig(serverLogger ServerLogger, listeners []net.Listener, config ServerConfig, options ...Option) (*GeneralFewerServer, error) {
+	opts, err := config.ServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	options = append([]Option{WithServerOptions(opts...)}, options...)
+	fs := newGeneralFewerServer(serverLogger, listeners, config.Observability, options...)
+	fs.SetBatchSize(config.Aggregation.BatchSize)
+	fs.SetAckInterval(config.Aggregation.AckInterval)
+	fs.SetAllowedReducers(config.Aggregation.AllowedReducers)
+	fs.SetStreamLimits(config.StreamLimits())
+	fs.SetRateLimits(config.RateLimits)
+	fs.auth.setTokens(config.Auth.Tokens)
+	fs.adminAuth.setTokens(config.Auth.AdminTokens)
+	fs.config = config
+	return fs, nil
+}
+
+// Internal constructor function shared by the constructors of the GeneralFewerServer, which
+//   registers the Fewer Service, the FewerAdmin service, and the observability services turned on,
+//   to a new gRPC server created with the built-in options followed by the given ones.
+func newGeneralFewerServer(serverLogger ServerLogger, listeners []net.Listener, observability ObservabilityConfig, options ...Option) *GeneralFewerServer {
+	// Obtain a new general gRPC server, whose calls are guarded against panics of their handlers and
+	//   authenticated first, and whose client connections are logged as they open and close.
+	recoverer := newPanicRecoverer(serverLogger)
+	auth := newTokenAuthenticator(serverLogger)
+	adminAuth := newAdminTokenAuthenticator(serverLogger)
+	options = append([]Option{
+		WithUnaryInterceptors(recoverer.unaryInterceptor, auth.unaryInterceptor, adminAuth.unaryInterceptor),
+		WithStreamInterceptors(recoverer.streamInterceptor, auth.streamInterceptor, adminAuth.streamInterceptor),
+		WithStatsHandlers(connectionLogger{serverLogger: serverLogger}),
+		WithServerOptions(grpc.InTapHandle(cancellableCallContext)),
+	}, options...)
+	grpcServer := grpc.NewServer(buildServerOptions(options)...)
+
 	// Create a new instance of the Fewer Service.
 	srv := NewFewerService(serverLogger)
 
-	return &GeneralFewerServer{
-		listener:     lis,
+	fs := &GeneralFewerServer{
+		listeners:    listeners,
 		grpcServer:   grpcServer,
 		serverLogger: serverLogger,
 		srv:          srv,
+		auth:         auth,
+		adminAuth:    adminAuth,
+		recoverer:    recoverer,
+		stopRequest:  make(chan struct{}),
+		config:       DefaultServerConfig(),
+	}
+
+	// Register the Fewer Service and FewerAdmin service instances, and the instances of the gRPC
+	//   reflection and health checking services that are turned on, to the gRPC server.
+	pb.RegisterFewerServiceServer(grpcServer, srv)
+	pb.RegisterFewerAdminServer(grpcServer, newFewerAdminService(fs))
+	if observability.Reflection {
+		reflection.Register(grpcServer)
+	}
+	if observability.Health {
+		fs.health = health.NewServer()
+		healthpb.RegisterHealthServer(grpcServer, fs.health)
+	}
+	return fs
+}
+
+// Method of the GeneralFewerServer for changing how many inputs its Fewer Service adds together
+//   into each aggregate.
+func (fs *GeneralFewerServer) SetBatchSize(batchSize int) {
+	fs.srv.SetBatchSize(batchSize)
+}
+
+// Method of the GeneralFewerServer for changing which reducers new aggregation streams of the
+//   Fewer Service may ask for.
+func (fs *GeneralFewerServer) SetAllowedReducers(reducers []string) {
+	fs.srv.SetAllowedReducers(reducers)
+}
+
+// Method of the GeneralFewerServer for changing how many inputs its Fewer Service processes
+//   between two acknowledgements sent back to a client.
+func (fs *GeneralFewerServer) SetAckInterval(ackInterval int) {
+	fs.srv.SetAckInterval(ackInterval)
+}
+
+// Method of the GeneralFewerServer for setting the limits its Fewer Service enforces on
+//   aggregation streams.
+func (fs *GeneralFewerServer) SetStreamLimits(limits StreamLimits) {
+	fs.srv.SetStreamLimits(limits)
+}
+
+// Method of the GeneralFewerServer for setting the rate limits its Fewer Service enforces on the
+//   inputs of each client.
+func (fs *GeneralFewerServer) SetRateLimits(config RateLimitConfig) {
+	fs.srv.SetRateLimits(config)
+}
+
+// Method of the GeneralFewerServer for setting the sink that its Fewer Service records every
+//   emitted aggregate to.  The sink is closed along with the server on shutdown.
+func (fs *GeneralFewerServer) SetAggregateSink(sink AggregateSink) {
+	fs.sink = sink
+	fs.srv.SetAggregateSink(sink)
+}
+
+// Method of the GeneralFewerServer for setting the notifier through which it reports to systemd that
+//   it is ready, alive (watchdog) and stopping.  A nil notifier reports nothing.
+func (fs *GeneralFewerServer) SetSystemdNotifier(notifier *SystemdNotifier) {
+	fs.notifier = notifier
+}
+
+// Method of the GeneralFewerServer for setting the function that loads its configuration anew (e.g.,
+//   from the configuration file, environment variables and flags) whenever it is asked to reload it.
+func (fs *GeneralFewerServer) SetConfigLoader(loader func() (ServerConfig, error)) {
+	fs.configMu.Lock()
+	defer fs.configMu.Unlock()
+	fs.configLoader = loader
+}
+
+// Method of the GeneralFewerServer that loads its configuration anew and applies it, as on SIGHUP.
+//   If the configuration cannot be loaded or is invalid, it is rejected and the current one kept.
+func (fs *GeneralFewerServer) ReloadConfig() error {
+	fs.configMu.Lock()
+	loader := fs.configLoader
+	fs.configMu.Unlock()
+	if loader == nil {
+		fs.serverLogger.ServerLogWarn("method", "GeneralFewerServer_ReloadConfig", "No configuration to reload, keeping the current one")
+		return fmt.Errorf("no server configuration loader is set")
+	}
+	config, err := loader()
+	if err != nil {
+		fs.serverLogger.ServerLogError(
+			"method",
+			"GeneralFewerServer_ReloadConfig",
+			fmt.Sprintf("Rejected reloaded configuration, keeping the current one: %v", err),
+		)
+		return err
 	}
+	return fs.ApplyConfig(config)
 }
 
-// Method of the GeneralFewerServer that is used for registering its new Fewer Service instance to its general
-//   gRPC server, registering a gRPC reflection service (for providing RPC and other useful information about
-//   the Fewer Service to tools such as grpcurl), and then setting up a channel to listen to OS termination or
+// Method of the GeneralFewerServer that applies the settings of a new configuration that can be
+//   changed without dropping open streams: the log level, the batch size, acknowledgement
+//   interval and allowed reducers of new streams, the auth and admin tokens, and the rate limits.
+//   Each change is logged with its old and new values.  Changes to any other setting are logged
+//   and ignored, as they only take effect once the server is restarted.  An invalid configuration
+//   is rejected and the current one kept.
+func (fs *GeneralFewerServer) ApplyConfig(config ServerConfig) error {
+	if err := config.Validate(); err != nil {
+		fs.serverLogger.ServerLogError(
+			"method",
+			"GeneralFewerServer_ReloadConfig",
+			fmt.Sprintf("Rejected reloaded configuration, keeping the current one: %v", err),
+		)
+		return err
+	}
+	fs.configMu.Lock()
+	defer fs.configMu.Unlock()
+	old := fs.config
+
+	logChange := func(setting string, oldValue, newValue any) {
+		fs.serverLogger.ServerLogInfo(
+			"method",
+			"GeneralFewerServer_ReloadConfig",
+			fmt.Sprintf("Changed %s from %v to %v", setting, oldValue, newValue),
+		)
+	}
+	if config.Logging.Level != old.Logging.Level {
+		if leveled, ok := fs.serverLogger.(interface{ SetLogLevel(string) error }); ok {
+			if err := leveled.SetLogLevel(config.Logging.Level); err != nil {
+				fs.serverLogger.ServerLogError(
+					"method",
+					"GeneralFewerServer_ReloadConfig",
+					fmt.Sprintf("Could not change logging.level to %s, keeping %s: %v", config.Logging.Level, old.Logging.Level, err),
+				)
+				config.Logging.Level = old.Logging.Level
+			} else {
+				logChange("logging.level", old.Logging.Level, config.Logging.Level)
+			}
+		} else {
+			fs.serverLogger.ServerLogWarn("method", "GeneralFewerServer_ReloadConfig", "Ignoring change of logging.level, as the server logger has no level")
+			config.Logging.Level = old.Logging.Level
+		}
+	}
+	if config.Aggregation.BatchSize != old.Aggregation.BatchSize {
+		logChange("aggregation.batch_size", old.Aggregation.BatchSize, config.Aggregation.BatchSize)
+		fs.SetBatchSize(config.Aggregation.BatchSize)
+	}
+	if config.Aggregation.AckInterval != old.Aggregation.AckInterval {
+		logChange("aggregation.ack_interval", old.Aggregation.AckInterval, config.Aggregation.AckInterval)
+		fs.SetAckInterval(config.Aggregation.AckInterval)
+	}
+	if !slices.Equal(config.Aggregation.AllowedReducers, old.Aggregation.AllowedReducers) {
+		logChange("aggregation.allowed_reducers", old.Aggregation.AllowedReducers, config.Aggregation.AllowedReducers)
+		fs.SetAllowedReducers(config.Aggregation.AllowedReducers)
+	}
+	if !slices.Equal(config.Auth.Tokens, old.Auth.Tokens) {
+		logChange("auth.tokens", describeTokens(old.Auth.Tokens), describeTokens(config.Auth.Tokens))
+		fs.auth.setTokens(config.Auth.Tokens)
+	}
+	if !slices.Equal(config.Auth.AdminTokens, old.Auth.AdminTokens) {
+		logChange("auth.admin_tokens", describeTokens(old.Auth.AdminTokens), describeTokens(config.Auth.AdminTokens))
+		fs.adminAuth.setTokens(config.Auth.AdminTokens)
+	}
+	if config.RateLimits != old.RateLimits {
+		for _, change := range []struct {
+			setting            string
+			oldValue, newValue any
+		}{
+			{"rate_limits.inputs_per_second", old.RateLimits.InputsPerSecond, config.RateLimits.InputsPerSecond},
+			{"rate_limits.burst", old.RateLimits.Burst, config.RateLimits.Burst},
+			{"rate_limits.mode", old.RateLimits.Mode, config.RateLimits.Mode},
+			{"rate_limits.key", old.RateLimits.Key, config.RateLimits.Key},
+		} {
+			if change.oldValue != change.newValue {
+				logChange(change.setting, change.oldValue, change.newValue)
+			}
+		}
+		fs.SetRateLimits(config.RateLimits)
+	}
+
+	// Keep the settings that cannot change while serving as they are.
+	applied := old
+	applied.Logging.Level = config.Logging.Level
+	applied.Aggregation.BatchSize = config.Aggregation.BatchSize
+	applied.Aggregation.AckInterval = config.Aggregation.AckInterval
+	applied.Aggregation.AllowedReducers = config.Aggregation.AllowedReducers
+	applied.Auth = config.Auth
+	applied.RateLimits = config.RateLimits
+	if ignored := changedSections(applied, config); len(ignored) > 0 {
+		fs.serverLogger.ServerLogWarn(
+			"method",
+			"GeneralFewerServer_ReloadConfig",
+			fmt.Sprintf("Ignoring changes to %s, which only take effect once the server is restarted", strings.Join(ignored, ", ")),
+		)
+	}
+	fs.config = applied
+	return nil
+}
+
+// Method of the GeneralFewerServer that returns the number of panics of call handlers it has
+//   recovered from, each of which failed its call with an Internal status error.
+func (fs *GeneralFewerServer) RecoveredPanics() int64 {
+	return fs.recoverer.recoveredPanics()
+}
+
+// Method of the GeneralFewerServer that starts draining it, as asked by a Drain() call of the
+//   FewerAdmin service: new aggregation calls are rejected, and the Fewer Service is reported as not
+//   serving to health checking clients, while open streams run to their end.  If shutdown is set,
+//   the server is asked to stop once all of them have ended (see StopRequested()).  Returns the
+//   number of aggregation streams still open.
+func (fs *GeneralFewerServer) Drain(shutdown bool) int {
+	open, drained := fs.srv.limiter.drain()
+	fs.serverLogger.ServerLogInfo(
+		"method",
+		"GeneralFewerServer_Drain",
+		fmt.Sprintf("Draining server, with %d aggregation streams still open (shutdown once drained: %t)", open, shutdown),
+	)
+	if fs.health != nil {
+		fs.health.SetServingStatus(pb.FewerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	if shutdown {
+		go func() {
+			<-drained
+			fs.serverLogger.ServerLogInfo("method", "GeneralFewerServer_Drain", "All aggregation streams ended, asking the server to stop...")
+			fs.stopOnce.Do(func() { close(fs.stopRequest) })
+		}()
+	}
+	return open
+}
+
+// Method of the GeneralFewerServer that returns a channel closed once the server is asked to stop
+//   by a Drain() call.  ListenAndServe() then shuts the server down; in-process servers calling
+//   Serve() themselves must call Shutdown() once it is closed.
+func (fs *GeneralFewerServer) StopRequested() <-chan struct{} {
+	return fs.stopRequest
+}
+
+// Method of the GeneralFewerServer that returns the configuration it currently runs with.
+func (fs *GeneralFewerServer) Config() ServerConfig {
+	fs.configMu.Lock()
+	defer fs.configMu.Unlock()
+	return fs.config
+}
+
+// Method of the GeneralFewerServer that serves Fewer Service clients on all of its listeners at
+//   once, blocking until the server is stopped or one of its listeners fails.  Used directly by
+//   in-process servers, such as test harnesses, that handle shutdowns themselves.
+func (fs *GeneralFewerServer) Serve() error {
+	served := make(chan error, len(fs.listeners))
+	for _, lis := range fs.listeners {
+		fs.serverLogger.ServerLogInfo(
+			"method",
+			"GeneralFewerServer_Serve",
+			fmt.Sprintf("FewerServer listening on address %v (%s)", lis.Addr(), lis.Addr().Network()),
+		)
+		go func() { served <- fs.grpcServer.Serve(lis) }()
+	}
+	for range fs.listeners {
+		if err := <-served; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method of the GeneralFewerServer that is used for setting up a channel to listen to OS termination or
 //   interruption signals and a goroutine for serving Fewer Service clients.
 func (fs *GeneralFewerServer) ListenAndServe() {
-	// Register the Fewer Service instance and an instance of the gRPC reflection service to the gRPC server.
-	pb.RegisterFewerServiceServer(fs.grpcServer, fs.srv)
-	reflection.Register(fs.grpcServer)
-
-	// Create a channel, sigChan, that will listen to an OS termination or interruption signal
+	// Create a channel, sigChan, that will listen to an OS termination or interruption signal, or to
+	//   a hangup signal asking to reload the configuration.
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	// Have the server listen to all FewerService-specific requests.
 	go func() {
-		fs.serverLogger.ServerLogInfo(
-			"method",
-			"GeneralFewerServer_ListenAndServe",
-			fmt.Sprintf("FewerServer listening on address %v", fs.listener.Addr()),
-		)
-		if err := fs.grpcServer.Serve(fs.listener); err != nil {
+		if err := fs.Serve(); err != nil {
 			fs.serverLogger.ServerLogError(
 				"method",
 				"GeneralFewerServer_ListenAndServe",
@@ -81,30 +400,111 @@ func (fs *GeneralFewerServer) ListenAndServe() {
 				"GeneralFewerServer_ListenAndServe",
 				"Closing server log, returning exit code 1...",
 			)
+			fs.closeSink()
 			fs.serverLogger.Close()
-			fs.listener.Close()
+			for _, lis := range fs.listeners {
+				lis.Close()
+			}
 			os.Exit(1)
 		}
 	}()
 
-	// Block until an interruption/termination signal is received.
-	sig := <-sigChan
-	fs.serverLogger.ServerLogInfo(
-		"method",
-		"GeneralFewerServer_ListenAndServe",
-		fmt.Sprintf("Received signal (%s), starting graceful shutdown...", sig.String()),
-	)
-	fs.shutdown()
+	// Tell systemd that the server is ready to serve clients, and keep its watchdog from firing.
+	fs.notifySystemd(SystemdReady)
+	fs.notifier.StartWatchdog(func(err error) {
+		fs.serverLogger.ServerLogWarn("method", "GeneralFewerServer_ListenAndServe", fmt.Sprintf("Failed to notify systemd watchdog: %v", err))
+	})
+
+	// Reload the configuration on every hangup signal, and block until an interruption/termination
+	//   signal is received, or until a drained server is asked to stop.
+waitForStop:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				fs.serverLogger.ServerLogInfo(
+					"method",
+					"GeneralFewerServer_ListenAndServe",
+					fmt.Sprintf("Received signal (%s), reloading configuration...", sig.String()),
+				)
+				fs.ReloadConfig()
+				continue
+			}
+			fs.serverLogger.ServerLogInfo(
+				"method",
+				"GeneralFewerServer_ListenAndServe",
+				fmt.Sprintf("Received signal (%s), starting graceful shutdown...", sig.String()),
+			)
+		case <-fs.stopRequest:
+			fs.serverLogger.ServerLogInfo(
+				"method",
+				"GeneralFewerServer_ListenAndServe",
+				"Server drained and asked to stop, starting graceful shutdown...",
+			)
+		}
+		break waitForStop
+	}
+	fs.Shutdown()
 }
 
-// Internal method of the GeneralFewerServer for ensuring graceful stop of
-//  gRPC server when an OS termination/interruption signal is issued.
-func (fs *GeneralFewerServer) shutdown() {
+// Method of the GeneralFewerServer for ensuring graceful stop of the gRPC server, when an OS
+//   termination/interruption signal is issued or an in-process server is no longer needed.
+func (fs *GeneralFewerServer) Shutdown() {
+	// Tell systemd that the server is stopping, so that it does not wait for it to be ready again.
+	fs.notifySystemd(SystemdStopping)
+	fs.notifier.StopWatchdog()
+	// Report every service as not serving anymore to health checking clients.
+	if fs.health != nil {
+		fs.health.Shutdown()
+	}
+	fs.srv.CloseSubscriptions()
 	fs.grpcServer.GracefulStop()
 	fs.serverLogger.ServerLogInfo(
 		"method",
 		"GeneralFewerServer_Shutdown",
 		"gRPC server gracefully stopped.",
 	)
+	fs.closeSink()
 	fs.serverLogger.Close()
-}
\ No newline at end of file
+}
+
+// Internal method of the GeneralFewerServer for reporting a state to systemd, if it was started by
+//   systemd.  Failing to do so is logged, but does not stop the server.
+func (fs *GeneralFewerServer) notifySystemd(state string) {
+	if fs.notifier == nil {
+		return
+	}
+	if err := fs.notifier.Notify(state); err != nil {
+		fs.serverLogger.ServerLogWarn("method", "GeneralFewerServer_notifySystemd", fmt.Sprintf("Failed to notify systemd of %s: %v", state, err))
+		return
+	}
+	fs.serverLogger.ServerLogDebug("method", "GeneralFewerServer_notifySystemd", fmt.Sprintf("Notified systemd of %s", state))
+}
+
+// Internal method of the GeneralFewerServer for closing its aggregate sink, if it has one.
+func (fs *GeneralFewerServer) closeSink() {
+	if fs.sink == nil {
+		return
+	}
+	if err := fs.sink.Close(); err != nil {
+		fs.serverLogger.ServerLogError(
+			"method",
+			"GeneralFewerServer_Shutdown",
+			fmt.Sprintf("Failed to close aggregate sink: %v", err),
+		)
+	}
+}
+
+// Helper function that returns the names of the top-level sections (e.g., "listeners" or "tls")
+//   whose settings differ between two configurations.
+func changedSections(a, b ServerConfig) []string {
+	var sections []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
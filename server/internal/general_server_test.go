@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Helper function that creates a GeneralFewerServer from the given configuration over an in-memory
+//   bufconn listener, and returns it along with a client stub connected to it.  The server is not
+//   serving yet.
+func newBufconnGeneralFewerServer(t *testing.T, serverLogger ServerLogger, config ServerConfig, options ...Option) (*GeneralFewerServer, pb.FewerServiceClient) {
+	t.Helper()
+	fs, conn := newBufconnGeneralFewerServerConn(t, serverLogger, config, options...)
+	return fs, pb.NewFewerServiceClient(conn)
+}
+
+// Helper function that creates a GeneralFewerServer like newBufconnGeneralFewerServer(), but returns
+//   the client connection to it, for stubs of its other services (e.g., FewerAdmin).
+func newBufconnGeneralFewerServerConn(t *testing.T, serverLogger ServerLogger, config ServerConfig, options ...Option) (*GeneralFewerServer, *grpc.ClientConn) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	fs, err := NewGeneralFewerServerWithConfig(serverLogger, []net.Listener{lis}, config, options...)
+	if err != nil {
+		t.Fatalf("NewGeneralFewerServerWithConfig() error = %v", err)
+	}
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return fs, conn
+}
+
+// Helper function that loads a server configuration from the given environment variables only.
+func loadTestServerConfig(t *testing.T, env map[string]string) ServerConfig {
+	t.Helper()
+	config, err := LoadServerConfig("", fakeEnv(env), nil)
+	if err != nil {
+		t.Fatalf("LoadServerConfig() error = %v", err)
+	}
+	return config
+}
+
+// Test of applying a new configuration to a serving server: the settings that can change are
+//   applied and logged with their old and new values, the others are reported as ignored, and an
+//   invalid configuration is rejected.
+func TestApplyConfig(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	reloaded := loadTestServerConfig(t, map[string]string{
+		"FEWER_AGGREGATION_BATCH_SIZE":       "2",
+		"FEWER_AGGREGATION_ACK_INTERVAL":     "4",
+		"FEWER_AGGREGATION_ALLOWED_REDUCERS": "sum,max",
+		"FEWER_AUTH_TOKENS":                  "s3cret",
+		"FEWER_LISTENERS":                    "localhost:6000",
+	})
+	if err := fs.ApplyConfig(reloaded); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	serverLogger.AssertLogged(t, "info", "Changed aggregation.batch_size from 3 to 2")
+	serverLogger.AssertLogged(t, "info", "Changed aggregation.ack_interval from 8 to 4")
+	serverLogger.AssertLogged(t, "info", "Changed aggregation.allowed_reducers from [sum min max] to [sum max]")
+	serverLogger.AssertLogged(t, "info", "Changed auth.tokens from 0 tokens to 1 tokens")
+	serverLogger.AssertNotLogged(t, "", "s3cret")
+	serverLogger.AssertLogged(t, "warn", "Ignoring changes to listeners, which only take effect once the server is restarted")
+	if got := fs.Config(); got.Aggregation.BatchSize != 2 || got.Listeners[0] != DefaultServerListener {
+		t.Errorf("Config() = %+v, want the new batch size and the old listeners", got)
+	}
+
+	// New calls must present the new token, and are aggregated in batches of the new size.
+	_, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("AggregateBatch() without token error = %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), AuthorizationMetadataKey, "Bearer s3cret")
+	resp, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatalf("AggregateBatch() with token error = %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Errorf("AggregateBatch() results = %v, want 2 batches of 2 inputs", resp.Results)
+	}
+	_, err = client.AggregateBatch(metadata.AppendToOutgoingContext(ctx, ReducerMetadataKey, MinReducer), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.InvalidArgument || info.Reason != "REDUCER_NOT_ALLOWED" || info.Metadata["allowed_reducers"] != "sum,max" {
+		t.Errorf("AggregateBatch() with a reducer no longer allowed error = %v (ErrorInfo %v), want InvalidArgument REDUCER_NOT_ALLOWED", err, info)
+	}
+
+	// An invalid configuration leaves the current one in place.
+	invalid := reloaded
+	invalid.Aggregation.BatchSize = 0
+	if err := fs.ApplyConfig(invalid); err == nil {
+		t.Errorf("ApplyConfig() of invalid configuration succeeded, want an error")
+	}
+	serverLogger.AssertLogged(t, "error", "Rejected reloaded configuration, keeping the current one: aggregation.batch_size must be at least 1")
+	if got := fs.Config().Aggregation.BatchSize; got != 2 {
+		t.Errorf("batch size after rejected configuration = %d, want 2", got)
+	}
+}
+
+// Test of changing the level of the server logger through a new configuration.
+func TestApplyConfigLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	serverLogger, err := NewServerLoggingObject(logging.Config{Level: "info", Writers: []io.Writer{&buf}})
+	if err != nil {
+		t.Fatalf("NewServerLoggingObject() error = %v", err)
+	}
+	fs, _ := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+
+	serverLogger.ServerLogDebug("rpc", "test", "dropped before reload")
+	if err := fs.ApplyConfig(loadTestServerConfig(t, map[string]string{"FEWER_LOGGING_LEVEL": "debug"})); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	serverLogger.ServerLogDebug("rpc", "test", "kept after reload")
+	got := buf.String()
+	if strings.Contains(got, "dropped before reload") || !strings.Contains(got, "kept after reload") || !strings.Contains(got, "Changed logging.level from info to debug") {
+		t.Errorf("logged %q, want debug entries only after the level changed", got)
+	}
+}
+
+// Test that a new configuration whose logging level the server logger fails to take keeps the
+//   current level, and logs why.
+func TestApplyConfigLogLevelError(t *testing.T) {
+	serverLogger := &fixedLevelServerLogger{RecordingServerLogger: NewRecordingServerLogger()}
+	fs, _ := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	oldLevel := fs.Config().Logging.Level
+
+	if err := fs.ApplyConfig(loadTestServerConfig(t, map[string]string{"FEWER_LOGGING_LEVEL": "debug", "FEWER_AGGREGATION_BATCH_SIZE": "5"})); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	serverLogger.AssertLogged(t, "error", fmt.Sprintf("Could not change logging.level to debug, keeping %s: level is fixed", oldLevel))
+	if got := fs.Config().Logging.Level; got != oldLevel {
+		t.Errorf("logging level after failed change = %q, want %q", got, oldLevel)
+	}
+	if got := fs.Config().Aggregation.BatchSize; got != 5 {
+		t.Errorf("batch size after failed logging level change = %d, want 5", got)
+	}
+}
+
+// Definition of a server logger whose level cannot be changed.
+type fixedLevelServerLogger struct {
+	*RecordingServerLogger
+}
+
+func (l *fixedLevelServerLogger) SetLogLevel(string) error {
+	return errors.New("level is fixed")
+}
+
+// Helper function that returns the path of a socket file in a new temporary directory.  The
+//   directory is kept short, as socket paths are limited to about 100 bytes.
+func tempSocketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "fewer")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "fewer.sock")
+}
+
+// Test of serving on TCP listeners (IPv4, and IPv6 if the host has it) and a Unix domain socket
+//   listener at once, with the configured permissions on the socket file.
+func TestServeMultipleListeners(t *testing.T) {
+	socketPath := tempSocketPath(t)
+	listeners := []string{"tcp4://127.0.0.1:0", "unix://" + socketPath}
+	if lis, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		lis.Close()
+		listeners = append(listeners, "tcp6://[::1]:0")
+	}
+
+	// A socket file left behind by a previous server is replaced.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	config := loadTestServerConfig(t, map[string]string{
+		"FEWER_LISTENERS":        strings.Join(listeners, ","),
+		"FEWER_UNIX_SOCKET_MODE": "0600",
+	})
+	opened, err := config.Listen()
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if info, err := os.Stat(socketPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket file %s: %v, %v, want permissions 0600", socketPath, info.Mode(), err)
+	}
+	serverLogger := NewRecordingServerLogger()
+	fs, err := NewGeneralFewerServerWithConfig(serverLogger, opened, config)
+	if err != nil {
+		t.Fatalf("NewGeneralFewerServerWithConfig() error = %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- fs.Serve() }()
+
+	for _, lis := range opened {
+		target := lis.Addr().String()
+		if lis.Addr().Network() == "unix" {
+			target = "unix://" + target
+		}
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("grpc.NewClient(%s): %v", target, err)
+		}
+		resp, err := pb.NewFewerServiceClient(conn).AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3}})
+		if err != nil || len(resp.Results) != 1 || resp.Results[0].Result != 6 {
+			t.Errorf("AggregateBatch() through %s = %v, %v, want one sum of 6", target, resp, err)
+		}
+		conn.Close()
+		serverLogger.AssertLogged(t, "info", fmt.Sprintf("FewerServer listening on address %s (%s)", lis.Addr(), lis.Addr().Network()))
+	}
+
+	fs.Shutdown()
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket file %s still exists after shutdown: %v", socketPath, err)
+	}
+}
+
+// Test that a Unix domain socket listener does not replace a file that is not a socket.
+func TestListenUnixSocketOverRegularFile(t *testing.T) {
+	socketPath := tempSocketPath(t)
+	if err := os.WriteFile(socketPath, []byte("data"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	config := loadTestServerConfig(t, map[string]string{"FEWER_LISTENERS": "tcp4://127.0.0.1:0,unix://" + socketPath})
+	if _, err := config.Listen(); err == nil || !strings.Contains(err.Error(), "is not a socket") {
+		t.Errorf("Listen() error = %v, want the regular file to be kept", err)
+	}
+	if content, _ := os.ReadFile(socketPath); string(content) != "data" {
+		t.Errorf("regular file holds %q after Listen(), want it untouched", content)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package internal
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test that SIGHUP makes ListenAndServe() reload the configuration, keeping the current one if the
+//   reloaded one fails to load, and that SIGINT still shuts the server down.
+func TestListenAndServeReloadsOnSIGHUP(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, _ := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	loads := 0
+	fs.SetConfigLoader(func() (ServerConfig, error) {
+		loads++
+		if loads == 2 {
+			return ServerConfig{}, errors.New("could not parse server config file")
+		}
+		return LoadServerConfig("", fakeEnv(map[string]string{"FEWER_AGGREGATION_BATCH_SIZE": "5"}), nil)
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fs.ListenAndServe()
+	}()
+	serverLogger.WaitForLogged(t, "info", "FewerServer listening", time.Second)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	serverLogger.WaitForLogged(t, "info", "Changed aggregation.batch_size from 3 to 5", time.Second)
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	serverLogger.WaitForLogged(t, "error", "Rejected reloaded configuration, keeping the current one", time.Second)
+	if got := fs.Config().Aggregation.BatchSize; got != 5 {
+		t.Errorf("batch size after rejected reload = %d, want 5", got)
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ListenAndServe() did not return after SIGINT")
+	}
+	serverLogger.AssertLogged(t, "info", "gRPC server gracefully stopped.")
+}
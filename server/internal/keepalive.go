@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/stats"
+)
+
+// Default time after which the server pings a quiet client connection, and default time it waits
+//   for the ping to be answered.  Pinging every minute keeps connections through NATs and load
+//   balancers from being dropped silently, and finds out about dead ones.
+const DefaultKeepaliveTime = time.Minute
+const DefaultKeepaliveTimeout = 20 * time.Second
+
+// Default minimum time between the keepalive pings of a client.  This is the shortest interval gRPC
+//   clients ping at.
+const DefaultKeepaliveMinPingInterval = 10 * time.Second
+
+
+
+//*************************************************************************************************
+// Definition of the gRPC stats handler that logs when client connections to the server open and
+//   close, so that connections closed by keepalive or connection management (e.g., dead or too old
+//   connections) show up in the server logs.
+type connectionLogger struct {
+	serverLogger ServerLogger
+}
+
+// Key of the context value holding the connectionInfo of a client connection.
+type connectionInfoKey struct{}
+
+// Definition of what the connectionLogger remembers about a client connection.
+type connectionInfo struct {
+	remoteAddr net.Addr
+	opened     time.Time
+}
+
+// Method of the connectionLogger that attaches the remote address and opening time of a new client
+//   connection to its context.
+func (h connectionLogger) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connectionInfoKey{}, connectionInfo{remoteAddr: info.RemoteAddr, opened: time.Now()})
+}
+
+// Method of the connectionLogger that logs the opening and closing of a client connection.
+func (h connectionLogger) HandleConn(ctx context.Context, s stats.ConnStats) {
+	info, _ := ctx.Value(connectionInfoKey{}).(connectionInfo)
+	switch s.(type) {
+	case *stats.ConnBegin:
+		h.serverLogger.ServerLogDebug("conn", fmt.Sprint(info.remoteAddr), "Client connection opened")
+	case *stats.ConnEnd:
+		h.serverLogger.ServerLogInfo(
+			"conn",
+			fmt.Sprint(info.remoteAddr),
+			fmt.Sprintf("Client connection closed after %v", time.Since(info.opened).Round(time.Millisecond)),
+		)
+	}
+}
+
+// Method of the connectionLogger that leaves the context of a call as is.
+func (h connectionLogger) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// Method of the connectionLogger that ignores the events of a call.
+func (h connectionLogger) HandleRPC(context.Context, stats.RPCStats) {}
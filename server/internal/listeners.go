@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Default permissions of the socket files of Unix domain socket listeners: read and write for the
+//   owner and group of the server process, so that local sidecar clients in its group can connect.
+const DefaultUnixSocketMode = "0660"
+
+// Network types a listener can be declared with, as a scheme before its address (e.g.,
+//   tcp6://[::1]:50051 or unix:///run/fewer/fewer.sock).  A listener without a scheme is a TCP
+//   listener.
+var listenerNetworks = []string{"tcp", "tcp4", "tcp6", "unix"}
+
+// Function that splits a listener setting into its network type and address.  A listener without
+//   a scheme is a TCP listener.  The address of a Unix domain socket listener is the path of its
+//   socket file.
+func ParseListener(listener string) (network, address string, err error) {
+	network, address = "tcp", listener
+	if scheme, rest, ok := strings.Cut(listener, "://"); ok {
+		network, address = scheme, rest
+	}
+	known := false
+	for _, name := range listenerNetworks {
+		known = known || network == name
+	}
+	if !known {
+		return "", "", fmt.Errorf("unknown network type %q of listener %q (expected %s)", network, listener, strings.Join(listenerNetworks, ", "))
+	}
+	if network == "unix" {
+		if address == "" {
+			return "", "", fmt.Errorf("missing socket path of listener %q", listener)
+		}
+		return network, address, nil
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", fmt.Errorf("invalid address of listener %q: %v", listener, err)
+	}
+	return network, address, nil
+}
+
+// Method of the ServerConfig that opens every listener of the configuration.  Socket files of Unix
+//   domain socket listeners get the configured permissions; a stale socket file left behind by a
+//   previous server is replaced.  If any listener fails to open, the ones already opened are closed.
+func (c ServerConfig) Listen() ([]net.Listener, error) {
+	mode, err := c.unixSocketMode()
+	if err != nil {
+		return nil, err
+	}
+	var listeners []net.Listener
+	for _, listener := range c.Listeners {
+		lis, err := listen(listener, mode)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, lis)
+	}
+	return listeners, nil
+}
+
+// Internal method of the ServerConfig that parses the permissions of Unix domain socket files,
+//   written in octal.
+func (c ServerConfig) unixSocketMode() (fs.FileMode, error) {
+	value := c.UnixSocketMode
+	if value == "" {
+		value = DefaultUnixSocketMode
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid permissions %q (expected octal permissions, e.g., 0660)", value)
+	}
+	return fs.FileMode(mode), nil
+}
+
+// Helper function that opens a single listener, giving the socket file of a Unix domain socket
+//   listener the given permissions.
+func listen(listener string, mode fs.FileMode) (net.Listener, error) {
+	network, address, err := ParseListener(listener)
+	if err != nil {
+		return nil, err
+	}
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	// Only a leftover socket file is removed; any other file at the path is an error.
+	if info, err := os.Lstat(address); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(address); err != nil {
+			return nil, fmt.Errorf("could not remove stale socket file of listener %q: %w", listener, err)
+		}
+	} else if err == nil {
+		return nil, fmt.Errorf("could not listen on %q: %s exists and is not a socket", listener, address)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address, mode); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("could not set permissions of socket file of listener %q: %w", listener, err)
+	}
+	return lis, nil
+}
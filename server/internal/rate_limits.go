@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Modes of rate limiting: inputs over the rate are either delayed, or rejected.
+const (
+	RateLimitBackpressure = "backpressure"
+	RateLimitReject       = "reject"
+)
+
+// Keys that clients are told apart by when rate limiting their inputs.
+const (
+	RateLimitByPeer   = "peer"
+	RateLimitByTenant = "tenant"
+)
+
+// Number of token buckets kept before the ones of clients that have been quiet long enough to fill
+//   their bucket back up are dropped.
+const maxIdleRateBuckets = 1024
+
+
+
+//*************************************************************************************************
+// Definition of the rate limiter of the inputs sent to the Fewer Service, which gives every client
+//   a token bucket holding up to a burst of inputs, refilled at a steady rate of inputs per
+//   second.  Its settings can be replaced while the server is serving.
+type rateLimiter struct {
+	serverLogger ServerLogger
+
+	mu      sync.Mutex
+	config  RateLimitConfig
+	buckets map[string]*tokenBucket
+}
+
+// Definition of the token bucket of a single client.
+type tokenBucket struct {
+	// Inputs the client may still send right away.  Negative while later inputs are delayed.
+	tokens float64
+	// Time the tokens were last refilled.
+	filled time.Time
+}
+
+// Constructor function for creating a new rateLimiter that does not limit any client.
+func newRateLimiter(serverLogger ServerLogger) *rateLimiter {
+	return &rateLimiter{serverLogger: serverLogger, buckets: make(map[string]*tokenBucket)}
+}
+
+// Method of the rateLimiter that replaces its settings.  Every client starts over with a full
+//   bucket if the rate or burst changed.
+func (l *rateLimiter) setConfig(config RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if config.InputsPerSecond != l.config.InputsPerSecond || config.Burst != l.config.Burst {
+		clear(l.buckets)
+	}
+	l.config = config
+}
+
+// Method of the rateLimiter that takes the given number of inputs received in a call of the given
+//   method out of the bucket of its client.  If the bucket does not hold enough of them, the call
+//   is either held back until it does (backpressure), or a ResourceExhausted status error is
+//   returned (reject).  While held back, the client's next messages wait in the flow control
+//   window of the stream.  In reject mode, a message of more inputs than the burst, which the
+//   bucket can never hold, is rejected up front with an InvalidArgument status error, as waiting
+//   to retry it would not help.
+func (l *rateLimiter) wait(ctx context.Context, method string, inputs int) error {
+	l.mu.Lock()
+	config := l.config
+	if config.InputsPerSecond == 0 || inputs == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	client := l.clientOf(ctx, config)
+	burst := float64(config.Burst)
+	if burst == 0 {
+		burst = float64(config.InputsPerSecond)
+	}
+	if config.Mode == RateLimitReject && float64(inputs) > burst {
+		l.mu.Unlock()
+		l.serverLogger.ServerLogWarn(
+			"rpc",
+			method,
+			fmt.Sprintf("Rejecting %d inputs from %s, as they are more than the rate limit burst of %d inputs", inputs, client, int64(burst)),
+		)
+		return statusError(
+			codes.InvalidArgument,
+			pb.ErrorReason_INPUTS_OVER_BURST,
+			fmt.Sprintf("%d inputs in one message are more than the rate limit burst of %d inputs", inputs, int64(burst)),
+			limitMetadata(int64(burst)),
+			badRequest("input_nums", fmt.Sprintf("%d inputs are more than the rate limit burst of %d inputs", inputs, int64(burst))),
+		)
+	}
+	bucket := l.refill(client, burst, float64(config.InputsPerSecond))
+	if bucket.tokens >= float64(inputs) {
+		bucket.tokens -= float64(inputs)
+		l.mu.Unlock()
+		return nil
+	}
+	// Time until the bucket has refilled enough for the inputs.
+	delay := time.Duration((float64(inputs) - bucket.tokens) / float64(config.InputsPerSecond) * float64(time.Second))
+	if config.Mode == RateLimitReject {
+		l.mu.Unlock()
+		l.serverLogger.ServerLogWarn(
+			"rpc",
+			method,
+			fmt.Sprintf("Rejecting %d inputs from %s, as they go over the rate limit of %d inputs per second", inputs, client, config.InputsPerSecond),
+		)
+		return statusError(
+			codes.ResourceExhausted,
+			pb.ErrorReason_RATE_LIMITED,
+			fmt.Sprintf("rate limit of %d inputs per second exceeded", config.InputsPerSecond),
+			limitMetadata(int64(config.InputsPerSecond)),
+			quotaFailure(client, "rate_limits.inputs_per_second"),
+			retryInfo(delay),
+		)
+	}
+	// Reserve the inputs now, so that calls held back after this one wait their turn.
+	bucket.tokens -= float64(inputs)
+	l.mu.Unlock()
+
+	l.serverLogger.ServerLogInfo(
+		"rpc",
+		method,
+		fmt.Sprintf("Delaying %d inputs from %s by %v, as they go over the rate limit of %d inputs per second", inputs, client, delay.Round(time.Millisecond), config.InputsPerSecond),
+	)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return streamError(status.FromContextError(ctx.Err()).Err())
+	}
+}
+
+// Internal method of the rateLimiter that returns the bucket of a client, refilled for the time
+//   that went by since it was last refilled.  Must be called with mu held.
+func (l *rateLimiter) refill(client string, burst, rate float64) *tokenBucket {
+	now := time.Now()
+	bucket, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxIdleRateBuckets {
+			l.dropFullBuckets(now, burst, rate)
+		}
+		bucket = &tokenBucket{tokens: burst, filled: now}
+		l.buckets[client] = bucket
+		return bucket
+	}
+	bucket.tokens = min(burst, bucket.tokens+now.Sub(bucket.filled).Seconds()*rate)
+	bucket.filled = now
+	return bucket
+}
+
+// Internal method of the rateLimiter that drops the buckets that have filled back up, as their
+//   clients would start over with a full bucket anyway.  Must be called with mu held.
+func (l *rateLimiter) dropFullBuckets(now time.Time, burst, rate float64) {
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.filled).Seconds()*rate >= burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Internal method of the rateLimiter that returns the client a call is counted against.
+func (l *rateLimiter) clientOf(ctx context.Context, config RateLimitConfig) string {
+	if config.Key == RateLimitByTenant {
+		if tenant := incomingMetadataValue(ctx, TenantMetadataKey); tenant != "" {
+			return "tenant " + tenant
+		}
+	}
+	return peerHostOf(ctx)
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Test that inputs over the rate limit are delayed, rather than rejected, in backpressure mode.
+func TestRateLimitBackpressure(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, map[string]string{
+		"FEWER_RATE_LIMITS_INPUTS_PER_SECOND": "20",
+		"FEWER_RATE_LIMITS_BURST":             "5",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	started := time.Now()
+	upload, err := client.AggregateUpload(context.Background())
+	if err != nil {
+		t.Fatalf("AggregateUpload: %v", err)
+	}
+	for i := int32(1); i <= 15; i++ {
+		if err := upload.Send(&pb.NumberRequest{InputNum: i}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	summary, err := upload.CloseAndRecv()
+	if err != nil || summary.TotalInputs != 15 {
+		t.Fatalf("AggregateUpload() = %v, %v, want all 15 inputs aggregated", summary, err)
+	}
+	// The first 5 inputs use up the burst, and the other 10 come in at 20 inputs per second.
+	if elapsed := time.Since(started); elapsed < 400*time.Millisecond {
+		t.Errorf("upload took %v, want the inputs over the burst held back for about 500ms", elapsed)
+	}
+	serverLogger.AssertLogged(t, "info", "as they go over the rate limit of 20 inputs per second")
+}
+
+// Test that inputs over the rate limit are rejected in reject mode, with a bucket for each client
+//   when clients are told apart by tenant.
+func TestRateLimitReject(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, map[string]string{
+		"FEWER_RATE_LIMITS_INPUTS_PER_SECOND": "1",
+		"FEWER_RATE_LIMITS_BURST":             "2",
+		"FEWER_RATE_LIMITS_MODE":              "reject",
+		"FEWER_RATE_LIMITS_KEY":               "tenant",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	aggregate := func(tenant string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), TenantMetadataKey, tenant)
+		_, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: []int32{1, 2}})
+		return err
+	}
+	if err := aggregate("blue"); err != nil {
+		t.Errorf("first batch of tenant blue error = %v, want none", err)
+	}
+	if err := aggregate("green"); err != nil {
+		t.Errorf("first batch of tenant green error = %v, want none", err)
+	}
+	if err := aggregate("blue"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second batch of tenant blue error = %v, want ResourceExhausted", err)
+	}
+	serverLogger.AssertLogged(t, "warn", "Rejecting 2 inputs from tenant blue, as they go over the rate limit of 1 inputs per second")
+}
+
+// Test that a message of more inputs than the burst is rejected in reject mode as an invalid
+//   argument, which the client is not asked to retry, however full its bucket is.
+func TestRateLimitRejectOverBurst(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, map[string]string{
+		"FEWER_RATE_LIMITS_INPUTS_PER_SECOND": "1",
+		"FEWER_RATE_LIMITS_BURST":             "2",
+		"FEWER_RATE_LIMITS_MODE":              "reject",
+	}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	_, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("batch over the burst error = %v, want InvalidArgument", err)
+	}
+	if info := errorInfoOf(t, err); info.Reason != pb.ErrorReason_INPUTS_OVER_BURST.String() || info.Metadata["limit"] != "2" {
+		t.Errorf("batch over the burst error info = %v, want reason INPUTS_OVER_BURST with limit 2", info)
+	}
+	if badRequest := detailOf[*errdetails.BadRequest](err); len(badRequest.GetFieldViolations()) != 1 || badRequest.FieldViolations[0].Field != "input_nums" {
+		t.Errorf("batch over the burst bad request = %v, want a violation of input_nums", badRequest)
+	}
+	if retry := detailOf[*errdetails.RetryInfo](err); retry != nil {
+		t.Errorf("batch over the burst retry info = %v, want none", retry)
+	}
+	serverLogger.AssertLogged(t, "warn", "Rejecting 3 inputs from bufconn, as they are more than the rate limit burst of 2 inputs")
+
+	// The bucket is left as it was.
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}}); err != nil {
+		t.Errorf("batch within the burst error = %v, want none", err)
+	}
+}
+
+// Test of changing the rate limits of a serving server through a new configuration.
+func TestApplyConfigRateLimits(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	reloaded := loadTestServerConfig(t, map[string]string{"FEWER_RATE_LIMITS_INPUTS_PER_SECOND": "3", "FEWER_RATE_LIMITS_MODE": "reject"})
+	if err := fs.ApplyConfig(reloaded); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	serverLogger.AssertLogged(t, "info", "Changed rate_limits.inputs_per_second from 0 to 3")
+	serverLogger.AssertLogged(t, "info", "Changed rate_limits.mode from backpressure to reject")
+	serverLogger.AssertNotLogged(t, "warn", "Ignoring changes to rate_limits")
+
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}}); err != nil {
+		t.Errorf("first AggregateBatch() of 2 inputs error = %v, want none", err)
+	}
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second AggregateBatch() of 2 inputs error = %v, want ResourceExhausted", err)
+	}
+}
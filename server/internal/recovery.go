@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+
+
+//*************************************************************************************************
+// Definition of the recoverer that keeps a panic in a call handler from crashing the whole server.
+//   The panicking call fails with an Internal status error, the panic and its stack trace are
+//   logged at error level, and every other call keeps being served.
+type panicRecoverer struct {
+	serverLogger ServerLogger
+	// Number of panics recovered since the server was created.
+	recovered    atomic.Int64
+}
+
+// Constructor function for creating a new panicRecoverer.
+func newPanicRecoverer(serverLogger ServerLogger) *panicRecoverer {
+	return &panicRecoverer{serverLogger: serverLogger}
+}
+
+// Method of the panicRecoverer that is the unary interceptor recovering from panics of handlers.
+func (r *panicRecoverer) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer r.recoverCall(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// Method of the panicRecoverer that is the stream interceptor recovering from panics of handlers.
+func (r *panicRecoverer) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer r.recoverCall(info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+// Internal method of the panicRecoverer, deferred by its interceptors, that recovers from a panic
+//   of the handler of the given method, if there is one, and sets the error the call fails with.
+func (r *panicRecoverer) recoverCall(fullMethod string, err *error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	count := r.recovered.Add(1)
+	r.serverLogger.ServerLogError(
+		"rpc",
+		fullMethod,
+		fmt.Sprintf("Recovered from panic in call handler (%d panics recovered so far): %v\n%s", count, p, debug.Stack()),
+	)
+	*err = statusError(codes.Internal, pb.ErrorReason_INTERNAL_ERROR, "internal server error", nil)
+}
+
+// Method of the panicRecoverer that returns the number of panics recovered so far.
+func (r *panicRecoverer) recoveredPanics() int64 {
+	return r.recovered.Load()
+}
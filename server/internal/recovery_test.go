@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Definition of an aggregate sink that panics on the aggregates of streams labelled "boom", so that
+//   tests can make the call handlers that record them panic.
+type panickingSink struct{}
+
+func (panickingSink) RecordAggregate(record AggregateRecord) error {
+	if record.Key == "boom" {
+		panic("sink exploded")
+	}
+	return nil
+}
+func (panickingSink) Close() error { return nil }
+
+// Test that a panic in a stream or unary call handler fails only that call with an Internal status
+//   error, is logged with its stack trace and counted, and that other streams keep being served.
+func TestRecoverFromHandlerPanics(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	fs.SetAggregateSink(panickingSink{})
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	healthy, err := client.GetAggregatesStream(metadata.AppendToOutgoingContext(context.Background(), StreamKeyMetadataKey, "fine"))
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	healthy.Send(&pb.NumberRequest{InputNums: []int32{1, 2}})
+
+	boomCtx := metadata.AppendToOutgoingContext(context.Background(), StreamKeyMetadataKey, "boom")
+	panicking, err := client.GetAggregatesStream(boomCtx)
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	panicking.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3}})
+	for err == nil {
+		_, err = panicking.Recv()
+	}
+	if status.Code(err) != codes.Internal {
+		t.Errorf("panicking stream error = %v, want Internal", err)
+	}
+	if _, err := client.AggregateBatch(boomCtx, &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3}}); status.Code(err) != codes.Internal {
+		t.Errorf("panicking AggregateBatch() error = %v, want Internal", err)
+	}
+
+	// The stream opened before the panics is still served.
+	healthy.Send(&pb.NumberRequest{InputNum: 3})
+	resp, err := healthy.Recv()
+	if err != nil || resp.GetAggregate().GetResult() != 6 {
+		t.Errorf("healthy stream Recv() = %v, %v, want the sum 6", resp, err)
+	}
+	healthy.CloseSend()
+
+	if got := fs.RecoveredPanics(); got != 2 {
+		t.Errorf("RecoveredPanics() = %d, want 2", got)
+	}
+	serverLogger.AssertLogged(t, "error", "Recovered from panic in call handler (1 panics recovered so far): sink exploded")
+	serverLogger.AssertLogged(t, "error", "runtime/debug.Stack()")
+}
@@ -0,0 +1,587 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astronomical3/fewer_grpc/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables that override settings of the server configuration.  The
+//   name of the variable overriding a setting is the prefix followed by the path of the setting in
+//   upper case, with sections separated by an underscore (e.g., FEWER_LOGGING_LEVEL overrides the
+//   level setting of the logging section).
+const ServerConfigEnvPrefix = "FEWER_"
+
+// Environment variable holding the path of the server configuration file, if the --config flag is
+//   not given.
+const ServerConfigPathEnvVar = ServerConfigEnvPrefix + "CONFIG"
+
+// Default listener, log directory and log file names of the server.  Log file names are relative to
+//   the production/ or devtest/ subdirectory of the log directory.
+const DefaultServerListener = "localhost:50051"
+const DefaultServerLogDir = "serverlogs"
+const DefaultServerLogProdFilename = "server.log"
+const DefaultServerLogDevFilename = "server_devtest.log"
+
+// Placeholder written instead of every auth token when printing the server configuration.
+const redactedToken = "<redacted>"
+
+
+
+
+//*************************************************************************************************
+// Definition of the settings of the Fewer Service server application, as read from a YAML
+//   configuration file.  Every setting has a default, and can be overridden by a FEWER_*
+//   environment variable.
+type ServerConfig struct {
+	// Whether the server is a production server (true) or a development/test server (false).
+	Prod           bool                `yaml:"prod"`
+	// Addresses the server serves clients on, each optionally prefixed with its network type.
+	Listeners      []string            `yaml:"listeners"`
+	// Permissions of the socket files of Unix domain socket listeners, in octal (e.g., "0660").
+	UnixSocketMode string              `yaml:"unix_socket_mode"`
+	TLS            TLSConfig           `yaml:"tls"`
+	Auth           AuthConfig          `yaml:"auth"`
+	Limits         LimitsConfig        `yaml:"limits"`
+	RateLimits     RateLimitConfig     `yaml:"rate_limits"`
+	Keepalive      KeepaliveConfig     `yaml:"keepalive"`
+	Logging        LoggingConfig       `yaml:"logging"`
+	Aggregation    AggregationConfig   `yaml:"aggregation"`
+	Observability  ObservabilityConfig `yaml:"observability"`
+}
+
+// Definition of the TLS settings of the server.  TLS is turned on by giving a certificate file.
+type TLSConfig struct {
+	// PEM files holding the certificate of the server and its private key.
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	// PEM file holding the certificate authorities that client certificates must be signed by.  If
+	//   given, clients must present a certificate (mutual TLS).
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Definition of the authentication settings of the server.
+type AuthConfig struct {
+	// Bearer tokens accepted from Fewer Service clients.  If empty, clients are not authenticated.
+	Tokens      []string `yaml:"tokens,omitempty"`
+	// Bearer tokens accepted from operators calling the FewerAdmin service.  If empty, the FewerAdmin
+	//   service is turned off.
+	AdminTokens []string `yaml:"admin_tokens,omitempty"`
+}
+
+// Definition of the limits the server enforces on its connections and aggregation streams.  0
+//   leaves a limit at its gRPC default, or turns it off.
+type LimitsConfig struct {
+	// Maximum number of concurrent streams on each client connection.
+	MaxConcurrentStreams uint32        `yaml:"max_concurrent_streams"`
+	// Maximum size (in bytes) of a message received from or sent to a client.
+	MaxRecvMsgSize       int           `yaml:"max_recv_msg_size"`
+	MaxSendMsgSize       int           `yaml:"max_send_msg_size"`
+	// Maximum number of aggregation streams open at once, over all clients and from a single
+	//   client host.
+	MaxStreams           int           `yaml:"max_streams"`
+	MaxStreamsPerPeer    int           `yaml:"max_streams_per_peer"`
+	// Maximum number of inputs aggregated on a single stream or batch.
+	MaxStreamInputs      int64         `yaml:"max_stream_inputs"`
+	// Maximum time an aggregation stream may stay open, and may go without a request.
+	MaxStreamDuration    time.Duration `yaml:"max_stream_duration"`
+	MaxStreamIdle        time.Duration `yaml:"max_stream_idle"`
+}
+
+// Definition of the keepalive and connection management settings of the server.  0 leaves a
+//   setting at its gRPC default.
+type KeepaliveConfig struct {
+	// Time after which the server pings a client connection it has heard nothing from, and time it
+	//   waits for the ping to be answered before closing the connection as dead.
+	Time                  time.Duration `yaml:"time"`
+	Timeout               time.Duration `yaml:"timeout"`
+	// Time after which a client connection without open calls is closed.
+	MaxConnectionIdle     time.Duration `yaml:"max_connection_idle"`
+	// Time after which a client connection is closed (with a GOAWAY), and time its open calls are
+	//   then given to finish before it is closed for good.
+	MaxConnectionAge      time.Duration `yaml:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `yaml:"max_connection_age_grace"`
+	// Minimum time between the keepalive pings of a client, and whether a client may ping while it
+	//   has no open calls.  Clients going against this policy are disconnected.
+	MinPingInterval       time.Duration `yaml:"min_ping_interval"`
+	PermitWithoutStream   bool          `yaml:"permit_without_stream"`
+}
+
+// Definition of the rate limits on the inputs each client sends to the Fewer Service.  Can be
+//   changed while the server is serving.
+type RateLimitConfig struct {
+	// Inputs per second each client may send on average, and how many inputs it may send at once
+	//   after a pause.  A rate of 0 turns rate limiting off, and a burst of 0 is the rate.
+	InputsPerSecond int    `yaml:"inputs_per_second"`
+	Burst           int    `yaml:"burst"`
+	// What happens to inputs over the rate: "backpressure" delays them, and "reject" fails the call
+	//   with RESOURCE_EXHAUSTED.
+	Mode            string `yaml:"mode"`
+	// What clients are told apart by: "peer" (their host) or "tenant" (the tenant they label their
+	//   calls with, or their host if they do not).
+	Key             string `yaml:"key"`
+}
+
+// Definition of the logging settings of the server.  An empty level, file or list of outputs is
+//   filled in according to whether the server is a production server.
+type LoggingConfig struct {
+	// Directory whose production/ or devtest/ subdirectory holds the server log file.
+	Dir        string        `yaml:"dir"`
+	// Name of the server log file inside that subdirectory, or absolute path of the log file.
+	File       string        `yaml:"file"`
+	// Minimum level of logged activity: "debug", "info", "warn" or "error".
+	Level      string        `yaml:"level"`
+	// Format of logged activity: "logfmt", "json" or "text".
+	Format     string        `yaml:"format"`
+	// Destinations of logged activity: "stdout", "stderr" or paths of log files.
+	Outputs    []string      `yaml:"outputs"`
+	// Size (in megabytes) and age past which the log files are rotated, number of rotated log files
+	//   kept, and whether they are compressed.
+	MaxSize    int64         `yaml:"max_size"`
+	MaxAge     time.Duration `yaml:"max_age"`
+	MaxBackups int           `yaml:"max_backups"`
+	Compress   bool          `yaml:"compress"`
+}
+
+// Definition of the aggregation settings of the Fewer Service.
+type AggregationConfig struct {
+	// Number of inputs added together into each aggregate.
+	BatchSize       int      `yaml:"batch_size"`
+	// Number of inputs processed between two acknowledgements sent back to a client.
+	AckInterval     int      `yaml:"ack_interval"`
+	// Reducers clients may ask for when opening a stream ("sum", "min" or "max").
+	AllowedReducers []string `yaml:"allowed_reducers"`
+	// Backend emitted aggregates are persisted to ("none", "jsonl" or "bolt"), and the path of the
+	//   file it writes to.
+	Sink            string   `yaml:"sink"`
+	SinkPath        string   `yaml:"sink_path"`
+}
+
+// Definition of the services registered alongside the Fewer Service to observe the server.
+type ObservabilityConfig struct {
+	// Whether the gRPC reflection service is registered, so that tools like grpcurl can list the
+	//   services of the server.
+	Reflection bool `yaml:"reflection"`
+	// Whether the gRPC health checking service (grpc.health.v1.Health) is registered.
+	Health     bool `yaml:"health"`
+}
+
+// Function that returns the default settings of the server.
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Prod:           true,
+		Listeners:      []string{DefaultServerListener},
+		UnixSocketMode: DefaultUnixSocketMode,
+		RateLimits: RateLimitConfig{
+			Mode: RateLimitBackpressure,
+			Key:  RateLimitByPeer,
+		},
+		Keepalive: KeepaliveConfig{
+			Time:                DefaultKeepaliveTime,
+			Timeout:             DefaultKeepaliveTimeout,
+			MinPingInterval:     DefaultKeepaliveMinPingInterval,
+			PermitWithoutStream: true,
+		},
+		Logging: LoggingConfig{
+			Dir:    DefaultServerLogDir,
+			Format: logging.FormatLogfmt,
+		},
+		Aggregation: AggregationConfig{
+			BatchSize:       DefaultBatchSize,
+			AckInterval:     DefaultAckInterval,
+			AllowedReducers: slices.Clone(knownReducers),
+			Sink:            "none",
+			SinkPath:        "aggregates.jsonl",
+		},
+		Observability: ObservabilityConfig{
+			Reflection: true,
+			Health:     true,
+		},
+	}
+}
+
+// Function that loads the settings of the server.  The defaults are overlaid, in order, by the
+//   settings of the YAML file at path (if path is not empty), by the FEWER_* environment variables
+//   found through lookupEnv (e.g., os.LookupEnv), and by override (if not nil; e.g., command-line
+//   flags).  Settings left empty are then filled in, and the result is validated.
+func LoadServerConfig(path string, lookupEnv func(string) (string, bool), override func(*ServerConfig)) (ServerConfig, error) {
+	config := DefaultServerConfig()
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return ServerConfig{}, fmt.Errorf("could not read server config file: %w", err)
+		}
+		if err := config.decodeYAML(content); err != nil {
+			return ServerConfig{}, fmt.Errorf("could not parse server config file %s: %w", path, err)
+		}
+	}
+	if lookupEnv != nil {
+		if err := config.applyEnv(lookupEnv); err != nil {
+			return ServerConfig{}, err
+		}
+	}
+	if override != nil {
+		override(&config)
+	}
+	config.fillDefaults()
+	if err := config.Validate(); err != nil {
+		return ServerConfig{}, err
+	}
+	return config, nil
+}
+
+// Method of the ServerConfig that reports every invalid setting.
+func (c ServerConfig) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Listeners) == 0 {
+		invalid("listeners: at least one listener is required")
+	}
+	seen := make(map[string]bool)
+	for _, listener := range c.Listeners {
+		if _, _, err := ParseListener(listener); err != nil {
+			invalid("listeners: %v", err)
+		} else if seen[listener] {
+			invalid("listeners: listener %q is given more than once", listener)
+		}
+		seen[listener] = true
+	}
+	if _, err := c.unixSocketMode(); err != nil {
+		invalid("unix_socket_mode: %v", err)
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		invalid("tls: cert_file and key_file must be given together")
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		invalid("tls: client_ca_file requires cert_file and key_file")
+	}
+
+	for i, token := range c.Auth.Tokens {
+		if token == "" {
+			invalid("auth.tokens: token %d is empty", i+1)
+		}
+	}
+	for i, token := range c.Auth.AdminTokens {
+		if token == "" {
+			invalid("auth.admin_tokens: token %d is empty", i+1)
+		}
+		if slices.Contains(c.Auth.Tokens, token) {
+			invalid("auth.admin_tokens: token %d is also a client token (auth.tokens)", i+1)
+		}
+	}
+
+	if c.Limits.MaxRecvMsgSize < 0 {
+		invalid("limits.max_recv_msg_size must not be negative, got %d", c.Limits.MaxRecvMsgSize)
+	}
+	if c.Limits.MaxSendMsgSize < 0 {
+		invalid("limits.max_send_msg_size must not be negative, got %d", c.Limits.MaxSendMsgSize)
+	}
+	if c.Limits.MaxStreams < 0 {
+		invalid("limits.max_streams must not be negative, got %d", c.Limits.MaxStreams)
+	}
+	if c.Limits.MaxStreamsPerPeer < 0 {
+		invalid("limits.max_streams_per_peer must not be negative, got %d", c.Limits.MaxStreamsPerPeer)
+	}
+	if c.Limits.MaxStreamInputs < 0 {
+		invalid("limits.max_stream_inputs must not be negative, got %d", c.Limits.MaxStreamInputs)
+	}
+	if c.Limits.MaxStreamDuration < 0 {
+		invalid("limits.max_stream_duration must not be negative, got %v", c.Limits.MaxStreamDuration)
+	}
+	if c.Limits.MaxStreamIdle < 0 {
+		invalid("limits.max_stream_idle must not be negative, got %v", c.Limits.MaxStreamIdle)
+	}
+
+	for _, setting := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"keepalive.time", c.Keepalive.Time},
+		{"keepalive.timeout", c.Keepalive.Timeout},
+		{"keepalive.max_connection_idle", c.Keepalive.MaxConnectionIdle},
+		{"keepalive.max_connection_age", c.Keepalive.MaxConnectionAge},
+		{"keepalive.max_connection_age_grace", c.Keepalive.MaxConnectionAgeGrace},
+		{"keepalive.min_ping_interval", c.Keepalive.MinPingInterval},
+	} {
+		if setting.value < 0 {
+			invalid("%s must not be negative, got %v", setting.name, setting.value)
+		}
+	}
+
+	if c.RateLimits.InputsPerSecond < 0 {
+		invalid("rate_limits.inputs_per_second must not be negative, got %d", c.RateLimits.InputsPerSecond)
+	}
+	if c.RateLimits.Burst < 0 {
+		invalid("rate_limits.burst must not be negative, got %d", c.RateLimits.Burst)
+	}
+	switch c.RateLimits.Mode {
+	case RateLimitBackpressure, RateLimitReject:
+	default:
+		invalid("rate_limits.mode: unknown rate limiting mode %q (expected backpressure or reject)", c.RateLimits.Mode)
+	}
+	switch c.RateLimits.Key {
+	case RateLimitByPeer, RateLimitByTenant:
+	default:
+		invalid("rate_limits.key: unknown rate limiting key %q (expected peer or tenant)", c.RateLimits.Key)
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		invalid("logging.level: %v", err)
+	}
+	switch c.Logging.Format {
+	case logging.FormatLogfmt, logging.FormatJSON, logging.FormatText:
+	default:
+		invalid("logging.format: unknown log format %q (expected logfmt, json or text)", c.Logging.Format)
+	}
+	if c.Logging.MaxSize < 0 {
+		invalid("logging.max_size must not be negative, got %d", c.Logging.MaxSize)
+	}
+	if c.Logging.MaxAge < 0 {
+		invalid("logging.max_age must not be negative, got %v", c.Logging.MaxAge)
+	}
+	if c.Logging.MaxBackups < 0 {
+		invalid("logging.max_backups must not be negative, got %d", c.Logging.MaxBackups)
+	}
+
+	if c.Aggregation.BatchSize < 1 {
+		invalid("aggregation.batch_size must be at least 1, got %d", c.Aggregation.BatchSize)
+	}
+	if c.Aggregation.AckInterval < 1 {
+		invalid("aggregation.ack_interval must be at least 1, got %d", c.Aggregation.AckInterval)
+	}
+	if len(c.Aggregation.AllowedReducers) == 0 {
+		invalid("aggregation.allowed_reducers must allow at least one reducer")
+	}
+	for _, reducer := range c.Aggregation.AllowedReducers {
+		if !slices.Contains(knownReducers, reducer) {
+			invalid("aggregation.allowed_reducers: unknown reducer %q (expected sum, min or max)", reducer)
+		}
+	}
+	switch c.Aggregation.Sink {
+	case "", "none":
+	case "jsonl", "bolt":
+		if c.Aggregation.SinkPath == "" {
+			invalid("aggregation.sink_path is required by the %s sink", c.Aggregation.Sink)
+		}
+	default:
+		invalid("aggregation.sink: unknown aggregate sink backend %q (expected none, jsonl or bolt)", c.Aggregation.Sink)
+	}
+	return errors.Join(errs...)
+}
+
+// Method of the ServerConfig that returns the settings of the server logger.
+func (c ServerConfig) LogConfig() logging.Config {
+	return logging.Config{
+		Level:        c.Logging.Level,
+		Format:       c.Logging.Format,
+		Destinations: c.Logging.Outputs,
+		Rotation: logging.RotationConfig{
+			MaxSize:    c.Logging.MaxSize << 20,
+			MaxAge:     c.Logging.MaxAge,
+			MaxBackups: c.Logging.MaxBackups,
+			Compress:   c.Logging.Compress,
+		},
+	}
+}
+
+// Method of the ServerConfig that returns the limits its Fewer Service enforces on aggregation
+//   streams.
+func (c ServerConfig) StreamLimits() StreamLimits {
+	return StreamLimits{
+		MaxStreams:        c.Limits.MaxStreams,
+		MaxStreamsPerPeer: c.Limits.MaxStreamsPerPeer,
+		MaxInputs:         c.Limits.MaxStreamInputs,
+		MaxDuration:       c.Limits.MaxStreamDuration,
+		MaxIdle:           c.Limits.MaxStreamIdle,
+	}
+}
+
+// Method of the ServerConfig that returns the gRPC server options carrying its TLS credentials,
+//   limits and keepalive settings.  Fails if the TLS certificate or key files cannot be loaded.
+func (c ServerConfig) ServerOptions() ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if c.TLS.CertFile != "" {
+		creds, err := c.TLS.serverCredentials()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if c.Limits.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.Limits.MaxConcurrentStreams))
+	}
+	if c.Limits.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.Limits.MaxRecvMsgSize))
+	}
+	if c.Limits.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.Limits.MaxSendMsgSize))
+	}
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  c.Keepalive.Time,
+			Timeout:               c.Keepalive.Timeout,
+			MaxConnectionIdle:     c.Keepalive.MaxConnectionIdle,
+			MaxConnectionAge:      c.Keepalive.MaxConnectionAge,
+			MaxConnectionAgeGrace: c.Keepalive.MaxConnectionAgeGrace,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.Keepalive.MinPingInterval,
+			PermitWithoutStream: c.Keepalive.PermitWithoutStream,
+		}),
+	)
+	return opts, nil
+}
+
+// Method of the ServerConfig that writes its settings to w as YAML, in the format of a server
+//   configuration file.  Auth and admin tokens are masked.
+func (c ServerConfig) WriteYAML(w io.Writer) error {
+	if len(c.Auth.Tokens) > 0 {
+		c.Auth.Tokens = slices.Repeat([]string{redactedToken}, len(c.Auth.Tokens))
+	}
+	if len(c.Auth.AdminTokens) > 0 {
+		c.Auth.AdminTokens = slices.Repeat([]string{redactedToken}, len(c.Auth.AdminTokens))
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Internal method of the ServerConfig that overlays the settings of a YAML document.  Unknown
+//   settings are rejected, so that misspelled ones are not silently ignored.
+func (c *ServerConfig) decodeYAML(content []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Internal method of the ServerConfig that fills in the logging settings left empty, according to
+//   whether the server is a production server.
+func (c *ServerConfig) fillDefaults() {
+	if c.Logging.Level == "" && c.Prod {
+		c.Logging.Level = "info"
+	} else if c.Logging.Level == "" {
+		c.Logging.Level = "debug"
+	}
+	if c.Logging.File == "" && c.Prod {
+		c.Logging.File = DefaultServerLogProdFilename
+	} else if c.Logging.File == "" {
+		c.Logging.File = DefaultServerLogDevFilename
+	}
+	if len(c.Logging.Outputs) == 0 {
+		c.Logging.Outputs = []string{logging.StdoutDestination, logging.LogFilePath(c.Logging.Dir, c.Logging.File, c.Prod)}
+	}
+}
+
+// Internal method of the ServerConfig that overrides its settings with the FEWER_* environment
+//   variables that are set.
+func (c *ServerConfig) applyEnv(lookupEnv func(string) (string, bool)) error {
+	return applyEnvToStruct(reflect.ValueOf(c).Elem(), ServerConfigEnvPrefix, lookupEnv)
+}
+
+// Helper function that overrides the fields of a configuration struct with the environment
+//   variables named after their YAML keys, descending into nested sections.
+func applyEnvToStruct(v reflect.Value, prefix string, lookupEnv func(string) (string, bool)) error {
+	durationType := reflect.TypeOf(time.Duration(0))
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvToStruct(field, name+"_", lookupEnv); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch {
+		case field.Type() == durationType:
+			var d time.Duration
+			if d, err = time.ParseDuration(value); err == nil {
+				field.SetInt(int64(d))
+			}
+		case field.Kind() == reflect.String:
+			field.SetString(value)
+		case field.Kind() == reflect.Bool:
+			var b bool
+			if b, err = strconv.ParseBool(value); err == nil {
+				field.SetBool(b)
+			}
+		case field.CanInt():
+			var n int64
+			if n, err = strconv.ParseInt(value, 10, field.Type().Bits()); err == nil {
+				field.SetInt(n)
+			}
+		case field.CanUint():
+			var n uint64
+			if n, err = strconv.ParseUint(value, 10, field.Type().Bits()); err == nil {
+				field.SetUint(n)
+			}
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			field.Set(reflect.ValueOf(logging.SplitDestinations(value)))
+		default:
+			err = fmt.Errorf("unsupported setting type %v", field.Type())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value %q of environment variable %s: %v", value, name, err)
+		}
+	}
+	return nil
+}
+
+// Internal method of the TLSConfig that loads the transport credentials of the server.
+func (c TLSConfig) serverCredentials() (credentials.TransportCredentials, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS client CA file: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in TLS client CA file %s", c.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Helper function that writes a server configuration file, and returns its path.
+func writeServerConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// Helper function that returns a lookupEnv function reading from the given variables.
+func fakeEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+// Test of the order in which settings are overlaid: defaults, then the configuration file, then the
+//   environment variables, then the override.
+func TestLoadServerConfigPrecedence(t *testing.T) {
+	path := writeServerConfigFile(t, `
+prod: false
+listeners: ["tcp6://[::1]:6000"]
+logging:
+  level: warn
+  format: json
+  max_age: 24h
+aggregation:
+  batch_size: 5
+  ack_interval: 4
+limits:
+  max_concurrent_streams: 100
+`)
+	env := fakeEnv(map[string]string{
+		"FEWER_LOGGING_FORMAT":           "text",
+		"FEWER_AGGREGATION_BATCH_SIZE":   "7",
+		"FEWER_LIMITS_MAX_RECV_MSG_SIZE": "1048576",
+		"FEWER_OBSERVABILITY_HEALTH":     "false",
+		"FEWER_LOGGING_OUTPUTS":          "stderr, /var/log/fewer.log",
+	})
+	config, err := LoadServerConfig(path, env, func(c *ServerConfig) { c.Aggregation.AckInterval = 2 })
+	if err != nil {
+		t.Fatalf("LoadServerConfig() error = %v", err)
+	}
+
+	want := DefaultServerConfig()
+	want.Prod = false
+	want.Listeners = []string{"tcp6://[::1]:6000"}
+	want.Logging.Level = "warn"
+	want.Logging.Format = "text"
+	want.Logging.MaxAge = 24 * time.Hour
+	want.Logging.File = DefaultServerLogDevFilename
+	want.Logging.Outputs = []string{"stderr", "/var/log/fewer.log"}
+	want.Aggregation.BatchSize = 7
+	want.Aggregation.AckInterval = 2
+	want.Limits = LimitsConfig{MaxConcurrentStreams: 100, MaxRecvMsgSize: 1 << 20}
+	want.Observability.Health = false
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadServerConfig() = %+v, want %+v", config, want)
+	}
+}
+
+// Test of the logging settings filled in according to whether the server is a production server.
+func TestLoadServerConfigDefaults(t *testing.T) {
+	for _, prod := range []string{"true", "false"} {
+		config, err := LoadServerConfig("", fakeEnv(map[string]string{"FEWER_PROD": prod}), nil)
+		if err != nil {
+			t.Fatalf("LoadServerConfig() error = %v", err)
+		}
+		wantLevel, wantPath := "info", filepath.Join(DefaultServerLogDir, "production", DefaultServerLogProdFilename)
+		if !config.Prod {
+			wantLevel, wantPath = "debug", filepath.Join(DefaultServerLogDir, "devtest", DefaultServerLogDevFilename)
+		}
+		if config.Logging.Level != wantLevel || !reflect.DeepEqual(config.Logging.Outputs, []string{"stdout", wantPath}) {
+			t.Errorf("FEWER_PROD=%s: got level %q and outputs %v, want %q and [stdout %s]", prod, config.Logging.Level, config.Logging.Outputs, wantLevel, wantPath)
+		}
+	}
+}
+
+// Test of rejecting invalid configuration files, environment variables and settings.
+func TestLoadServerConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		wantErr []string
+	}{
+		{name: "unknown setting", content: "aggregation:\n  batch_sise: 3\n", wantErr: []string{"batch_sise"}},
+		{name: "malformed duration", content: "logging:\n  max_age: forever\n", wantErr: []string{"forever"}},
+		{name: "malformed environment variable", env: map[string]string{"FEWER_AGGREGATION_BATCH_SIZE": "three"}, wantErr: []string{"FEWER_AGGREGATION_BATCH_SIZE"}},
+		{
+			name:    "invalid settings",
+			content: "listeners: [\"udp://localhost:1\"]\ntls:\n  cert_file: server.pem\nlogging:\n  level: verbose\naggregation:\n  batch_size: 0\n  sink: s3\n",
+			wantErr: []string{`unknown network type "udp"`, "cert_file and key_file", "logging.level", "aggregation.batch_size", "aggregation.sink"},
+		},
+		{name: "no listener", content: "listeners: []\n", wantErr: []string{"at least one listener"}},
+		{name: "duplicate listener", env: map[string]string{"FEWER_LISTENERS": "localhost:1,localhost:1"}, wantErr: []string{`"localhost:1" is given more than once`}},
+		{name: "unix listener without path", env: map[string]string{"FEWER_LISTENERS": "unix://"}, wantErr: []string{"missing socket path"}},
+		{name: "invalid socket permissions", env: map[string]string{"FEWER_UNIX_SOCKET_MODE": "0999"}, wantErr: []string{"unix_socket_mode", "0999"}},
+		{name: "negative stream limits", env: map[string]string{"FEWER_LIMITS_MAX_STREAMS": "-1", "FEWER_LIMITS_MAX_STREAM_IDLE": "-1s"}, wantErr: []string{"limits.max_streams must not be negative", "limits.max_stream_idle"}},
+		{name: "invalid rate limits", env: map[string]string{"FEWER_RATE_LIMITS_MODE": "drop", "FEWER_RATE_LIMITS_KEY": "user"}, wantErr: []string{`rate limiting mode "drop"`, `rate limiting key "user"`}},
+		{name: "negative keepalive", env: map[string]string{"FEWER_KEEPALIVE_TIMEOUT": "-1s"}, wantErr: []string{"keepalive.timeout must not be negative"}},
+		{name: "listener without port", env: map[string]string{"FEWER_LISTENERS": "localhost"}, wantErr: []string{"invalid address"}},
+		{name: "unknown reducer", env: map[string]string{"FEWER_AGGREGATION_ALLOWED_REDUCERS": "sum,median"}, wantErr: []string{`aggregation.allowed_reducers: unknown reducer "median"`}},
+		{name: "admin token shared with clients", env: map[string]string{"FEWER_AUTH_TOKENS": "s3cret", "FEWER_AUTH_ADMIN_TOKENS": "adm1n,s3cret"}, wantErr: []string{"auth.admin_tokens: token 2 is also a client token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.content != "" {
+				path = writeServerConfigFile(t, tt.content)
+			}
+			_, err := LoadServerConfig(path, fakeEnv(tt.env), nil)
+			if err == nil {
+				t.Fatalf("LoadServerConfig() succeeded, want an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadServerConfig() error = %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+// Test that the configuration printed by WriteYAML() loads back into the same settings.
+func TestServerConfigWriteYAML(t *testing.T) {
+	config, err := LoadServerConfig("", fakeEnv(map[string]string{"FEWER_LOGGING_MAX_AGE": "90m", "FEWER_TLS_CERT_FILE": "server.pem", "FEWER_TLS_KEY_FILE": "server.key"}), nil)
+	if err != nil {
+		t.Fatalf("LoadServerConfig() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := config.WriteYAML(&buf); err != nil {
+		t.Fatalf("WriteYAML() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "max_age: 1h30m0s") {
+		t.Errorf("WriteYAML() wrote %q, want durations written as strings", buf.String())
+	}
+	reloaded, err := LoadServerConfig(writeServerConfigFile(t, buf.String()), nil, nil)
+	if err != nil {
+		t.Fatalf("LoadServerConfig() of printed configuration error = %v", err)
+	}
+	if !reflect.DeepEqual(reloaded, config) {
+		t.Errorf("printed configuration loads back as %+v, want %+v", reloaded, config)
+	}
+}
+
+// Test of a GeneralFewerServer created from a configuration: its health checking service reports
+//   it as serving until it shuts down, and its Fewer Service uses the configured batch size.
+func TestNewGeneralFewerServerWithConfig(t *testing.T) {
+	config, err := LoadServerConfig("", fakeEnv(map[string]string{"FEWER_AGGREGATION_BATCH_SIZE": "2"}), nil)
+	if err != nil {
+		t.Fatalf("LoadServerConfig() error = %v", err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	fs, err := NewGeneralFewerServerWithConfig(discardServerLogger{}, []net.Listener{lis}, config)
+	if err != nil {
+		t.Fatalf("NewGeneralFewerServerWithConfig() error = %v", err)
+	}
+	go fs.Serve()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	defer conn.Close()
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Check() = %v, %v, want SERVING", health, err)
+	}
+	resp, err := pb.NewFewerServiceClient(conn).AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatalf("AggregateBatch: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Result != 3 || resp.Results[1].Result != 7 {
+		t.Errorf("AggregateBatch() results = %v, want sums of batches of 2 inputs", resp.Results)
+	}
+	fs.Shutdown()
+}
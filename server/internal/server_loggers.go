@@ -1,10 +1,12 @@
 package internal
 
 import (
-	"os"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/go-kit/log"
-	"github.com/go-kit/log/level"
+	"github.com/astronomical3/fewer_grpc/internal/logging"
 )
 
 
@@ -13,6 +15,7 @@ import (
 // Definition of a ServerLogger interface that includes common logging operation methods that will
 //   be used across multiple concrete logger types.
 type ServerLogger interface {
+	ServerLogDebug(key, value, message string)
 	ServerLogInfo(key, value, message string)
 	ServerLogWarn(key, value, message string)
 	ServerLogError(key, value, message string)
@@ -22,150 +25,229 @@ type ServerLogger interface {
 
 
 //*************************************************************************************************
-// Definition of a production-level server activity logger that logs both on the terminal/stdout
-//   and a file.
-type ServerLoggingObjectPROD struct {
-	// Log only current server session activity on standard output
-	terminalLogger   log.Logger
-	// Log all server sessions' history on a file
-	serverFileLogger log.Logger
-	// Log file that the serverFileLogger operates on.  Stored in this field so that
-	//   it can be properly closed when client app is exited.
-	serverLogFile    *os.File
+// Definition of a server activity logger that logs through the structured logging package shared
+//   with the client, to every destination (terminal, log files) and in the format of its logging
+//   configuration.  The key/value pair of each ServerLogger call is logged as an attribute of the
+//   entry.
+type ServerLoggingObject struct {
+	logger *logging.Logger
+}
+
+// Constructor function that creates a server activity logger from the given logging configuration.
+func NewServerLoggingObject(config logging.Config) (*ServerLoggingObject, error) {
+	logger, err := logging.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerLoggingObject{logger: logger}, nil
+}
+
+// Function that returns the default logging configuration of a production-level server (INFO-level
+//   and above) or development-level server (DEBUG-level and above), logging both on the terminal
+//   and the given server log file.
+func DefaultServerLogConfig(serverLogFilename string, isProd bool) logging.Config {
+	config := logging.Config{
+		Level:        "info",
+		Format:       logging.FormatLogfmt,
+		Destinations: []string{logging.StdoutDestination, serverLogFilename},
+	}
+	if !isProd {
+		config.Level = "debug"
+	}
+	return config
 }
 
 // Constructor function that creates a production-level logger for logging all server activity,
-//   INFO-level and above.
-func NewServerLoggingObjectPROD(serverLogFilename string) *ServerLoggingObjectPROD {
-	// Create terminal logger
-	terminalLogger := log.NewLogfmtLogger(os.Stdout)
-	terminalLogger = level.NewFilter(terminalLogger, level.AllowInfo())
-	terminalLogger = log.With(terminalLogger, "time", log.DefaultTimestampUTC)
-
-	// Add filepath to serverlogs directory to get full server log filepath.
-	//   serverLogFilename = "github.com/astronomical3/fewer_grpc/server/serverlogs/production/" + serverLogFilename
-
-	// Create or open the server log file object
-	serverLogFile, err := os.OpenFile(serverLogFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+//   INFO-level and above, on both the terminal/stdout and a file.
+func NewServerLoggingObjectPROD(serverLogFilename string) *ServerLoggingObject {
+	serverLogger, err := NewServerLoggingObject(DefaultServerLogConfig(serverLogFilename, true))
 	if err != nil {
 		panic(err)
 	}
+	return serverLogger
+}
 
-	// Create the logger to operate logging operations on the server log file
-	serverFileLogger := log.NewLogfmtLogger(serverLogFile)
-	serverFileLogger = level.NewFilter(serverFileLogger, level.AllowInfo())
-	serverFileLogger = log.With(serverFileLogger, "time", log.DefaultTimestampUTC)
-
-	return &ServerLoggingObjectPROD{
-		terminalLogger:   terminalLogger,
-		serverFileLogger: serverFileLogger,
-		serverLogFile:    serverLogFile,
+// Constructor function that creates a development-level logger for logging all server activity,
+//   DEBUG-level and above, on both the terminal/stdout and a file.
+func NewServerLoggingObjectDEV(serverLogFilename string) *ServerLoggingObject {
+	serverLogger, err := NewServerLoggingObject(DefaultServerLogConfig(serverLogFilename, false))
+	if err != nil {
+		panic(err)
 	}
+	return serverLogger
 }
 
-// Method of the ServerLoggingObjectPROD that is used for simultaneously logging
-//   INFO-level activity on both the terminal and server log file.
-func (slop *ServerLoggingObjectPROD) ServerLogInfo(key, value, message string) {
-	level.Info(slop.terminalLogger).Log(key, value, "message", message)
-	level.Info(slop.serverFileLogger).Log(key, value, "message", message)
+// Method of the ServerLoggingObject that is used for logging DEBUG-level activity.
+func (slo *ServerLoggingObject) ServerLogDebug(key, value, message string) {
+	slo.logger.Debug(message, key, value)
 }
 
-// Method of the ServerLoggingObjectPROD that is used for simultaneously logging
-//   WARN-level activity on both the terminal and server log file.
-func (slop *ServerLoggingObjectPROD) ServerLogWarn(key, value, message string) {
-	level.Warn(slop.terminalLogger).Log(key, value, "message", message)
-	level.Warn(slop.serverFileLogger).Log(key, value, "message", message)
+// Method of the ServerLoggingObject that is used for logging INFO-level activity.
+func (slo *ServerLoggingObject) ServerLogInfo(key, value, message string) {
+	slo.logger.Info(message, key, value)
 }
 
-// Method of the ServerLoggingObjectPROD that is used for simultaneouslu logging
-//   ERROR-level activity on both the terminal and server log file.
-func (slop *ServerLoggingObjectPROD) ServerLogError(key, value, message string) {
-	level.Error(slop.terminalLogger).Log(key, value, "error", message)
-	level.Error(slop.serverFileLogger).Log(key, value, "error", message)
+// Method of the ServerLoggingObject that is used for logging WARN-level activity.
+func (slo *ServerLoggingObject) ServerLogWarn(key, value, message string) {
+	slo.logger.Warn(message, key, value)
 }
 
-// Method of the ServerLoggingObjectPROD that is used for closing the server log
-//   file properly when the server is about to shutdown.
-func (slop *ServerLoggingObjectPROD) Close() {
-	slop.serverLogFile.Close()
+// Method of the ServerLoggingObject that is used for logging ERROR-level activity.
+func (slo *ServerLoggingObject) ServerLogError(key, value, message string) {
+	slo.logger.Error(message, key, value)
+}
+
+// Method of the ServerLoggingObject that returns its underlying structured logger, for logging
+//   entries with any number of attributes (e.g., slo.Logger().Info("Opened stream", "stream_id", id,
+//   "key", key)), or changing its level.
+func (slo *ServerLoggingObject) Logger() *logging.Logger {
+	return slo.logger
+}
+
+// Method of the ServerLoggingObject that changes the minimum level of the logged activity ("debug",
+//   "info", "warn" or "error") while the server is running.
+func (slo *ServerLoggingObject) SetLogLevel(name string) error {
+	level, err := logging.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	slo.logger.SetLevel(level)
+	return nil
+}
+
+// Method of the ServerLoggingObject that is used for closing the server log file properly when the
+//   server is about to shutdown.
+func (slo *ServerLoggingObject) Close() {
+	slo.logger.Close()
 }
 
 
 
 //*************************************************************************************************
-// Definition of a development-level server activity logger that logs both on the terminal/stdout
-//   and a file.
-type ServerLoggingObjectDEV struct {
-	// Log only current server session activity on standard output
-	terminalLogger   log.Logger
-	// Log all server sessions' history on a file
-	serverFileLogger log.Logger
-	// Log file that the serverFileLogger operates on.  Stored in this field so that
-	//   it can be properly closed when client app is exited.
-	serverLogFile    *os.File
+// Definition of one activity entry captured by a RecordingServerLogger.  Level is "debug", "info",
+//   "warn" or "error".
+type ServerLogRecord struct {
+	Level   string
+	Key     string
+	Value   string
+	Message string
 }
 
-// Constructor function that creates a production-level logger for logging all server activity,
-//   DEBUG-level and above.
-func NewServerLoggingObjectDEV(serverLogFilename string) *ServerLoggingObjectDEV {
-	// Create terminal logger
-	terminalLogger := log.NewLogfmtLogger(os.Stdout)
-	terminalLogger = level.NewFilter(terminalLogger, level.AllowDebug())
-	terminalLogger = log.With(terminalLogger, "time", log.DefaultTimestampUTC)
-
-	// Add filepath to serverlogs directory to get full server log filepath.
-	//   serverLogFilename = "github.com/astronomical3/fewer_grpc/server/serverlogs/devtest/" + serverLogFilename
-
-	// Create or open the server log file object
-	serverLogFile, err := os.OpenFile(serverLogFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		panic(err)
-	}
+// Definition of the subset of testing.TB used by the assertion helpers of a RecordingServerLogger,
+//   so that the server itself does not depend on the testing package.
+type LogAssertionT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Definition of a server activity logger that captures every entry in memory instead of writing it
+//   anywhere, for tests to inspect.  It is safe for concurrent use, and its entries stay readable
+//   after it is closed.
+type RecordingServerLogger struct {
+	mu      sync.Mutex
+	records []ServerLogRecord
+}
+
+// Constructor function that creates an empty in-memory server activity logger.
+func NewRecordingServerLogger() *RecordingServerLogger {
+	return &RecordingServerLogger{}
+}
 
-	// Create the logger to operate logging operations on the server log file
-	serverFileLogger := log.NewLogfmtLogger(serverLogFile)
-	serverFileLogger = level.NewFilter(serverFileLogger, level.AllowDebug())
-	serverFileLogger = log.With(serverFileLogger, "time", log.DefaultTimestampUTC)
+// Method of the RecordingServerLogger that captures DEBUG-level activity.
+func (rsl *RecordingServerLogger) ServerLogDebug(key, value, message string) {
+	rsl.record("debug", key, value, message)
+}
+
+// Method of the RecordingServerLogger that captures INFO-level activity.
+func (rsl *RecordingServerLogger) ServerLogInfo(key, value, message string) {
+	rsl.record("info", key, value, message)
+}
 
-	return &ServerLoggingObjectDEV{
-		terminalLogger:   terminalLogger,
-		serverFileLogger: serverFileLogger,
-		serverLogFile:    serverLogFile,
+// Method of the RecordingServerLogger that captures WARN-level activity.
+func (rsl *RecordingServerLogger) ServerLogWarn(key, value, message string) {
+	rsl.record("warn", key, value, message)
+}
+
+// Method of the RecordingServerLogger that captures ERROR-level activity.
+func (rsl *RecordingServerLogger) ServerLogError(key, value, message string) {
+	rsl.record("error", key, value, message)
+}
+
+// Method of the RecordingServerLogger that does nothing, as there is no file to close.
+func (rsl *RecordingServerLogger) Close() {}
+
+// Internal method of the RecordingServerLogger that captures one entry.
+func (rsl *RecordingServerLogger) record(level, key, value, message string) {
+	rsl.mu.Lock()
+	rsl.records = append(rsl.records, ServerLogRecord{Level: level, Key: key, Value: value, Message: message})
+	rsl.mu.Unlock()
+}
+
+// Method of the RecordingServerLogger that returns a copy of the entries captured so far.
+func (rsl *RecordingServerLogger) Records() []ServerLogRecord {
+	rsl.mu.Lock()
+	defer rsl.mu.Unlock()
+	return append([]ServerLogRecord(nil), rsl.records...)
+}
+
+// Method of the RecordingServerLogger that returns the captured entries of the given level (any
+//   level, if empty) whose message contains substring.
+func (rsl *RecordingServerLogger) Find(level, substring string) []ServerLogRecord {
+	var found []ServerLogRecord
+	for _, record := range rsl.Records() {
+		if (level == "" || record.Level == level) && strings.Contains(record.Message, substring) {
+			found = append(found, record)
+		}
 	}
+	return found
 }
 
-// Method of the ServerLoggingObjectDEV that is used for simultaneously logging
-//   DEBUG-level activity on both the terminal and server log file.
-// This is an operation only used when using a ServerLoggingObjectDEV for a 
-//   ServerLogger.
-func (slod *ServerLoggingObjectDEV) ServerLogDebug(key, value, message string) {
-	level.Debug(slod.terminalLogger).Log(key, value, "message", message)
-	level.Debug(slod.serverFileLogger).Log(key, value, "message", message)
+// Method of the RecordingServerLogger that reports whether an entry of the given level (any level,
+//   if empty) was captured with a message containing substring.
+func (rsl *RecordingServerLogger) Contains(level, substring string) bool {
+	return len(rsl.Find(level, substring)) > 0
 }
 
-// Method of the ServerLoggingObjectDEV that is used for simultaneously logging
-//   INFO-level activity on both the terminal and server log file.
-func (slod *ServerLoggingObjectDEV) ServerLogInfo(key, value, message string) {
-	level.Info(slod.terminalLogger).Log(key, value, "message", message)
-	level.Info(slod.serverFileLogger).Log(key, value, "message", message)
+// Assertion helper of the RecordingServerLogger that fails the test if no entry of the given level
+//   was captured with a message containing substring.
+func (rsl *RecordingServerLogger) AssertLogged(t LogAssertionT, level, substring string) {
+	t.Helper()
+	if !rsl.Contains(level, substring) {
+		t.Errorf("server logged no %s entry containing %q; captured entries:\n%s", level, substring, rsl.dump())
+	}
 }
 
-// Method of the ServerLoggingObjectDEV that is used for simultaneously logging
-//   WARN-level activity on both the terminal and server log file.
-func (slod *ServerLoggingObjectDEV) ServerLogWarn(key, value, message string) {
-	level.Warn(slod.terminalLogger).Log(key, value, "message", message)
-	level.Warn(slod.serverFileLogger).Log(key, value, "message", message)
+// Assertion helper of the RecordingServerLogger that fails the test if an entry of the given level
+//   was captured with a message containing substring.
+func (rsl *RecordingServerLogger) AssertNotLogged(t LogAssertionT, level, substring string) {
+	t.Helper()
+	if found := rsl.Find(level, substring); len(found) > 0 {
+		t.Errorf("server logged unexpected %s entry containing %q: %+v", level, substring, found[0])
+	}
 }
 
-// Method of the ServerLoggingObjectDEV that is used for simultaneously logging
-//   ERROR-level activity on both the terminal and server log file.
-func (slod *ServerLoggingObjectDEV) ServerLogError(key, value, message string) {
-	level.Error(slod.terminalLogger).Log(key, value, "error", message)
-	level.Error(slod.serverFileLogger).Log(key, value, "error", message)
+// Assertion helper of the RecordingServerLogger that waits up to timeout for an entry of the given
+//   level, with a message containing substring, to be captured, and stops the test if none is.
+//   Used for entries that are logged asynchronously from the test's point of view.
+func (rsl *RecordingServerLogger) WaitForLogged(t LogAssertionT, level, substring string, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !rsl.Contains(level, substring) {
+		if time.Now().After(deadline) {
+			t.Fatalf("server logged no %s entry containing %q within %v; captured entries:\n%s", level, substring, timeout, rsl.dump())
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
 }
 
-// Method of the ServerLoggingObjectDEV that is used for closing the server log
-//   file properly.
-func (slod *ServerLoggingObjectDEV) Close() {
-	slod.serverLogFile.Close()
-}
\ No newline at end of file
+// Internal method of the RecordingServerLogger that formats every captured entry, one per line, for
+//   failure messages.
+func (rsl *RecordingServerLogger) dump() string {
+	var b strings.Builder
+	for _, record := range rsl.Records() {
+		fmt.Fprintf(&b, "  level=%s %s=%s message=%q\n", record.Level, record.Key, record.Value, record.Message)
+	}
+	return b.String()
+}
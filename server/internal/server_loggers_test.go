@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Definition of a LogAssertionT that records failures instead of failing the test, for checking
+//   that the assertion helpers fail when they should.
+type fakeAssertionT struct {
+	errors []string
+	fatals []string
+}
+
+func (f *fakeAssertionT) Helper() {}
+func (f *fakeAssertionT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+func (f *fakeAssertionT) Fatalf(format string, args ...any) {
+	f.fatals = append(f.fatals, fmt.Sprintf(format, args...))
+}
+
+// Test of the RecordingServerLogger capturing entries and matching them by level and message.
+func TestRecordingServerLogger(t *testing.T) {
+	rsl := NewRecordingServerLogger()
+	rsl.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "Received input number 1, sum is now 1")
+	rsl.ServerLogWarn("rpc", "pb.FewerService_GetAggregatesStream", "Leftover data not reported in last returned sum.  Actual final sum is 1.")
+	rsl.Close()
+	rsl.ServerLogError("method", "GeneralFewerServer_Shutdown", "Failed to close aggregate sink: boom")
+
+	records := rsl.Records()
+	if len(records) != 3 {
+		t.Fatalf("captured %d entries, want 3", len(records))
+	}
+	want := ServerLogRecord{Level: "error", Key: "method", Value: "GeneralFewerServer_Shutdown", Message: "Failed to close aggregate sink: boom"}
+	if records[2] != want {
+		t.Errorf("last entry = %+v, want %+v", records[2], want)
+	}
+	if got := len(rsl.Find("", "sum")); got != 2 {
+		t.Errorf("Find any level = %d entries, want 2", got)
+	}
+	if rsl.Contains("info", "Leftover data") {
+		t.Errorf("Contains matched an entry of the wrong level")
+	}
+
+	rsl.AssertLogged(t, "warn", "Leftover data not reported in last returned sum")
+	rsl.AssertNotLogged(t, "warn", "No leftover data")
+	rsl.WaitForLogged(t, "error", "Failed to close", time.Second)
+
+	fake := &fakeAssertionT{}
+	rsl.AssertLogged(fake, "error", "Leftover data")
+	rsl.AssertNotLogged(fake, "info", "Received input number 1")
+	rsl.WaitForLogged(fake, "debug", "never logged", 20*time.Millisecond)
+	if len(fake.errors) != 2 || len(fake.fatals) != 1 {
+		t.Errorf("failing assertions reported %d errors and %d fatals, want 2 and 1", len(fake.errors), len(fake.fatals))
+	}
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// Definition of an option of a GeneralFewerServer, given to its constructors, which adds to the
+//   interceptors, stats handlers or options of its gRPC server.  The built-in features of the
+//   server (panic recovery, authentication, connection logging and stream cancellation) are added
+//   through the same options, ahead of the ones given to the constructors.
+type Option func(*serverOptions)
+
+// Definition of everything the options of a GeneralFewerServer add to its gRPC server.
+type serverOptions struct {
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	statsHandlers      []stats.Handler
+	grpcOptions        []grpc.ServerOption
+}
+
+// Function that returns an option adding unary interceptors to the server.  Interceptors run in
+//   the order they are added, after the built-in ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *serverOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// Function that returns an option adding stream interceptors to the server.  Interceptors run in
+//   the order they are added, after the built-in ones.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *serverOptions) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// Function that returns an option adding stats handlers (e.g., for metrics or tracing) to the
+//   server.
+func WithStatsHandlers(handlers ...stats.Handler) Option {
+	return func(o *serverOptions) {
+		o.statsHandlers = append(o.statsHandlers, handlers...)
+	}
+}
+
+// Function that returns an option adding options to the gRPC server (e.g., grpc.MaxRecvMsgSize()).
+//   Interceptors and stats handlers should be added with the other options instead, so that they
+//   are chained with the built-in ones.  grpc.InTapHandle() cannot be added, as the server sets its
+//   own tap handle, through which streams are cancelled.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *serverOptions) {
+		o.grpcOptions = append(o.grpcOptions, opts...)
+	}
+}
+
+// Helper function that applies the given options, in order, and returns the gRPC server options
+//   they add up to.
+func buildServerOptions(options []Option) []grpc.ServerOption {
+	var o serverOptions
+	for _, option := range options {
+		option(&o)
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(o.unaryInterceptors...),
+		grpc.ChainStreamInterceptor(o.streamInterceptors...),
+	}
+	for _, handler := range o.statsHandlers {
+		opts = append(opts, grpc.StatsHandler(handler))
+	}
+	return append(opts, o.grpcOptions...)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// Definition of a stats handler that counts the calls it sees begin.
+type countingStatsHandler struct {
+	begun atomic.Int64
+}
+
+func (h *countingStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+func (h *countingStatsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if _, ok := s.(*stats.Begin); ok {
+		h.begun.Add(1)
+	}
+}
+func (h *countingStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (h *countingStatsHandler) HandleConn(context.Context, stats.ConnStats)                       {}
+
+// Test that the interceptors and stats handlers given as options see the calls of the server, and
+//   that they run after the built-in interceptors, so that unauthenticated calls never reach them.
+func TestServerOptions(t *testing.T) {
+	var mu sync.Mutex
+	var intercepted []string
+	record := func(fullMethod string) {
+		mu.Lock()
+		defer mu.Unlock()
+		intercepted = append(intercepted, fullMethod)
+	}
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		record(info.FullMethod)
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		record(info.FullMethod)
+		return handler(srv, ss)
+	}
+	handler := &countingStatsHandler{}
+
+	fs, client := newBufconnGeneralFewerServer(t, NewRecordingServerLogger(), loadTestServerConfig(t, map[string]string{"FEWER_AUTH_TOKENS": "s3cret"}),
+		WithUnaryInterceptors(unary),
+		WithStreamInterceptors(stream),
+		WithStatsHandlers(handler),
+	)
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1}}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("unauthenticated AggregateBatch() error = %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), AuthorizationMetadataKey, "Bearer s3cret")
+	if _, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: []int32{1, 2}}); err != nil {
+		t.Fatalf("AggregateBatch() error = %v", err)
+	}
+	upload, err := client.AggregateUpload(ctx)
+	if err != nil {
+		t.Fatalf("AggregateUpload: %v", err)
+	}
+	upload.Send(&pb.NumberRequest{InputNum: 1})
+	if _, err := upload.CloseAndRecv(); err != nil {
+		t.Fatalf("AggregateUpload() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{pb.FewerService_AggregateBatch_FullMethodName, pb.FewerService_AggregateUpload_FullMethodName}
+	if len(intercepted) != len(want) || intercepted[0] != want[0] || intercepted[1] != want[1] {
+		t.Errorf("intercepted calls = %v, want %v", intercepted, want)
+	}
+	if got := handler.begun.Load(); got != 3 {
+		t.Errorf("stats handler saw %d calls begin, want 3", got)
+	}
+}
@@ -1,12 +1,53 @@
 package internal
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Default number of inputs the Fewer Service processes on a stream before it sends back an
+//   InputAck acknowledging them.
+const DefaultAckInterval = 8
+
+// Metadata key through which a client advertises the maximum number of unacknowledged inputs
+//   it will keep in flight on a GetAggregatesStream() stream (see the wire package).
+const MaxInFlightMetadataKey = wire.MaxInFlightMetadataKey
+
+// Metadata keys through which a client can label a GetAggregatesStream() stream with a key and a
+//   tenant.  Every aggregate emitted on the stream carries these labels, and SubscribeAggregates()
+//   subscribers can filter on them.
+const StreamKeyMetadataKey = wire.StreamKeyMetadataKey
+const TenantMetadataKey = wire.TenantMetadataKey
+
+// Metadata key through which a client picks the reducer of an aggregation stream, batch or upload
+//   among the allowed reducers of the server (default SumReducer).
+const ReducerMetadataKey = wire.ReducerMetadataKey
+
+// Trailer metadata key holding the serialized AggregationSummary of a GetAggregatesStream() stream,
+//   set whether the stream ends successfully or not.
+const SummaryTrailerKey = wire.SummaryTrailerKey
+
+// Trailer metadata key holding the reason an aggregation stream was cancelled for through the
+//   FewerAdmin service.
+const CancelReasonTrailerKey = wire.CancelReasonTrailerKey
+
 
 
 //*****************************************************************************************
@@ -16,33 +57,170 @@ import (
 type FewerService struct {
 	pb.UnimplementedFewerServiceServer
 	serverLogger ServerLogger
+	// Number of inputs added together into each aggregate.  Can be changed while streams are open.
+	batchSize    atomic.Int64
+	// Number of inputs processed between two InputAck messages sent back to a client.  Can be
+	//   changed while streams are open.
+	ackInterval  atomic.Int64
+	// Reducers new streams may ask for.  Can be changed while streams are open.
+	reducers     atomic.Pointer[[]string]
+	// Optional sink that every emitted aggregate is recorded to.  A nil sink means aggregates
+	//   are not persisted.
+	sink         AggregateSink
+	// Broadcaster that fans out every emitted aggregate to SubscribeAggregates() subscribers.
+	broadcaster  *aggregateBroadcaster
+	// Limiter enforcing the limits on aggregation streams.
+	limiter      *streamLimiter
+	// Limiter of the rate at which each client may send inputs.
+	rateLimiter  *rateLimiter
 }
 
 // Constructor function for creating a new instance of the FewerService.
 func NewFewerService(serverLogger ServerLogger) *FewerService {
-	return &FewerService{serverLogger: serverLogger}
+	s := &FewerService{
+		serverLogger: serverLogger,
+		broadcaster:  newAggregateBroadcaster(),
+		limiter:      newStreamLimiter(serverLogger),
+		rateLimiter:  newRateLimiter(serverLogger),
+	}
+	s.batchSize.Store(DefaultBatchSize)
+	s.ackInterval.Store(DefaultAckInterval)
+	s.SetAllowedReducers(knownReducers)
+	return s
+}
+
+// Method of the FewerService for changing how many inputs are added together into each aggregate.
+//   Values below 1 are ignored.  Only streams opened afterwards use the new batch size.
+func (s *FewerService) SetBatchSize(batchSize int) {
+	if batchSize >= 1 {
+		s.batchSize.Store(int64(batchSize))
+	}
+}
+
+// Method of the FewerService for changing how many inputs are processed between two InputAck
+//   messages.  Values below 1 are ignored.
+func (s *FewerService) SetAckInterval(ackInterval int) {
+	if ackInterval >= 1 {
+		s.ackInterval.Store(int64(ackInterval))
+	}
+}
+
+// Method of the FewerService for changing which reducers new aggregation streams may ask for.  An
+//   empty list is ignored.  Streams that are already open keep their reducer.
+func (s *FewerService) SetAllowedReducers(reducers []string) {
+	if len(reducers) > 0 {
+		reducers = slices.Clone(reducers)
+		s.reducers.Store(&reducers)
+	}
+}
+
+// Method of the FewerService for setting the limits on its aggregation streams.  Only streams
+//   opened afterwards use the new limits.
+func (s *FewerService) SetStreamLimits(limits StreamLimits) {
+	s.limiter.setLimits(limits)
+}
+
+// Method of the FewerService for setting the rate limits on the inputs of each client.  Applies to
+//   open streams right away.
+func (s *FewerService) SetRateLimits(config RateLimitConfig) {
+	s.rateLimiter.setConfig(config)
+}
+
+// Method of the FewerService for setting the sink that every emitted aggregate is recorded to.
+func (s *FewerService) SetAggregateSink(sink AggregateSink) {
+	s.sink = sink
 }
 
-// Implementation of the GetAggregatesStream() RPC, which takes in NumberRequest messages,
-//   adds 3 of them together, and every 3rd NumberRequest send, returns a sum (aggregate)
-//   of the last 3 numbers in a NumberResponse.  Of course, this will be a very simple, 
-//   incremental batch processing operation.
+// Method of the FewerService that ends every SubscribeAggregates() subscription, so that a
+//   graceful stop of the server does not wait on them forever.
+func (s *FewerService) CloseSubscriptions() {
+	s.broadcaster.close()
+}
+
+// Internal method of the FewerService that is called for every aggregate sent back to a client.
+//   The aggregate is recorded to the sink, if there is one, and published to the matching
+//   SubscribeAggregates() subscribers.  Failing to record an aggregate is logged, but does not
+//   fail the stream.
+func (s *FewerService) aggregateEmitted(record AggregateRecord) {
+	if s.sink != nil {
+		if err := s.sink.RecordAggregate(record); err != nil {
+			s.serverLogger.ServerLogWarn(
+				"rpc",
+				"pb.FewerService_GetAggregatesStream",
+				fmt.Sprintf("Could not record aggregate %d of stream %s to aggregate sink: %v", record.Window.Index, record.StreamID, err),
+			)
+		}
+	}
+	s.broadcaster.publish(&pb.AggregateEvent{
+		StreamId:  record.StreamID,
+		Key:       record.Key,
+		Tenant:    record.Tenant,
+		Aggregate: newNumberResponse(record),
+		Partial:   record.Partial,
+		EmittedAt: timestamppb.New(record.EmittedAt),
+	})
+}
+
+// Internal method of the FewerService that works out the acknowledgement interval to use on a
+//   stream.  If the client advertised a max-in-flight window smaller than twice the configured
+//   interval, the service acknowledges at least every half window, so that a client waiting on
+//   a full window always gets an InputAck back.
+func (s *FewerService) streamAckInterval(stream pb.FewerService_GetAggregatesStreamServer) int {
+	ackInterval := int(s.ackInterval.Load())
+	value := incomingMetadataValue(stream.Context(), MaxInFlightMetadataKey)
+	if value == "" {
+		return ackInterval
+	}
+	maxInFlight, err := strconv.Atoi(value)
+	if err != nil || maxInFlight < 1 {
+		s.serverLogger.ServerLogWarn(
+			"rpc",
+			"pb.FewerService_GetAggregatesStream",
+			fmt.Sprintf("Ignoring invalid %s metadata value %q", MaxInFlightMetadataKey, value),
+		)
+		return ackInterval
+	}
+	if halfWindow := (maxInFlight + 1) / 2; halfWindow < ackInterval {
+		ackInterval = halfWindow
+	}
+	return ackInterval
+}
+
+// Implementation of the GetAggregatesStream() RPC, which takes in the numbers of NumberRequest
+//   messages, one by one or packed, in batches of the configured batch size, and every time a batch
+//   fills up, returns its aggregate in a NumberResponse.  The aggregate is the sum, the smallest or
+//   the largest of the batch's numbers, as the reducer of the stream asks.  Of course, this will
+//   be a very simple, incremental batch processing operation.
 // This is an operation being used for testing whether or not it is possible to have the
 //   server return back to clients FEWER responses than it receives requests (hence the 
 //   name "Fewer Service").  If this operation is successful, it can be assumed that 
 //   bidirectional streaming RPCs are useful for different types of batch processing.
 func (s *FewerService) GetAggregatesStream(stream pb.FewerService_GetAggregatesStreamServer) error {
 	s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~STARTING RPC OPERATION NOW~~~~~~~~~~~")
-	i := 0
-	sum := int32(0)
+	limited, err := s.limiter.openStream(stream.Context(), "pb.FewerService_GetAggregatesStream")
+	if err != nil {
+		s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+		return err
+	}
+	defer limited.close()
+	ackInterval := s.streamAckInterval(stream)
+	agg, err := s.newStreamAggregator(stream.Context(), "pb.FewerService_GetAggregatesStream")
+	if err != nil {
+		s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+		return err
+	}
+	limited.attach(agg)
+	defer s.setSummaryTrailer(stream, agg)
+	s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", fmt.Sprintf("Opened stream %s (key %q, tenant %q)", agg.streamID, agg.key, agg.tenant))
 	for {
-		// Try to receive a new NumberRequest, req, through the stream.
-		req, err := stream.Recv()
+		// Try to receive a new NumberRequest, req, through the stream, within the limits on how
+		//   long the stream may stay idle or open.
+		req, err := recvWithinLimits(limited, stream.Recv)
 		// If final request was already received from client...
 		if err == io.EOF {
-			if i % 3 != 0 {
+			if record, ok := agg.flush(); ok {
 				// Log final "leftover sum" into server log and return that sum to client if
-				//   number of lefotver number requests is not 3.
+				//   number of lefotver number requests is not a full batch.
 				// Maybe this could be considered a "partial" operation, and could set off
 				//   a warning.  We will simulate such a situation here...
 				s.serverLogger.ServerLogWarn(
@@ -50,10 +228,21 @@ func (s *FewerService) GetAggregatesStream(stream pb.FewerService_GetAggregatesS
 					"pb.FewerService_GetAggregatesStream",
 					fmt.Sprintf(
 						"Leftover data not reported in last returned sum.  Actual final sum is %d.  Returning residual sum back to client...",
-						sum,
+						record.Value,
 					),
 				)
-				stream.Send(&pb.NumberResponse{Result: sum})
+				if err := stream.Send(newAggregateResponse(record)); err != nil {
+					// The client never received the residual sum, so the summary must not count it.
+					agg.retract(record)
+					s.serverLogger.ServerLogError(
+						"rpc",
+						"pb.FewerService_GetAggregatesStream",
+						fmt.Sprintf("Could not send residual sum %d to client", record.Value),
+					)
+					s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+					return streamError(err)
+				}
+				s.aggregateEmitted(record)
 			} else {
 				s.serverLogger.ServerLogInfo(
 					"rpc",
@@ -61,8 +250,12 @@ func (s *FewerService) GetAggregatesStream(stream pb.FewerService_GetAggregatesS
 					"No leftover data after final sum.  Last sum returned is actual final sum.",
 				)
 			}
+			// End the stream with its summary, which lets the client check that no message was lost.
+			summary := agg.summary()
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", fmt.Sprintf("Returning stream summary: %v", summary))
+			err := stream.Send(&pb.AggregatesStreamResponse{Payload: &pb.AggregatesStreamResponse_Summary{Summary: summary}})
 			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
-			return nil
+			return streamError(err)
 		}
 
 		// If receive error is some other non-nil error...
@@ -70,40 +263,459 @@ func (s *FewerService) GetAggregatesStream(stream pb.FewerService_GetAggregatesS
 			s.serverLogger.ServerLogError(
 				"rpc",
 				"pb.FewerService_GetAggregatesStream",
-				fmt.Sprintf("Could not receive latest request at iteration %d: %v", (i + 1), err),
+				fmt.Sprintf("Could not receive latest request at iteration %d: %v", (agg.inputs + 1), err),
 			)
 			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
 			return err
 		}
 
 		// If no receive error was received, or it is not the end of the stream of messages from the
-		//   client...
-		i++
-		sum += req.InputNum
-		s.serverLogger.ServerLogInfo(
-			"rpc",
-			"pb.FewerService_GetAggregatesStream",
-			fmt.Sprintf("Received input number %d, sum is now %d", req.InputNum, sum),
-		)
-		if i % 3 == 0 {
-			// Every 3 requests the service receives, it returns back the sum of those last 3 numbers
-			//   received, and resets the sum back to 0.
-			// If there is an error during the send, though, error is returned through gRPC runtime.
+		//   client, aggregate every number the request carries, in order, unless they would take the
+		//   stream over its maximum number of inputs.  Numbers over the rate limit of the client are
+		//   held back (or rejected) first.
+		inputNums := requestInputNums(req)
+		if err := limited.checkInputs(agg.inputs + int64(len(inputNums))); err != nil {
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+			return err
+		}
+		if err := s.rateLimiter.wait(stream.Context(), "pb.FewerService_GetAggregatesStream", len(inputNums)); err != nil {
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+			return err
+		}
+		inputsBefore := agg.inputs
+		for i, inputNum := range inputNums {
+			record, full, violation := agg.add(inputNum)
+			if err := s.checkViolation(agg, "pb.FewerService_GetAggregatesStream", requestInputField(req, i), violation); err != nil {
+				s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+				return err
+			}
+			sum := agg.currentValue()
+			if full {
+				sum = int32(record.Value)
+			}
 			s.serverLogger.ServerLogInfo(
 				"rpc",
 				"pb.FewerService_GetAggregatesStream",
-				"3 input numbers have been added, sending back sum to client...",
+				fmt.Sprintf("Received input number %d, %s is now %d", inputNum, agg.reducer, sum),
 			)
-			if err := stream.Send(&pb.NumberResponse{Result: sum}); err != nil {
+			if full {
+				// Every full batch of numbers the service receives, it returns back the aggregate of
+				//   those last numbers received, and the aggregator starts a new batch.
+				// If there is an error during the send, though, error is returned through gRPC runtime.
+				s.serverLogger.ServerLogInfo(
+					"rpc",
+					"pb.FewerService_GetAggregatesStream",
+					fmt.Sprintf("%d input numbers have been added, sending back sum to client...", agg.batchSize),
+				)
+				if err := stream.Send(newAggregateResponse(record)); err != nil {
+					agg.retract(record)
+					s.serverLogger.ServerLogError(
+						"rpc",
+						"pb.FewerService_GetAggregatesStream",
+						fmt.Sprintf("Could not send latest sum %d to client", sum),
+					)
+					s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+					return streamError(err)
+				}
+				s.aggregateEmitted(record)
+			}
+		}
+
+		// Every ackInterval requests the service receives, it acknowledges the inputs it has
+		//   processed so far, letting the client send more of them.  A packed request may carry
+		//   the stream across several intervals at once, in which case a single ack covers them.
+		if agg.inputs / int64(ackInterval) != inputsBefore / int64(ackInterval) {
+			if err := stream.Send(newAckResponse(agg.inputs)); err != nil {
 				s.serverLogger.ServerLogError(
 					"rpc",
 					"pb.FewerService_GetAggregatesStream",
-					fmt.Sprintf("Could not send latest sum %d to client", sum),
+					fmt.Sprintf("Could not acknowledge %d processed inputs to client", agg.inputs),
 				)
 				s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_GetAggregatesStream", "~~~~~~~~~~~~END OF RPC OPERATION~~~~~~~~~~~")
+				return streamError(err)
+			}
+		}
+	}
+}
+
+// Implementation of the AggregateBatch() RPC, for clients that already have all of their numbers
+//   at once.  The numbers go through the same aggregation as on a GetAggregatesStream() stream,
+//   and every aggregate is returned in a single response, along with a summary of the batch.
+func (s *FewerService) AggregateBatch(ctx context.Context, req *pb.AggregateBatchRequest) (*pb.AggregateBatchResponse, error) {
+	if err := s.limiter.checkDraining(ctx, "pb.FewerService_AggregateBatch"); err != nil {
+		return nil, err
+	}
+	if err := s.limiter.checkBatchInputs(ctx, "pb.FewerService_AggregateBatch", len(req.InputNums)); err != nil {
+		return nil, err
+	}
+	if err := s.rateLimiter.wait(ctx, "pb.FewerService_AggregateBatch", len(req.InputNums)); err != nil {
+		return nil, err
+	}
+	agg, err := s.newStreamAggregator(ctx, "pb.FewerService_AggregateBatch")
+	if err != nil {
+		return nil, err
+	}
+	s.serverLogger.ServerLogInfo(
+		"rpc",
+		"pb.FewerService_AggregateBatch",
+		fmt.Sprintf("Aggregating batch %s of %d input numbers (key %q, tenant %q)", agg.streamID, len(req.InputNums), agg.key, agg.tenant),
+	)
+
+	resp := &pb.AggregateBatchResponse{}
+	for i, inputNum := range req.InputNums {
+		record, full, violation := agg.add(inputNum)
+		if err := s.checkViolation(agg, "pb.FewerService_AggregateBatch", fmt.Sprintf("input_nums[%d]", i), violation); err != nil {
+			return nil, err
+		}
+		if full {
+			resp.Results = append(resp.Results, newNumberResponse(record))
+			s.aggregateEmitted(record)
+		}
+	}
+	if record, ok := agg.flush(); ok {
+		s.serverLogger.ServerLogWarn(
+			"rpc",
+			"pb.FewerService_AggregateBatch",
+			fmt.Sprintf("Batch did not end on a full set of numbers, last aggregate is residual sum %d", record.Value),
+		)
+		resp.Results = append(resp.Results, newNumberResponse(record))
+		s.aggregateEmitted(record)
+	}
+	resp.Summary = agg.summary()
+
+	s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_AggregateBatch", fmt.Sprintf("Returning %d aggregates", len(resp.Results)))
+	return resp, nil
+}
+
+// Implementation of the AggregateUpload() RPC, which takes in a stream of NumberRequest messages
+//   and aggregates them the same way as GetAggregatesStream(), but only returns a single summary
+//   once the client has sent all of its numbers.
+func (s *FewerService) AggregateUpload(stream grpc.ClientStreamingServer[pb.NumberRequest, pb.AggregationSummary]) error {
+	limited, err := s.limiter.openStream(stream.Context(), "pb.FewerService_AggregateUpload")
+	if err != nil {
+		return err
+	}
+	defer limited.close()
+	agg, err := s.newStreamAggregator(stream.Context(), "pb.FewerService_AggregateUpload")
+	if err != nil {
+		return err
+	}
+	limited.attach(agg)
+	s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_AggregateUpload", fmt.Sprintf("Opened upload %s (key %q, tenant %q)", agg.streamID, agg.key, agg.tenant))
+	for {
+		req, err := recvWithinLimits(limited, stream.Recv)
+		if err == io.EOF {
+			if record, ok := agg.flush(); ok {
+				s.serverLogger.ServerLogWarn(
+					"rpc",
+					"pb.FewerService_AggregateUpload",
+					fmt.Sprintf("Upload did not end on a full set of numbers, last aggregate is residual sum %d", record.Value),
+				)
+				s.aggregateEmitted(record)
+			}
+			summary := agg.summary()
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_AggregateUpload", fmt.Sprintf("Returning upload summary: %v", summary))
+			return streamError(stream.SendAndClose(summary))
+		}
+		if err != nil {
+			s.serverLogger.ServerLogError(
+				"rpc",
+				"pb.FewerService_AggregateUpload",
+				fmt.Sprintf("Could not receive latest request at iteration %d: %v", (agg.inputs + 1), err),
+			)
+			return err
+		}
+		inputNums := requestInputNums(req)
+		if err := limited.checkInputs(agg.inputs + int64(len(inputNums))); err != nil {
+			return err
+		}
+		if err := s.rateLimiter.wait(stream.Context(), "pb.FewerService_AggregateUpload", len(inputNums)); err != nil {
+			return err
+		}
+		for i, inputNum := range inputNums {
+			record, full, violation := agg.add(inputNum)
+			if err := s.checkViolation(agg, "pb.FewerService_AggregateUpload", requestInputField(req, i), violation); err != nil {
 				return err
 			}
-			sum = int32(0)
+			if full {
+				s.aggregateEmitted(record)
+			}
+		}
+	}
+}
+
+// Internal method of the FewerService that sets the summary of a GetAggregatesStream() stream as its
+//   trailer, so that the client learns how far the stream got even if it failed.
+func (s *FewerService) setSummaryTrailer(stream pb.FewerService_GetAggregatesStreamServer, agg *aggregator) {
+	trailer, err := summaryTrailer(agg)
+	if err != nil {
+		s.serverLogger.ServerLogWarn("rpc", "pb.FewerService_GetAggregatesStream", fmt.Sprintf("Could not encode summary trailer of stream %s: %v", agg.streamID, err))
+		return
+	}
+	stream.SetTrailer(trailer)
+}
+
+// Helper function that returns the trailer metadata holding the summary of a stream.
+func summaryTrailer(agg *aggregator) (metadata.MD, error) {
+	value, err := proto.Marshal(agg.summary())
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(SummaryTrailerKey, string(value)), nil
+}
+
+// Internal method of the FewerService that creates the aggregator for a new stream, batch or
+//   upload of the given method, labelled with the key and tenant, reducing inputs with the reducer,
+//   and validating them against the validation rules, sent in the metadata of the call.  A reducer
+//   that is not allowed, and invalid validation rules, are logged and returned as InvalidArgument
+//   status errors.
+func (s *FewerService) newStreamAggregator(ctx context.Context, method string) (*aggregator, error) {
+	reducer, err := s.streamReducer(ctx)
+	if err == nil {
+		var rules validationRules
+		if rules, err = validationRulesFromContext(ctx); err == nil {
+			return newAggregator(
+				newStreamID(),
+				incomingMetadataValue(ctx, StreamKeyMetadataKey),
+				incomingMetadataValue(ctx, TenantMetadataKey),
+				reducer,
+				int(s.batchSize.Load()),
+				rules,
+			), nil
+		}
+	}
+	s.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Rejecting call with %v", status.Convert(err).Message()))
+	return nil, err
+}
+
+// Internal method of the FewerService that returns the reducer a call asks for in its metadata
+//   (SumReducer if it asks for none), or an InvalidArgument status error if the reducer is unknown
+//   or not among the allowed reducers of the server.
+func (s *FewerService) streamReducer(ctx context.Context) (string, error) {
+	reducer := incomingMetadataValue(ctx, ReducerMetadataKey)
+	if reducer == "" {
+		reducer = SumReducer
+	}
+	allowed := *s.reducers.Load()
+	if slices.Contains(allowed, reducer) {
+		return reducer, nil
+	}
+	description := fmt.Sprintf("reducer %q is not allowed (expected %s)", reducer, strings.Join(allowed, ", "))
+	return "", statusError(
+		codes.InvalidArgument,
+		pb.ErrorReason_REDUCER_NOT_ALLOWED,
+		description,
+		map[string]string{"reducer": reducer, "allowed_reducers": strings.Join(allowed, ",")},
+		badRequest(ReducerMetadataKey, description),
+	)
+}
+
+// Internal method of the FewerService that handles an input of a call of the given method that
+//   broke the validation rules of the call, as reported by the add() method of its aggregator.
+//   Skipped and clamped inputs are logged.  A rejected input, which the aggregator did not add, is
+//   logged as a warning and returned as an InvalidArgument status error ending the call.
+func (s *FewerService) checkViolation(agg *aggregator, method, field string, violation string) error {
+	switch {
+	case violation == "":
+		return nil
+	case agg.rules.Policy == InvalidInputFail:
+		s.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Ending call %s, as input %d is invalid: %s", agg.streamID, agg.inputs+1, violation))
+		return invalidInputError(field, agg.inputs+1, violation)
+	}
+	s.serverLogger.ServerLogInfo("rpc", method, fmt.Sprintf("Input %d of call %s is invalid (%s), and was handled by the %s policy", agg.inputs, agg.streamID, violation, agg.rules.Policy))
+	return nil
+}
+
+// Default and maximum number of aggregates per page streamed back by the QueryAggregates() RPC.
+const defaultQueryPageSize = 100
+const maxQueryPageSize = 1000
+
+// Implementation of the QueryAggregates() RPC, which reads back aggregates persisted by the
+//   server's aggregate store, and streams them back to the client page by page.  Each page carries
+//   the token to resume right after it, so a client can stop reading and continue later with a new
+//   QueryAggregates() call.
+func (s *FewerService) QueryAggregates(req *pb.QueryAggregatesRequest, stream grpc.ServerStreamingServer[pb.QueryAggregatesResponse]) error {
+	store, ok := s.sink.(AggregateStore)
+	if !ok {
+		s.serverLogger.ServerLogWarn("rpc", "pb.FewerService_QueryAggregates", "Rejecting query, as no queryable aggregate store is configured")
+		return statusError(codes.FailedPrecondition, pb.ErrorReason_AGGREGATES_NOT_PERSISTED, "aggregates are not persisted by this server", nil)
+	}
+
+	query := AggregateQuery{
+		StreamID:  req.StreamId,
+		Key:       req.Key,
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+	}
+	if req.EmittedAfter != nil {
+		query.EmittedAfter = req.EmittedAfter.AsTime()
+	}
+	if req.EmittedBefore != nil {
+		query.EmittedBefore = req.EmittedBefore.AsTime()
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = defaultQueryPageSize
+	} else if query.PageSize > maxQueryPageSize {
+		query.PageSize = maxQueryPageSize
+	}
+	s.serverLogger.ServerLogInfo(
+		"rpc",
+		"pb.FewerService_QueryAggregates",
+		fmt.Sprintf("Querying aggregates (stream %q, key %q, page size %d, limit %d)", query.StreamID, query.Key, query.PageSize, req.Limit),
+	)
+
+	sent := 0
+	for {
+		// Do not read a bigger page than what is left under the limit.
+		if req.Limit > 0 && int(req.Limit)-sent < query.PageSize {
+			query.PageSize = int(req.Limit) - sent
+		}
+		records, nextPageToken, err := store.QueryAggregates(query)
+		if errors.Is(err, ErrInvalidPageToken) {
+			s.serverLogger.ServerLogWarn("rpc", "pb.FewerService_QueryAggregates", fmt.Sprintf("Rejecting query: %v", err))
+			return statusError(codes.InvalidArgument, pb.ErrorReason_INVALID_PAGE_TOKEN, err.Error(), nil, badRequest("page_token", "not a page token returned by a previous query"))
+		}
+		if err != nil {
+			s.serverLogger.ServerLogError("rpc", "pb.FewerService_QueryAggregates", fmt.Sprintf("Could not query aggregate store: %v", err))
+			return statusError(codes.Internal, pb.ErrorReason_STORE_FAILURE, fmt.Sprintf("could not query aggregates: %v", err), nil)
+		}
+
+		resp := &pb.QueryAggregatesResponse{NextPageToken: nextPageToken}
+		for _, record := range records {
+			resp.Aggregates = append(resp.Aggregates, storedAggregateFromRecord(record))
+		}
+		if err := stream.Send(resp); err != nil {
+			s.serverLogger.ServerLogError("rpc", "pb.FewerService_QueryAggregates", fmt.Sprintf("Could not send page of aggregates to client: %v", err))
+			return streamError(err)
+		}
+		sent += len(records)
+
+		if nextPageToken == "" || (req.Limit > 0 && sent >= int(req.Limit)) {
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_QueryAggregates", fmt.Sprintf("Returned %d aggregates", sent))
+			return nil
+		}
+		query.PageToken = nextPageToken
+	}
+}
+
+// Helper function that converts a persisted aggregate record into its protobuf form.
+func storedAggregateFromRecord(record AggregateRecord) *pb.StoredAggregate {
+	return &pb.StoredAggregate{
+		StreamId:        record.StreamID,
+		Key:             record.Key,
+		Tenant:          record.Tenant,
+		WindowIndex:     record.Window.Index,
+		FirstInput:      record.Window.FirstInput,
+		LastInput:       record.Window.LastInput,
+		Reducer:         record.Reducer,
+		Value:           record.Value,
+		Partial:         record.Partial,
+		StreamStartedAt: timestamppb.New(record.StreamStartedAt),
+		WindowStartedAt: timestamppb.New(record.WindowStartedAt),
+		EmittedAt:       timestamppb.New(record.EmittedAt),
+		SkippedInputs:   record.Window.SkippedInputs,
+		ClampedInputs:   record.Window.ClampedInputs,
+	}
+}
+
+// Implementation of the SubscribeAggregates() RPC, which streams back to the client every aggregate
+//   that the Fewer Service emits on matching GetAggregatesStream() streams, until the client
+//   cancels the subscription.  Each subscriber gets its own bounded buffer of events; when it is
+//   full, the events are either dropped, or the subscriber is disconnected, according to the
+//   slow consumer policy of the request.
+func (s *FewerService) SubscribeAggregates(req *pb.SubscribeAggregatesRequest, stream grpc.ServerStreamingServer[pb.AggregateEvent]) error {
+	sub, unsubscribe := s.broadcaster.subscribe(req)
+	defer unsubscribe()
+	s.serverLogger.ServerLogInfo(
+		"rpc",
+		"pb.FewerService_SubscribeAggregates",
+		fmt.Sprintf(
+			"New subscriber (key %q, tenant %q, buffer %d, policy %s), %d subscribers now registered",
+			req.Key, req.Tenant, cap(sub.events), req.SlowConsumerPolicy, s.broadcaster.subscriberCount(),
+		),
+	)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_SubscribeAggregates", "Subscriber cancelled subscription")
+			return nil
+		case <-s.broadcaster.closed:
+			s.serverLogger.ServerLogInfo("rpc", "pb.FewerService_SubscribeAggregates", "Ending subscription, as the server is shutting down")
+			return statusError(codes.Unavailable, pb.ErrorReason_SHUTTING_DOWN, "server is shutting down", nil)
+		case <-sub.slow:
+			s.serverLogger.ServerLogWarn("rpc", "pb.FewerService_SubscribeAggregates", "Disconnecting subscriber, as its buffer of undelivered events is full")
+			return statusError(codes.ResourceExhausted, pb.ErrorReason_SLOW_SUBSCRIBER, "subscriber is too slow to keep up with aggregate events", nil)
+		case event := <-sub.events:
+			event = sub.prepare(event)
+			if event.DroppedBefore > 0 {
+				s.serverLogger.ServerLogWarn(
+					"rpc",
+					"pb.FewerService_SubscribeAggregates",
+					fmt.Sprintf("Dropped %d events for slow subscriber", event.DroppedBefore),
+				)
+			}
+			if err := stream.Send(event); err != nil {
+				s.serverLogger.ServerLogError("rpc", "pb.FewerService_SubscribeAggregates", fmt.Sprintf("Could not send event to subscriber: %v", err))
+				return streamError(err)
+			}
 		}
 	}
+}
+
+// Helper function that returns the numbers carried by a NumberRequest: the packed input_nums if
+//   there are any, and the single input_num otherwise.
+func requestInputNums(req *pb.NumberRequest) []int32 {
+	if len(req.InputNums) > 0 {
+		return req.InputNums
+	}
+	return []int32{req.InputNum}
+}
+
+// Helper function that returns the first value of an incoming metadata key of a stream context,
+//   or an empty string if the key was not sent.
+func incomingMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Helper function that generates a random ID for a new GetAggregatesStream() stream.
+func newStreamID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Helper function that converts the record of an emitted aggregate into a NumberResponse.
+func newNumberResponse(record AggregateRecord) *pb.NumberResponse {
+	return &pb.NumberResponse{
+		Result:        int32(record.Value),
+		WindowIndex:   record.Window.Index,
+		FirstInput:    record.Window.FirstInput,
+		LastInput:     record.Window.LastInput,
+		SkippedInputs: record.Window.SkippedInputs,
+		ClampedInputs: record.Window.ClampedInputs,
+	}
+}
+
+// Helper function that wraps the record of an emitted aggregate into an AggregatesStreamResponse.
+func newAggregateResponse(record AggregateRecord) *pb.AggregatesStreamResponse {
+	return &pb.AggregatesStreamResponse{
+		Payload: &pb.AggregatesStreamResponse_Aggregate{Aggregate: newNumberResponse(record)},
+	}
+}
+
+// Helper function that wraps an acknowledgement of processed inputs into an
+//   AggregatesStreamResponse.
+func newAckResponse(processedInputs int64) *pb.AggregatesStreamResponse {
+	return &pb.AggregatesStreamResponse{
+		Payload: &pb.AggregatesStreamResponse_Ack{Ack: &pb.InputAck{ProcessedInputs: processedInputs}},
+	}
 }
\ No newline at end of file
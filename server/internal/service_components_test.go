@@ -0,0 +1,443 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// Definition of a ServerLogger that discards everything, so that benchmarks measure the service
+//   rather than the terminal.
+type discardServerLogger struct{}
+
+func (discardServerLogger) ServerLogDebug(key, value, message string) {}
+func (discardServerLogger) ServerLogInfo(key, value, message string)  {}
+func (discardServerLogger) ServerLogWarn(key, value, message string)  {}
+func (discardServerLogger) ServerLogError(key, value, message string) {}
+func (discardServerLogger) Close()                                    {}
+
+// Helper function that serves a FewerService over an in-memory bufconn listener, and returns a
+//   client stub connected to it.
+func newBufconnFewerClient(tb testing.TB) pb.FewerServiceClient {
+	return newBufconnFewerClientWithLogger(tb, discardServerLogger{})
+}
+
+// Helper function that serves a FewerService logging to serverLogger over an in-memory bufconn
+//   listener, and returns a client stub connected to it.
+func newBufconnFewerClientWithLogger(tb testing.TB, serverLogger ServerLogger) pb.FewerServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterFewerServiceServer(grpcServer, NewFewerService(serverLogger))
+	go grpcServer.Serve(lis)
+	tb.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		tb.Fatalf("grpc.NewClient: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	return pb.NewFewerServiceClient(conn)
+}
+
+// Test of how GetAggregatesStream() logs the end of a stream, depending on whether its inputs ended
+//   on a full batch.
+func TestGetAggregatesStreamLogsLeftover(t *testing.T) {
+	tests := []struct {
+		name         string
+		totalInputs  int
+		wantLeftover bool
+		wantMessage  string
+	}{
+		{name: "full batches", totalInputs: 6, wantLeftover: false, wantMessage: "No leftover data after final sum."},
+		{name: "residual batch", totalInputs: 7, wantLeftover: true, wantMessage: "Leftover data not reported in last returned sum.  Actual final sum is 7."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverLogger := NewRecordingServerLogger()
+			stream, err := newBufconnFewerClientWithLogger(t, serverLogger).GetAggregatesStream(context.Background())
+			if err != nil {
+				t.Fatalf("GetAggregatesStream: %v", err)
+			}
+			for i := 1; i <= tt.totalInputs; i++ {
+				if err := stream.Send(&pb.NumberRequest{InputNum: int32(i)}); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			stream.CloseSend()
+			for {
+				if _, err := stream.Recv(); err != nil {
+					if err != io.EOF {
+						t.Fatalf("Recv: %v", err)
+					}
+					break
+				}
+			}
+
+			if tt.wantLeftover {
+				serverLogger.AssertLogged(t, "warn", tt.wantMessage)
+			} else {
+				serverLogger.AssertLogged(t, "info", tt.wantMessage)
+				serverLogger.AssertNotLogged(t, "warn", "Leftover data not reported in last returned sum")
+			}
+			serverLogger.AssertLogged(t, "info", "END OF RPC OPERATION")
+		})
+	}
+}
+
+// Helper function that decodes the summary trailer of a GetAggregatesStream() stream.
+func summaryTrailerOf(t *testing.T, stream pb.FewerService_GetAggregatesStreamClient) *pb.AggregationSummary {
+	t.Helper()
+	values := stream.Trailer().Get(SummaryTrailerKey)
+	if len(values) != 1 {
+		t.Fatalf("stream trailer %v has no %s value", stream.Trailer(), SummaryTrailerKey)
+	}
+	summary := &pb.AggregationSummary{}
+	if err := proto.Unmarshal([]byte(values[0]), summary); err != nil {
+		t.Fatalf("could not decode summary trailer: %v", err)
+	}
+	return summary
+}
+
+// Test that a GetAggregatesStream() stream ends with a summary message, and carries the same
+//   summary in its trailer, which is also set when the stream fails.
+func TestGetAggregatesStreamSummary(t *testing.T) {
+	client := newBufconnFewerClient(t)
+
+	stream, err := client.GetAggregatesStream(context.Background())
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{4, 1, 7, 2, 5, 3, 6}})
+	stream.CloseSend()
+	var last *pb.AggregatesStreamResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		last = resp
+	}
+	summary := last.GetSummary()
+	if summary == nil {
+		t.Fatalf("last message of the stream = %v, want its summary", last)
+	}
+	if summary.TotalInputs != 7 || summary.TotalBatches != 3 || summary.GrandTotal != 28 || !summary.PartialLastBatch ||
+		summary.MinInput != 1 || summary.MaxInput != 7 || summary.Duration.AsDuration() <= 0 {
+		t.Errorf("stream summary = %v, want 7 inputs in 3 batches totalling 28, a partial last batch, inputs from 1 to 7 and a duration", summary)
+	}
+	if trailer := summaryTrailerOf(t, stream); !proto.Equal(trailer, summary) {
+		t.Errorf("summary trailer = %v, want the summary message %v", trailer, summary)
+	}
+
+	// A stream failing on an invalid input still reports how far it got.
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MaxInputMetadataKey, "10")
+	stream, err = client.GetAggregatesStream(ctx)
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3, 4, 50}})
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if trailer := summaryTrailerOf(t, stream); trailer.TotalInputs != 4 || trailer.TotalBatches != 1 || trailer.GrandTotal != 6 {
+		t.Errorf("summary trailer of failed stream = %v, want 4 inputs and 1 batch totalling 6", trailer)
+	}
+}
+
+// Definition of a server stream whose sends of aggregates fail after the first failAfter of them, as
+//   if the connection to the client was lost.
+type aggregateFailingStream struct {
+	grpc.ServerStream
+	failAfter int
+	sent      int
+}
+
+func (s *aggregateFailingStream) SendMsg(m any) error {
+	if resp, ok := m.(*pb.AggregatesStreamResponse); ok && resp.GetAggregate() != nil {
+		if s.sent++; s.sent > s.failAfter {
+			return status.Error(codes.Unavailable, "connection to client lost")
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// Test that an aggregate that could not be sent to the client, whether from a full or a residual
+//   batch, is left out of the summary trailer of the stream, and that the send error ends the stream.
+func TestGetAggregatesStreamSendFailure(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		failAfter  int
+		wantLogged string
+	}{
+		{"full batch", 0, "Could not send latest sum 6 to client"},
+		{"residual batch", 1, "Could not send residual sum 4 to client"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				return handler(srv, &aggregateFailingStream{ServerStream: ss, failAfter: tt.failAfter})
+			}
+			serverLogger := NewRecordingServerLogger()
+			fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil), WithStreamInterceptors(failing))
+			go fs.Serve()
+			defer fs.Shutdown()
+
+			stream, err := client.GetAggregatesStream(context.Background())
+			if err != nil {
+				t.Fatalf("GetAggregatesStream: %v", err)
+			}
+			stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3, 4}})
+			stream.CloseSend()
+			var aggregates int
+			for err == nil {
+				var resp *pb.AggregatesStreamResponse
+				if resp, err = stream.Recv(); resp.GetAggregate() != nil {
+					aggregates++
+				}
+			}
+			if status.Code(err) != codes.Unavailable || aggregates != tt.failAfter {
+				t.Errorf("stream received %d aggregates and ended with %v, want %d aggregates and Unavailable", aggregates, err, tt.failAfter)
+			}
+			trailer := summaryTrailerOf(t, stream)
+			if trailer.TotalBatches != int64(tt.failAfter) || trailer.GrandTotal != int64(6*tt.failAfter) || trailer.PartialLastBatch {
+				t.Errorf("summary trailer = %v, want only the %d aggregates the client received", trailer, tt.failAfter)
+			}
+			serverLogger.AssertLogged(t, "error", tt.wantLogged)
+		})
+	}
+}
+
+// Test of the reducers a client can ask for, applied to every batch of its inputs.
+func TestReducers(t *testing.T) {
+	client := newBufconnFewerClient(t)
+
+	inputNums := []int32{4, 1, 7, 2, 5, 3, 6}
+	tests := []struct {
+		reducer string
+		want    []int32
+	}{
+		{"", []int32{12, 10, 6}},
+		{SumReducer, []int32{12, 10, 6}},
+		{MinReducer, []int32{1, 2, 6}},
+		{MaxReducer, []int32{7, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("reducer %q", tt.reducer), func(t *testing.T) {
+			ctx := context.Background()
+			if tt.reducer != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, ReducerMetadataKey, tt.reducer)
+			}
+			resp, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: inputNums})
+			if err != nil {
+				t.Fatalf("AggregateBatch() error = %v", err)
+			}
+			var results []int32
+			for _, result := range resp.Results {
+				results = append(results, result.Result)
+			}
+			if fmt.Sprint(results) != fmt.Sprint(tt.want) {
+				t.Errorf("AggregateBatch() results = %v, want %v", results, tt.want)
+			}
+		})
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ReducerMetadataKey, "median")
+	_, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: inputNums})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.InvalidArgument || info.Reason != "REDUCER_NOT_ALLOWED" || info.Metadata["reducer"] != "median" {
+		t.Errorf("AggregateBatch() with unknown reducer error = %v (ErrorInfo %v), want InvalidArgument REDUCER_NOT_ALLOWED", err, info)
+	}
+}
+
+// Test of the page sizes of the QueryAggregates() RPC: the server picks a default page size when
+//   none is given, caps it at its maximum, and does not read past the limit of the query.
+func TestQueryAggregatesPageSize(t *testing.T) {
+	fs, client := newBufconnGeneralFewerServer(t, NewRecordingServerLogger(), loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+	sink, err := NewJSONLinesAggregateSink(filepath.Join(t.TempDir(), "aggregates.jsonl"))
+	if err != nil {
+		t.Fatalf("NewJSONLinesAggregateSink() error = %v", err)
+	}
+	defer sink.Close()
+	for i := range maxQueryPageSize + 1 {
+		record := AggregateRecord{StreamID: "s1", Window: AggregateWindow{Index: int64(i)}, EmittedAt: time.Now().UTC()}
+		if err := sink.RecordAggregate(record); err != nil {
+			t.Fatalf("RecordAggregate() error = %v", err)
+		}
+	}
+	fs.SetAggregateSink(sink)
+
+	tests := []struct {
+		name      string
+		req       *pb.QueryAggregatesRequest
+		wantPages []int
+	}{
+		{"default page size", &pb.QueryAggregatesRequest{}, []int{100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 1}},
+		{"page size over the maximum", &pb.QueryAggregatesRequest{PageSize: 5000}, []int{1000, 1}},
+		{"page size", &pb.QueryAggregatesRequest{PageSize: 400}, []int{400, 400, 201}},
+		{"limit", &pb.QueryAggregatesRequest{PageSize: 400, Limit: 500}, []int{400, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.QueryAggregates(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("QueryAggregates() error = %v", err)
+			}
+			var pages []int
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv() error = %v", err)
+				}
+				pages = append(pages, len(resp.Aggregates))
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) {
+				t.Errorf("sizes of QueryAggregates() pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+// Benchmark of GetAggregatesStream() throughput, sending b.N numbers over one stream, either one
+//   number per NumberRequest, or packed into NumberRequest messages of several numbers.
+func BenchmarkGetAggregatesStream(b *testing.B) {
+	for _, packSize := range []int{1, 16, 64, 256} {
+		b.Run(fmt.Sprintf("pack=%d", packSize), func(b *testing.B) {
+			client := newBufconnFewerClient(b)
+			stream, err := client.GetAggregatesStream(context.Background())
+			if err != nil {
+				b.Fatalf("GetAggregatesStream: %v", err)
+			}
+
+			// Drain the responses concurrently, as the service would otherwise block on sends.
+			drained := make(chan error, 1)
+			go func() {
+				for {
+					if _, err := stream.Recv(); err != nil {
+						if err == io.EOF {
+							err = nil
+						}
+						drained <- err
+						return
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			packed := make([]int32, 0, packSize)
+			for i := 0; i < b.N; i++ {
+				packed = append(packed, int32(i))
+				if len(packed) == packSize || i == b.N-1 {
+					req := &pb.NumberRequest{InputNums: packed}
+					if len(packed) == 1 {
+						req = &pb.NumberRequest{InputNum: packed[0]}
+					}
+					if err := stream.Send(req); err != nil {
+						b.Fatalf("Send: %v", err)
+					}
+					packed = packed[:0]
+				}
+			}
+			stream.CloseSend()
+			if err := <-drained; err != nil {
+				b.Fatalf("Recv: %v", err)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "inputs/s")
+		})
+	}
+}
+
+// Benchmark of GetAggregatesStream() throughput with many concurrent streams, each sending 300
+//   numbers per benchmark iteration.
+func BenchmarkGetAggregatesStreamParallel(b *testing.B) {
+	const inputsPerStream = 300
+	client := newBufconnFewerClient(b)
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			stream, err := client.GetAggregatesStream(context.Background())
+			if err != nil {
+				b.Errorf("GetAggregatesStream: %v", err)
+				return
+			}
+			go func() {
+				for i := 0; i < inputsPerStream; i++ {
+					if err := stream.Send(&pb.NumberRequest{InputNum: int32(i)}); err != nil {
+						return
+					}
+				}
+				stream.CloseSend()
+			}()
+			for {
+				if _, err := stream.Recv(); err != nil {
+					if err != io.EOF {
+						b.Errorf("Recv: %v", err)
+					}
+					break
+				}
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(b.N*inputsPerStream)/b.Elapsed().Seconds(), "inputs/s")
+}
+
+// Benchmark of the unary AggregateBatch() RPC, with 300 numbers per batch.
+func BenchmarkAggregateBatch(b *testing.B) {
+	client := newBufconnFewerClient(b)
+	req := &pb.AggregateBatchRequest{InputNums: make([]int32, 300)}
+	for i := range req.InputNums {
+		req.InputNums[i] = int32(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.AggregateBatch(context.Background(), req); err != nil {
+			b.Fatalf("AggregateBatch: %v", err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N*len(req.InputNums))/b.Elapsed().Seconds(), "inputs/s")
+}
+
+// Benchmark of the client-streaming AggregateUpload() RPC, uploading b.N numbers one per message.
+func BenchmarkAggregateUpload(b *testing.B) {
+	client := newBufconnFewerClient(b)
+	stream, err := client.AggregateUpload(context.Background())
+	if err != nil {
+		b.Fatalf("AggregateUpload: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := stream.Send(&pb.NumberRequest{InputNum: int32(i)}); err != nil {
+			b.Fatalf("Send: %v", err)
+		}
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		b.Fatalf("CloseAndRecv: %v", err)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "inputs/s")
+}
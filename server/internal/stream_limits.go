@@ -0,0 +1,402 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/tap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Definition of the limits the Fewer Service enforces on its aggregation streams (the
+//   GetAggregatesStream() and AggregateUpload() streams).  A limit of 0 is no limit.
+type StreamLimits struct {
+	// Maximum number of aggregation streams open at once, over all clients and from a single
+	//   client (peer host).
+	MaxStreams        int
+	MaxStreamsPerPeer int
+	// Maximum number of inputs aggregated on a single stream, or in a single AggregateBatch() call.
+	MaxInputs         int64
+	// Maximum time a stream may stay open, and maximum time between two requests on a stream.
+	MaxDuration       time.Duration
+	MaxIdle           time.Duration
+}
+
+
+
+//*************************************************************************************************
+// Definition of the limiter that keeps count of the open aggregation streams of the Fewer Service,
+//   and enforces its StreamLimits on them.  It also keeps track of the open streams by ID, so that
+//   the FewerAdmin service can list and cancel them, and stops taking new streams once the server
+//   is draining.
+type streamLimiter struct {
+	serverLogger ServerLogger
+
+	mu          sync.Mutex
+	limits      StreamLimits
+	open        int
+	openPerPeer map[string]int
+	// Open streams whose aggregator was attached, by stream ID.
+	streams     map[string]*limitedStream
+	// Whether the server is draining, and the channel closed once no stream is open anymore while
+	//   it is draining.
+	draining    bool
+	drained     chan struct{}
+}
+
+// Constructor function for creating a new streamLimiter without limits.
+func newStreamLimiter(serverLogger ServerLogger) *streamLimiter {
+	return &streamLimiter{
+		serverLogger: serverLogger,
+		openPerPeer:  make(map[string]int),
+		streams:      make(map[string]*limitedStream),
+	}
+}
+
+// Method of the streamLimiter that replaces its limits.  Only streams opened afterwards use the
+//   new limits.
+func (l *streamLimiter) setLimits(limits StreamLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+// Method of the streamLimiter that opens a new aggregation stream of the given method, unless
+//   there are already as many streams open as the limits allow, in which case a ResourceExhausted
+//   status error is returned.  The returned limitedStream must be closed once the stream ends.
+func (l *streamLimiter) openStream(ctx context.Context, method string) (*limitedStream, error) {
+	peerHost := peerHostOf(ctx)
+	peerAddr := peerHost
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return nil, l.drainingError(method, peerHost)
+	}
+	if l.limits.MaxStreams > 0 && l.open >= l.limits.MaxStreams {
+		l.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Rejecting stream from %s, as %d aggregation streams are already open (limits.max_streams)", peerHost, l.open))
+		return nil, statusError(
+			codes.ResourceExhausted,
+			pb.ErrorReason_TOO_MANY_STREAMS,
+			fmt.Sprintf("too many aggregation streams open on the server (limit %d)", l.limits.MaxStreams),
+			limitMetadata(int64(l.limits.MaxStreams)),
+			quotaFailure("server", "limits.max_streams"),
+		)
+	}
+	if l.limits.MaxStreamsPerPeer > 0 && l.openPerPeer[peerHost] >= l.limits.MaxStreamsPerPeer {
+		l.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Rejecting stream from %s, as it already has %d aggregation streams open (limits.max_streams_per_peer)", peerHost, l.openPerPeer[peerHost]))
+		return nil, statusError(
+			codes.ResourceExhausted,
+			pb.ErrorReason_TOO_MANY_STREAMS_PER_CLIENT,
+			fmt.Sprintf("too many aggregation streams open from this client (limit %d)", l.limits.MaxStreamsPerPeer),
+			limitMetadata(int64(l.limits.MaxStreamsPerPeer)),
+			quotaFailure("client "+peerHost, "limits.max_streams_per_peer"),
+		)
+	}
+	l.open++
+	l.openPerPeer[peerHost]++
+
+	ls := &limitedStream{limiter: l, method: method, peerHost: peerHost, peerAddr: peerAddr, limits: l.limits, ctx: ctx}
+	ls.cancel, _ = ctx.Value(callCancelKey{}).(context.CancelCauseFunc)
+	if l.limits.MaxDuration > 0 {
+		ls.deadline = time.Now().Add(l.limits.MaxDuration)
+	}
+	return ls, nil
+}
+
+// Method of the streamLimiter that checks that a stream or batch of the given method does not go
+//   over the maximum number of inputs.  Returns a ResourceExhausted status error if it does.
+func (l *streamLimiter) checkInputs(method, peerHost string, maxInputs, inputs int64) error {
+	if maxInputs > 0 && inputs > maxInputs {
+		l.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Rejecting inputs from %s, as they go over the maximum of %d inputs (limits.max_stream_inputs)", peerHost, maxInputs))
+		return statusError(
+			codes.ResourceExhausted,
+			pb.ErrorReason_TOO_MANY_INPUTS,
+			fmt.Sprintf("too many inputs (limit %d)", maxInputs),
+			limitMetadata(maxInputs),
+			quotaFailure("call", "limits.max_stream_inputs"),
+		)
+	}
+	return nil
+}
+
+// Method of the streamLimiter that checks the number of inputs of an AggregateBatch() call.
+func (l *streamLimiter) checkBatchInputs(ctx context.Context, method string, inputs int) error {
+	l.mu.Lock()
+	maxInputs := l.limits.MaxInputs
+	l.mu.Unlock()
+	return l.checkInputs(method, peerHostOf(ctx), maxInputs, int64(inputs))
+}
+
+// Method of the streamLimiter that returns the number of aggregation streams currently open.
+func (l *streamLimiter) openStreams() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open
+}
+
+// Method of the streamLimiter that checks that the server is not draining before an AggregateBatch()
+//   call is aggregated.  Returns an Unavailable status error if it is.
+func (l *streamLimiter) checkDraining(ctx context.Context, method string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return l.drainingError(method, peerHostOf(ctx))
+	}
+	return nil
+}
+
+// Internal method of the streamLimiter that logs a call rejected as the server is draining, and
+//   returns its Unavailable status error.
+func (l *streamLimiter) drainingError(method, peerHost string) error {
+	l.serverLogger.ServerLogWarn("rpc", method, fmt.Sprintf("Rejecting call from %s, as the server is draining", peerHost))
+	return statusError(codes.Unavailable, pb.ErrorReason_DRAINING, "server is draining, and does not take new aggregation calls", nil)
+}
+
+// Method of the streamLimiter that starts draining: new aggregation streams and batches are
+//   rejected from now on.  It returns the number of streams still open, and a channel closed once
+//   all of them have ended.  Draining again returns the same channel.
+func (l *streamLimiter) drain() (open int, drained <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.draining {
+		l.draining = true
+		l.drained = make(chan struct{})
+		if l.open == 0 {
+			close(l.drained)
+		}
+	}
+	return l.open, l.drained
+}
+
+// Method of the streamLimiter that returns whether the server is draining.
+func (l *streamLimiter) isDraining() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.draining
+}
+
+// Method of the streamLimiter that returns the open streams whose aggregator was attached, oldest
+//   first.
+func (l *streamLimiter) activeStreams() []*limitedStream {
+	l.mu.Lock()
+	streams := make([]*limitedStream, 0, len(l.streams))
+	for _, ls := range l.streams {
+		streams = append(streams, ls)
+	}
+	l.mu.Unlock()
+	slices.SortFunc(streams, func(a, b *limitedStream) int {
+		return a.agg.streamStartedAt.Compare(b.agg.streamStartedAt)
+	})
+	return streams
+}
+
+// Method of the streamLimiter that cancels the open stream with the given ID, through the context of
+//   its call, which ends the stream with a Canceled status error.  Its summary and the reason are
+//   set as its trailer first.  Returns false if no such stream is open.
+func (l *streamLimiter) cancelStream(streamID, reason string) (*limitedStream, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ls, ok := l.streams[streamID]
+	if !ok {
+		return nil, false
+	}
+	if !ls.cancelled {
+		message := "stream cancelled by an administrator"
+		if reason != "" {
+			message += ": " + reason
+		}
+		l.serverLogger.ServerLogWarn("rpc", ls.method, fmt.Sprintf("Cancelling stream %s from %s (%s)", streamID, ls.peerHost, message))
+		// gRPC ends the stream as soon as its context is cancelled, before its handler returns, so
+		//   its trailer cannot be left to the handler.
+		trailer, err := summaryTrailer(ls.agg)
+		if err != nil {
+			l.serverLogger.ServerLogWarn("rpc", ls.method, fmt.Sprintf("Could not encode summary trailer of stream %s: %v", streamID, err))
+		}
+		grpc.SetTrailer(ls.ctx, metadata.Join(trailer, metadata.Pairs(CancelReasonTrailerKey, reason)))
+		ls.cancelled = true
+		if ls.cancel != nil {
+			ls.cancel(errors.New(message))
+		}
+	}
+	return ls, true
+}
+
+// Key of the context value holding the function that cancels the context of a call (see
+//   cancellableCallContext()).
+type callCancelKey struct{}
+
+// Tap handle of the GeneralFewerServer, run as every call arrives, before its stream is set up,
+//   that makes the context of the call cancellable, so that the FewerAdmin service can cancel an
+//   open aggregation stream.  Cancelling the context also ends a receive pending on the stream,
+//   which a context derived by the handler of the call could not do.
+func cancellableCallContext(ctx context.Context, info *tap.Info) (context.Context, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return context.WithValue(ctx, callCancelKey{}, cancel), nil
+}
+
+
+
+//*************************************************************************************************
+// Definition of an aggregation stream opened through a streamLimiter, holding the limits that were
+//   in place when it was opened.
+type limitedStream struct {
+	limiter   *streamLimiter
+	method    string
+	peerHost  string
+	peerAddr  string
+	limits    StreamLimits
+	// Time by which the stream must end, if it has a maximum duration.
+	deadline  time.Time
+	// Aggregator of the stream, once it is attached.
+	agg       *aggregator
+	// Context of the call of the stream, the function cancelling it (see cancellableCallContext()),
+	//   nil if the call was not made through a GeneralFewerServer, and whether the stream was
+	//   cancelled (guarded by the mutex of the limiter).
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	cancelled bool
+}
+
+// Method of the limitedStream that attaches the aggregator of the stream, which makes the stream
+//   known by its ID to the FewerAdmin service.
+func (ls *limitedStream) attach(agg *aggregator) {
+	l := ls.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ls.agg = agg
+	l.streams[agg.streamID] = ls
+}
+
+// Method of the limitedStream that releases its place among the open streams.
+func (ls *limitedStream) close() {
+	l := ls.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.open--
+	if l.openPerPeer[ls.peerHost]--; l.openPerPeer[ls.peerHost] <= 0 {
+		delete(l.openPerPeer, ls.peerHost)
+	}
+	if ls.agg != nil {
+		delete(l.streams, ls.agg.streamID)
+	}
+	if l.draining && l.open == 0 {
+		close(l.drained)
+	}
+}
+
+// Method of the limitedStream that describes the stream, as it is now, for the FewerAdmin service.
+//   Its aggregator must be attached.
+func (ls *limitedStream) describe() *pb.ActiveStream {
+	inputs, value, batches := ls.agg.progress()
+	return &pb.ActiveStream{
+		StreamId:   ls.agg.streamID,
+		Method:     strings.TrimPrefix(ls.method, "pb.FewerService_"),
+		Peer:       ls.peerAddr,
+		Key:        ls.agg.key,
+		Tenant:     ls.agg.tenant,
+		StartedAt:  timestamppb.New(ls.agg.streamStartedAt),
+		InputsSeen: inputs,
+		PartialSum: int64(value),
+		Reducer:    ls.agg.reducer,
+		Batches:    batches,
+	}
+}
+
+// Method of the limitedStream that checks that the stream does not go over the maximum number of
+//   inputs once it reaches the given number of inputs.
+func (ls *limitedStream) checkInputs(inputs int64) error {
+	return ls.limiter.checkInputs(ls.method, ls.peerHost, ls.limits.MaxInputs, inputs)
+}
+
+// Function that receives the next request of a limitedStream through recv, unless the stream stays
+//   idle for longer than its maximum idle time, or goes past its maximum duration, in which case a
+//   DeadlineExceeded status error is returned.  The stream must then be ended, so that the pending
+//   recv returns.
+func recvWithinLimits[Req any](ls *limitedStream, recv func() (*Req, error)) (*Req, error) {
+	if ls.limits.MaxIdle == 0 && ls.limits.MaxDuration == 0 {
+		req, err := recv()
+		return req, ls.checkRecvErr(err)
+	}
+
+	wait, idle := ls.limits.MaxIdle, true
+	if ls.limits.MaxDuration > 0 {
+		if left := time.Until(ls.deadline); wait == 0 || left < wait {
+			wait, idle = left, false
+		}
+	}
+	if wait > 0 {
+		type received struct {
+			req *Req
+			err error
+		}
+		results := make(chan received, 1)
+		go func() {
+			req, err := recv()
+			results <- received{req, err}
+		}()
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case r := <-results:
+			return r.req, ls.checkRecvErr(r.err)
+		case <-timer.C:
+		}
+	}
+
+	if idle {
+		ls.limiter.serverLogger.ServerLogWarn("rpc", ls.method, fmt.Sprintf("Ending stream from %s, as it was idle for longer than %v (limits.max_stream_idle)", ls.peerHost, ls.limits.MaxIdle))
+		return nil, statusError(
+			codes.DeadlineExceeded,
+			pb.ErrorReason_STREAM_IDLE,
+			fmt.Sprintf("no input received for %v", ls.limits.MaxIdle),
+			map[string]string{"limit": ls.limits.MaxIdle.String()},
+		)
+	}
+	ls.limiter.serverLogger.ServerLogWarn("rpc", ls.method, fmt.Sprintf("Ending stream from %s, as it was open for longer than %v (limits.max_stream_duration)", ls.peerHost, ls.limits.MaxDuration))
+	return nil, statusError(
+		codes.DeadlineExceeded,
+		pb.ErrorReason_STREAM_TOO_LONG,
+		fmt.Sprintf("stream was open for longer than %v", ls.limits.MaxDuration),
+		map[string]string{"limit": ls.limits.MaxDuration.String()},
+	)
+}
+
+// Method of the limitedStream that logs a request rejected by gRPC for being larger than the
+//   maximum receive message size, and returns the receive error with details (see streamError()).
+func (ls *limitedStream) checkRecvErr(err error) error {
+	if status.Code(err) == codes.ResourceExhausted {
+		ls.limiter.serverLogger.ServerLogWarn("rpc", ls.method, fmt.Sprintf("Rejected request from %s larger than the maximum message size (limits.max_recv_msg_size): %v", ls.peerHost, err))
+	}
+	return streamError(err)
+}
+
+// Helper function that returns the host of the client of a call, which the per-peer limits are
+//   counted by.  Clients connected through a Unix domain socket all count as the same peer.
+func peerHostOf(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	if p.Addr.Network() == "unix" {
+		return "unix"
+	}
+	return p.Addr.String()
+}
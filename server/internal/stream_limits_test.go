@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Helper function that waits until the given number of aggregation streams are open on a server.
+func waitForOpenStreams(t *testing.T, fs *GeneralFewerServer, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for fs.srv.limiter.openStreams() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d aggregation streams open, want %d", fs.srv.limiter.openStreams(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Test that streams beyond the maximum number of open aggregation streams, over all clients or from
+//   a single client, are rejected until an open stream ends.
+func TestStreamLimitsOpenStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantLog string
+	}{
+		{name: "global", env: map[string]string{"FEWER_LIMITS_MAX_STREAMS": "1"}, wantLog: "(limits.max_streams)"},
+		{name: "per peer", env: map[string]string{"FEWER_LIMITS_MAX_STREAMS_PER_PEER": "1"}, wantLog: "(limits.max_streams_per_peer)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverLogger := NewRecordingServerLogger()
+			fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, tt.env))
+			go fs.Serve()
+			defer fs.Shutdown()
+
+			first, err := client.GetAggregatesStream(context.Background())
+			if err != nil {
+				t.Fatalf("GetAggregatesStream: %v", err)
+			}
+			waitForOpenStreams(t, fs, 1)
+
+			upload, err := client.AggregateUpload(context.Background())
+			if err != nil {
+				t.Fatalf("AggregateUpload: %v", err)
+			}
+			if _, err := upload.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("second stream error = %v, want ResourceExhausted", err)
+			}
+			serverLogger.AssertLogged(t, "warn", tt.wantLog)
+
+			first.CloseSend()
+			if resp, err := first.Recv(); err != nil || resp.GetSummary() == nil {
+				t.Fatalf("first stream Recv() = %v, %v, want the summary of the stream", resp, err)
+			}
+			if _, err := first.Recv(); err == nil {
+				t.Fatalf("first stream Recv() returned a message after the summary, want the end of the stream")
+			}
+			waitForOpenStreams(t, fs, 0)
+			upload, err = client.AggregateUpload(context.Background())
+			if err != nil {
+				t.Fatalf("AggregateUpload: %v", err)
+			}
+			if _, err := upload.CloseAndRecv(); err != nil {
+				t.Errorf("stream after the first one ended error = %v, want none", err)
+			}
+		})
+	}
+}
+
+// Test that streams and batches going over the maximum number of inputs are rejected.
+func TestStreamLimitsMaxInputs(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, map[string]string{"FEWER_LIMITS_MAX_STREAM_INPUTS": "4"}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3, 4}}); err != nil {
+		t.Errorf("AggregateBatch() of 4 inputs error = %v, want none", err)
+	}
+	if _, err := client.AggregateBatch(context.Background(), &pb.AggregateBatchRequest{InputNums: []int32{1, 2, 3, 4, 5}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("AggregateBatch() of 5 inputs error = %v, want ResourceExhausted", err)
+	}
+
+	stream, err := client.GetAggregatesStream(context.Background())
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3}})
+	stream.Send(&pb.NumberRequest{InputNums: []int32{4, 5}})
+	for {
+		_, err := stream.Recv()
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("GetAggregatesStream() error = %v, want ResourceExhausted", err)
+		}
+		break
+	}
+	serverLogger.AssertLogged(t, "warn", "as they go over the maximum of 4 inputs (limits.max_stream_inputs)")
+}
+
+// Test that streams that stay idle, or stay open, for too long are ended with DeadlineExceeded.
+func TestStreamLimitsDeadlines(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantLog string
+	}{
+		{name: "idle", env: map[string]string{"FEWER_LIMITS_MAX_STREAM_IDLE": "100ms"}, wantLog: "as it was idle for longer than 100ms (limits.max_stream_idle)"},
+		{name: "duration", env: map[string]string{"FEWER_LIMITS_MAX_STREAM_DURATION": "300ms", "FEWER_LIMITS_MAX_STREAM_IDLE": "1s"}, wantLog: "as it was open for longer than 300ms (limits.max_stream_duration)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverLogger := NewRecordingServerLogger()
+			fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, tt.env))
+			go fs.Serve()
+			defer fs.Shutdown()
+
+			stream, err := client.GetAggregatesStream(context.Background())
+			if err != nil {
+				t.Fatalf("GetAggregatesStream: %v", err)
+			}
+			// Keep sending inputs more often than the idle limit, which only the duration limit ends.
+			if tt.name == "duration" {
+				go func() {
+					for i := int32(1); stream.Send(&pb.NumberRequest{InputNum: i}) == nil; i++ {
+						time.Sleep(20 * time.Millisecond)
+					}
+				}()
+			}
+			started := time.Now()
+			for {
+				if _, err = stream.Recv(); err != nil {
+					break
+				}
+			}
+			if status.Code(err) != codes.DeadlineExceeded {
+				t.Errorf("GetAggregatesStream() error = %v, want DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("stream ended after %v, want it ended by its limit", elapsed)
+			}
+			serverLogger.AssertLogged(t, "warn", tt.wantLog)
+			waitForOpenStreams(t, fs, 0)
+		})
+	}
+}
+
+// Test that requests larger than the maximum receive message size are rejected and logged.
+func TestStreamLimitsMaxRecvMsgSize(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, map[string]string{"FEWER_LIMITS_MAX_RECV_MSG_SIZE": "64"}))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	upload, err := client.AggregateUpload(context.Background())
+	if err != nil {
+		t.Fatalf("AggregateUpload: %v", err)
+	}
+	upload.Send(&pb.NumberRequest{InputNums: make([]int32, 100)})
+	if _, err := upload.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("AggregateUpload() error = %v, want ResourceExhausted", err)
+	}
+	serverLogger.AssertLogged(t, "warn", "larger than the maximum message size (limits.max_recv_msg_size)")
+}
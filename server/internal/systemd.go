@@ -0,0 +1,9 @@
+package internal
+
+// States reported to systemd through its notification socket (see SystemdNotifier).  systemd only
+//   runs on Unix systems; elsewhere, the server is never socket-activated, and reports nothing.
+const (
+	SystemdReady    = "READY=1"
+	SystemdStopping = "STOPPING=1"
+	SystemdWatchdog = "WATCHDOG=1"
+)
@@ -0,0 +1,43 @@
+//go:build !unix
+
+package internal
+
+import (
+	"net"
+	"time"
+)
+
+
+
+//*************************************************************************************************
+// Definition of the notifier reporting the state of the server to systemd, on systems without
+//   systemd.  It is always nil, and reports nothing.
+type SystemdNotifier struct{}
+
+// Function that returns the listeners passed to the process by systemd socket activation, which
+//   never happens on systems without systemd.
+func SystemdListeners() ([]net.Listener, error) {
+	return nil, nil
+}
+
+// Constructor function that returns the SystemdNotifier of a process started by systemd, which is
+//   nil on systems without systemd.
+func NewSystemdNotifierFromEnv() *SystemdNotifier {
+	return nil
+}
+
+// Method of the SystemdNotifier that reports nothing.
+func (n *SystemdNotifier) Notify(state string) error {
+	return nil
+}
+
+// Method of the SystemdNotifier that returns 0, as there is no watchdog to notify.
+func (n *SystemdNotifier) WatchdogInterval() time.Duration {
+	return 0
+}
+
+// Method of the SystemdNotifier that does nothing, as there is no watchdog to notify.
+func (n *SystemdNotifier) StartWatchdog(onError func(error)) {}
+
+// Method of the SystemdNotifier that does nothing, as there is no watchdog to notify.
+func (n *SystemdNotifier) StopWatchdog() {}
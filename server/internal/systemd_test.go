@@ -0,0 +1,172 @@
+//go:build unix
+
+package internal
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Helper function that listens on a fake systemd notification socket, and returns it along with
+//   the environment variables that point a SystemdNotifier to it.
+func fakeNotifySocket(t *testing.T) (*net.UnixConn, map[string]string) {
+	t.Helper()
+	socketPath := tempSocketPath(t)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, map[string]string{"NOTIFY_SOCKET": socketPath}
+}
+
+// Helper function that waits for the next notification received on a fake systemd notification
+//   socket, and fails the test unless it is the wanted state.
+func expectNotification(t *testing.T, conn *net.UnixConn, want string) {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("waiting for %s: %v", want, err)
+	}
+	if got := string(buf[:n]); got != want {
+		t.Errorf("received notification %q, want %q", got, want)
+	}
+}
+
+// Helper function that returns a getenv function reading from the given variables.
+func fakeGetenv(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// Test that ListenAndServe() tells systemd that the server is ready, keeps its watchdog from firing
+//   while serving, and tells it that the server is stopping when it shuts down.
+func TestListenAndServeNotifiesSystemd(t *testing.T) {
+	conn, env := fakeNotifySocket(t)
+	env["WATCHDOG_USEC"] = "100000"
+	env["WATCHDOG_PID"] = strconv.Itoa(os.Getpid())
+	notifier := newSystemdNotifier(fakeGetenv(env), os.Getpid())
+	if got := notifier.WatchdogInterval(); got != 100*time.Millisecond {
+		t.Fatalf("WatchdogInterval() = %v, want 100ms", got)
+	}
+
+	serverLogger := NewRecordingServerLogger()
+	fs, _ := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	fs.SetSystemdNotifier(notifier)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fs.ListenAndServe()
+	}()
+	expectNotification(t, conn, SystemdReady)
+	expectNotification(t, conn, SystemdWatchdog)
+	expectNotification(t, conn, SystemdWatchdog)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ListenAndServe() did not return after SIGINT")
+	}
+	// Watchdog notifications sent before the shutdown may still be queued.
+	buf := make([]byte, 256)
+	for {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", SystemdStopping, err)
+		}
+		if got := string(buf[:n]); got == SystemdStopping {
+			break
+		} else if got != SystemdWatchdog {
+			t.Fatalf("received notification %q, want %q", got, SystemdStopping)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if n, err := conn.Read(buf); err == nil {
+		t.Errorf("received notification %q after the server stopped, want none", buf[:n])
+	}
+}
+
+// Test of the notifiers created from the environment: none without a notification socket, no
+//   watchdog if it is meant for another process, and a nil notifier reports nothing.
+func TestNewSystemdNotifier(t *testing.T) {
+	if n := newSystemdNotifier(fakeGetenv(nil), 100); n != nil {
+		t.Errorf("newSystemdNotifier() without NOTIFY_SOCKET = %+v, want nil", n)
+	}
+	var nilNotifier *SystemdNotifier
+	if err := nilNotifier.Notify(SystemdReady); err != nil {
+		t.Errorf("Notify() on nil notifier error = %v", err)
+	}
+	nilNotifier.StartWatchdog(nil)
+	nilNotifier.StopWatchdog()
+
+	n := newSystemdNotifier(fakeGetenv(map[string]string{"NOTIFY_SOCKET": "@fewer", "WATCHDOG_USEC": "1000000", "WATCHDOG_PID": "99"}), 100)
+	if n.socketAddr.Name != "\x00fewer" {
+		t.Errorf("socket address = %q, want the abstract socket \\x00fewer", n.socketAddr.Name)
+	}
+	if got := n.WatchdogInterval(); got != 0 {
+		t.Errorf("WatchdogInterval() for another process = %v, want 0", got)
+	}
+}
+
+// Test of taking over the listening sockets passed by systemd socket activation.
+func TestSystemdListeners(t *testing.T) {
+	lis, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer lis.Close()
+	file, err := lis.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	defer file.Close()
+	// systemdListeners() takes over the file descriptors it is given, so give it its own.
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+	pid := strconv.Itoa(os.Getpid())
+
+	// Sockets meant for another process are left alone.
+	listeners, err := systemdListeners(fakeGetenv(map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}), os.Getpid(), fd)
+	if err != nil || listeners != nil {
+		t.Fatalf("systemdListeners() for another process = %v, %v, want none", listeners, err)
+	}
+
+	listeners, err = systemdListeners(fakeGetenv(map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "1", "LISTEN_FDNAMES": "fewer.socket"}), os.Getpid(), fd)
+	if err != nil || len(listeners) != 1 {
+		t.Fatalf("systemdListeners() = %v, %v, want one listener", listeners, err)
+	}
+	defer listeners[0].Close()
+	if got, want := listeners[0].Addr().String(), lis.Addr().String(); got != want {
+		t.Errorf("listener address = %s, want %s", got, want)
+	}
+
+	_, err = systemdListeners(fakeGetenv(map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "many"}), os.Getpid(), fd)
+	if err == nil || !strings.Contains(err.Error(), "LISTEN_FDS") {
+		t.Errorf("systemdListeners() with invalid LISTEN_FDS error = %v, want it to mention LISTEN_FDS", err)
+	}
+
+	// A file descriptor that is not a socket is rejected.
+	regular, err := os.CreateTemp(t.TempDir(), "fewer")
+	if err != nil {
+		t.Fatalf("CreateTemp() error = %v", err)
+	}
+	defer regular.Close()
+	regularFD, err := syscall.Dup(int(regular.Fd()))
+	if err != nil {
+		t.Fatalf("Dup() error = %v", err)
+	}
+	_, err = systemdListeners(fakeGetenv(map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "1"}), os.Getpid(), regularFD)
+	if err == nil || !strings.Contains(err.Error(), "not a listening socket") {
+		t.Errorf("systemdListeners() of a regular file error = %v, want it to be rejected", err)
+	}
+}
@@ -0,0 +1,181 @@
+//go:build unix
+
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// First file descriptor of the sockets passed by systemd socket activation (SD_LISTEN_FDS_START).
+const systemdListenFDsStart = 3
+
+// Function that returns the listeners passed to the process by systemd socket activation (the
+//   LISTEN_FDS and LISTEN_PID environment variables), or no listeners if the process was not
+//   socket-activated.  The environment variables are cleared, so that child processes do not
+//   take the listeners for theirs.
+func SystemdListeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return systemdListeners(os.Getenv, os.Getpid(), systemdListenFDsStart)
+}
+
+// Helper function that returns the listeners passed by systemd socket activation, as described by
+//   the environment variables read through getenv, to the process with the given PID, starting at
+//   file descriptor firstFD.
+func systemdListeners(getenv func(string) string, pid int, firstFD int) ([]net.Listener, error) {
+	if listenPID := getenv("LISTEN_PID"); listenPID == "" || listenPID != strconv.Itoa(pid) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS value %q", getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(getenv("LISTEN_FDNAMES"), ":")
+
+	var listeners []net.Listener
+	for i := 0; i < count; i++ {
+		fd := firstFD + i
+		syscall.CloseOnExec(fd)
+		name := fmt.Sprintf("LISTEN_FD_%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(fd), name)
+		lis, err := net.FileListener(file)
+		// net.FileListener() works on a duplicate of the file descriptor.
+		file.Close()
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("socket %s passed by systemd is not a listening socket: %w", name, err)
+		}
+		listeners = append(listeners, lis)
+	}
+	return listeners, nil
+}
+
+
+
+//*************************************************************************************************
+// Definition of a notifier reporting the state of the server to systemd through the socket named by
+//   the NOTIFY_SOCKET environment variable (sd_notify), for services of Type=notify.  A nil
+//   SystemdNotifier, returned when the server was not started by systemd, reports nothing.
+type SystemdNotifier struct {
+	socketAddr       *net.UnixAddr
+	// Interval at which systemd expects WATCHDOG=1 notifications.  0 if the watchdog is off.
+	watchdogInterval time.Duration
+
+	// Stops sending watchdog notifications, if StartWatchdog() was called.  Guarded by mu.
+	mu           sync.Mutex
+	stopWatchdog func()
+}
+
+// Constructor function that creates a SystemdNotifier from the NOTIFY_SOCKET, WATCHDOG_USEC and
+//   WATCHDOG_PID environment variables set by systemd.  Returns nil if NOTIFY_SOCKET is not set.
+func NewSystemdNotifierFromEnv() *SystemdNotifier {
+	return newSystemdNotifier(os.Getenv, os.Getpid())
+}
+
+// Helper constructor function that creates a SystemdNotifier for the process with the given PID,
+//   from the environment variables read through getenv.
+func newSystemdNotifier(getenv func(string) string, pid int) *SystemdNotifier {
+	socketPath := getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace.
+	if strings.HasPrefix(socketPath, "@") {
+		socketPath = "\x00" + socketPath[1:]
+	}
+	n := &SystemdNotifier{socketAddr: &net.UnixAddr{Name: socketPath, Net: "unixgram"}}
+
+	watchdogPID := getenv("WATCHDOG_PID")
+	if usec, err := strconv.ParseInt(getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 && (watchdogPID == "" || watchdogPID == strconv.Itoa(pid)) {
+		n.watchdogInterval = time.Duration(usec) * time.Microsecond
+	}
+	return n
+}
+
+// Method of the SystemdNotifier that reports the given state (e.g., SystemdReady) to systemd.
+func (n *SystemdNotifier) Notify(state string) error {
+	if n == nil {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, n.socketAddr)
+	if err != nil {
+		return fmt.Errorf("could not connect to systemd notification socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("could not notify systemd of %s: %w", state, err)
+	}
+	return nil
+}
+
+// Method of the SystemdNotifier that returns the interval at which systemd expects watchdog
+//   notifications, or 0 if the watchdog is off.
+func (n *SystemdNotifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.watchdogInterval
+}
+
+// Method of the SystemdNotifier that sends WATCHDOG=1 to systemd at half of the watchdog interval,
+//   until StopWatchdog() is called.  Failures are passed to onError, if not nil.  Does nothing if
+//   the watchdog is off.
+func (n *SystemdNotifier) StartWatchdog(onError func(error)) {
+	if n.WatchdogInterval() == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopWatchdog != nil {
+		return
+	}
+	ticker := time.NewTicker(n.watchdogInterval / 2)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := n.Notify(SystemdWatchdog); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	n.stopWatchdog = func() {
+		ticker.Stop()
+		close(done)
+		<-stopped
+	}
+}
+
+// Method of the SystemdNotifier that stops sending watchdog notifications.
+func (n *SystemdNotifier) StopWatchdog() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopWatchdog != nil {
+		n.stopWatchdog()
+		n.stopWatchdog = nil
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+// Metadata keys through which a client sets the validation rules of an aggregation stream, batch
+//   or upload when it opens it: the bounds inputs must stay within, a comma-separated list of
+//   disallowed inputs, and the policy applied to inputs that break these rules (see the wire
+//   package).
+const MinInputMetadataKey = wire.MinInputMetadataKey
+const MaxInputMetadataKey = wire.MaxInputMetadataKey
+const DisallowedInputsMetadataKey = wire.DisallowedInputsMetadataKey
+const InvalidInputPolicyMetadataKey = wire.InvalidInputPolicyMetadataKey
+
+// Policies applied to inputs that break the validation rules of their stream: fail the stream with
+//   an InvalidArgument status error, skip them (leaving them out of their aggregate), or clamp them
+//   to the nearest bound.  Disallowed inputs within the bounds cannot be clamped, and are skipped.
+const (
+	InvalidInputFail  = wire.InvalidInputFail
+	InvalidInputSkip  = wire.InvalidInputSkip
+	InvalidInputClamp = wire.InvalidInputClamp
+)
+
+
+
+//*************************************************************************************************
+// Definition of the validation rules of an aggregation stream, batch or upload, as set by its
+//   client (see wire.ValidationRules), with the policy always set, and the disallowed inputs
+//   indexed for the validation of every input.
+type validationRules struct {
+	wire.ValidationRules
+	disallowed map[int32]bool
+}
+
+// Outcome of the validation of an input.
+type inputOutcome int
+
+const (
+	inputAccepted inputOutcome = iota
+	inputSkipped
+	inputClamped
+	inputRejected
+)
+
+// Function that reads the validation rules of a call from its metadata.  Invalid rules are
+//   reported with an InvalidArgument status error, with a BadRequest detail naming every
+//   metadata key at fault.
+func validationRulesFromContext(ctx context.Context) (validationRules, error) {
+	rules := validationRules{ValidationRules: wire.ValidationRules{Policy: InvalidInputFail}}
+	var violations []*errdetails.BadRequest_FieldViolation
+	invalid := func(key, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: key, Description: description})
+	}
+	parseInput := func(key, value string) (int32, bool) {
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			invalid(key, fmt.Sprintf("%q is not a 32-bit integer", value))
+			return 0, false
+		}
+		return int32(n), true
+	}
+
+	if value := incomingMetadataValue(ctx, MinInputMetadataKey); value != "" {
+		if n, ok := parseInput(MinInputMetadataKey, value); ok {
+			rules.Min = &n
+		}
+	}
+	if value := incomingMetadataValue(ctx, MaxInputMetadataKey); value != "" {
+		if n, ok := parseInput(MaxInputMetadataKey, value); ok {
+			rules.Max = &n
+		}
+	}
+	if rules.Min != nil && rules.Max != nil && *rules.Min > *rules.Max {
+		invalid(MaxInputMetadataKey, fmt.Sprintf("maximum %d is below the minimum %d", *rules.Max, *rules.Min))
+	}
+	if value := incomingMetadataValue(ctx, DisallowedInputsMetadataKey); value != "" {
+		rules.disallowed = map[int32]bool{}
+		for _, item := range strings.Split(value, ",") {
+			if n, ok := parseInput(DisallowedInputsMetadataKey, item); ok {
+				rules.Disallowed = append(rules.Disallowed, n)
+				rules.disallowed[n] = true
+			}
+		}
+	}
+	if value := incomingMetadataValue(ctx, InvalidInputPolicyMetadataKey); value != "" {
+		switch value {
+		case InvalidInputFail, InvalidInputSkip, InvalidInputClamp:
+			rules.Policy = value
+		default:
+			invalid(InvalidInputPolicyMetadataKey, fmt.Sprintf("unknown invalid input policy %q (expected fail, skip or clamp)", value))
+		}
+	}
+
+	if len(violations) > 0 {
+		return validationRules{}, statusError(
+			codes.InvalidArgument,
+			pb.ErrorReason_INVALID_VALIDATION_RULES,
+			fmt.Sprintf("invalid validation rules: %s", violations[0].Description),
+			nil,
+			&errdetails.BadRequest{FieldViolations: violations},
+		)
+	}
+	return rules, nil
+}
+
+// Method of the validationRules that validates an input.  It returns the value to aggregate (the
+//   input itself, or the bound it was clamped to), what to do with the input, and why the input is
+//   invalid (empty if it is valid).
+func (r validationRules) check(inputNum int32) (value int32, outcome inputOutcome, violation string) {
+	value = inputNum
+	switch {
+	case r.Min != nil && inputNum < *r.Min:
+		violation, value = fmt.Sprintf("input %d is below the minimum of %d", inputNum, *r.Min), *r.Min
+	case r.Max != nil && inputNum > *r.Max:
+		violation, value = fmt.Sprintf("input %d is above the maximum of %d", inputNum, *r.Max), *r.Max
+	case r.disallowed[inputNum]:
+		violation = fmt.Sprintf("input %d is not allowed", inputNum)
+	}
+
+	switch {
+	case violation == "":
+		return inputNum, inputAccepted, ""
+	case r.Policy == InvalidInputFail:
+		return inputNum, inputRejected, violation
+	case r.Policy == InvalidInputClamp && value != inputNum && !r.disallowed[value]:
+		return value, inputClamped, violation
+	}
+	return inputNum, inputSkipped, violation
+}
+
+// Helper function that returns the InvalidArgument status error ending a call whose input at the
+//   given stream position (counted from 1) broke its validation rules.  field is the path of the
+//   input in its request (e.g., "input_nums[2]").
+func invalidInputError(field string, position int64, violation string) error {
+	return statusError(
+		codes.InvalidArgument,
+		pb.ErrorReason_INVALID_INPUT,
+		fmt.Sprintf("invalid input at position %d: %s", position, violation),
+		map[string]string{"input_position": strconv.FormatInt(position, 10)},
+		badRequest(field, violation),
+	)
+}
+
+// Helper function that returns the path of the input at index i of a NumberRequest (see
+//   requestInputNums()).
+func requestInputField(req *pb.NumberRequest, i int) string {
+	if len(req.InputNums) > 0 {
+		return fmt.Sprintf("input_nums[%d]", i)
+	}
+	return "input_num"
+}
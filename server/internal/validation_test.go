@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pb "github.com/astronomical3/fewer_grpc/fewer"
+	"github.com/astronomical3/fewer_grpc/internal/wire"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Test of the invalid input policies of a batch: skipped and clamped inputs are counted in every
+//   aggregate and in the summary, and the fail policy ends the call at the first invalid input.
+func TestValidationPolicies(t *testing.T) {
+	fs, client := newBufconnGeneralFewerServer(t, NewRecordingServerLogger(), loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	// The inputs 0 and 12 are out of bounds, and 5 is disallowed.
+	inputNums := []int32{0, 3, 5, 12, 4, 6}
+	tests := []struct {
+		policy      string
+		wantResults []*pb.NumberResponse
+		wantSkipped int64
+		wantClamped int64
+	}{
+		{
+			policy: InvalidInputSkip,
+			wantResults: []*pb.NumberResponse{
+				{Result: 3, WindowIndex: 0, FirstInput: 1, LastInput: 3, SkippedInputs: 2},
+				{Result: 10, WindowIndex: 1, FirstInput: 4, LastInput: 6, SkippedInputs: 1},
+			},
+			wantSkipped: 3,
+		},
+		{
+			policy: InvalidInputClamp,
+			wantResults: []*pb.NumberResponse{
+				{Result: 4, WindowIndex: 0, FirstInput: 1, LastInput: 3, SkippedInputs: 1, ClampedInputs: 1},
+				{Result: 20, WindowIndex: 1, FirstInput: 4, LastInput: 6, ClampedInputs: 1},
+			},
+			wantSkipped: 1,
+			wantClamped: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(),
+				MinInputMetadataKey, "1",
+				MaxInputMetadataKey, "10",
+				DisallowedInputsMetadataKey, "5",
+				InvalidInputPolicyMetadataKey, tt.policy,
+			)
+			resp, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: inputNums})
+			if err != nil {
+				t.Fatalf("AggregateBatch() error = %v", err)
+			}
+			if len(resp.Results) != len(tt.wantResults) {
+				t.Fatalf("AggregateBatch() results = %v, want %v", resp.Results, tt.wantResults)
+			}
+			for i, got := range resp.Results {
+				want := tt.wantResults[i]
+				if got.Result != want.Result || got.FirstInput != want.FirstInput || got.LastInput != want.LastInput ||
+					got.SkippedInputs != want.SkippedInputs || got.ClampedInputs != want.ClampedInputs {
+					t.Errorf("AggregateBatch() result %d = %v, want %v", i, got, want)
+				}
+			}
+			if resp.Summary.SkippedInputs != tt.wantSkipped || resp.Summary.ClampedInputs != tt.wantClamped {
+				t.Errorf("AggregateBatch() summary = %v, want %d skipped and %d clamped inputs", resp.Summary, tt.wantSkipped, tt.wantClamped)
+			}
+		})
+	}
+
+	t.Run(InvalidInputFail, func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), MinInputMetadataKey, "1")
+		_, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: inputNums})
+		info := errorInfoOf(t, err)
+		if status.Code(err) != codes.InvalidArgument || info.Reason != "INVALID_INPUT" || info.Metadata["input_position"] != "1" {
+			t.Errorf("AggregateBatch() error = %v (ErrorInfo %v), want InvalidArgument INVALID_INPUT at position 1", err, info)
+		}
+		if badRequest := detailOf[*errdetails.BadRequest](err); len(badRequest.GetFieldViolations()) != 1 || badRequest.FieldViolations[0].Field != "input_nums[0]" {
+			t.Errorf("AggregateBatch() BadRequest detail = %v, want a violation of input_nums[0]", badRequest)
+		}
+	})
+}
+
+// Test that an invalid input ends a GetAggregatesStream() stream under the default fail policy,
+//   after the aggregates of the inputs before it were sent back.
+func TestValidationFailsStream(t *testing.T) {
+	serverLogger := NewRecordingServerLogger()
+	fs, client := newBufconnGeneralFewerServer(t, serverLogger, loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MaxInputMetadataKey, "10")
+	stream, err := client.GetAggregatesStream(ctx)
+	if err != nil {
+		t.Fatalf("GetAggregatesStream: %v", err)
+	}
+	stream.Send(&pb.NumberRequest{InputNums: []int32{1, 2, 3}})
+	stream.Send(&pb.NumberRequest{InputNum: 11})
+	var results []int32
+	for {
+		resp, recvErr := stream.Recv()
+		if recvErr != nil {
+			err = recvErr
+			break
+		}
+		if aggregate := resp.GetAggregate(); aggregate != nil {
+			results = append(results, aggregate.Result)
+		}
+	}
+	if len(results) != 1 || results[0] != 6 {
+		t.Errorf("aggregates received = %v, want [6]", results)
+	}
+	info := errorInfoOf(t, err)
+	if status.Code(err) != codes.InvalidArgument || info.Reason != "INVALID_INPUT" || info.Metadata["input_position"] != "4" {
+		t.Errorf("stream error = %v (ErrorInfo %v), want InvalidArgument INVALID_INPUT at position 4", err, info)
+	}
+	if badRequest := detailOf[*errdetails.BadRequest](err); len(badRequest.GetFieldViolations()) != 1 || badRequest.FieldViolations[0].Field != "input_num" {
+		t.Errorf("stream BadRequest detail = %v, want a violation of input_num", badRequest)
+	}
+	serverLogger.AssertLogged(t, "warn", "as input 4 is invalid: input 11 is above the maximum of 10")
+}
+
+// Test that invalid validation rules are rejected before any input is aggregated, with every
+//   metadata key at fault.
+func TestInvalidValidationRules(t *testing.T) {
+	fs, client := newBufconnGeneralFewerServer(t, NewRecordingServerLogger(), loadTestServerConfig(t, nil))
+	go fs.Serve()
+	defer fs.Shutdown()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		MinInputMetadataKey, "low",
+		DisallowedInputsMetadataKey, "1,2,x",
+		InvalidInputPolicyMetadataKey, "ignore",
+	)
+	_, err := client.AggregateBatch(ctx, &pb.AggregateBatchRequest{InputNums: []int32{1}})
+	if info := errorInfoOf(t, err); status.Code(err) != codes.InvalidArgument || info.Reason != "INVALID_VALIDATION_RULES" {
+		t.Errorf("AggregateBatch() error = %v (reason %s), want InvalidArgument INVALID_VALIDATION_RULES", err, info.Reason)
+	}
+	var fields []string
+	for _, violation := range detailOf[*errdetails.BadRequest](err).GetFieldViolations() {
+		fields = append(fields, violation.Field)
+	}
+	want := []string{MinInputMetadataKey, DisallowedInputsMetadataKey, InvalidInputPolicyMetadataKey}
+	if len(fields) != len(want) || fields[0] != want[0] || fields[1] != want[1] || fields[2] != want[2] {
+		t.Errorf("AggregateBatch() BadRequest fields = %v, want %v", fields, want)
+	}
+}
+
+// Test that the validation rules a client sends in the metadata of a call (see
+//   wire.ValidationRules.MetadataPairs()) are read back as they were set.
+func TestValidationRulesMetadataRoundTrip(t *testing.T) {
+	minInput, maxInput := int32(-5), int32(50)
+	tests := []wire.ValidationRules{
+		{},
+		{Min: &minInput, Policy: InvalidInputClamp},
+		{Max: &maxInput, Disallowed: []int32{3, -1}},
+		{Min: &minInput, Max: &maxInput, Disallowed: []int32{7}, Policy: InvalidInputSkip},
+	}
+	for _, sent := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(sent.MetadataPairs()...))
+		got, err := validationRulesFromContext(ctx)
+		if err != nil {
+			t.Fatalf("validationRulesFromContext(%v) error = %v", sent.MetadataPairs(), err)
+		}
+		want := sent
+		if want.Policy == "" {
+			want.Policy = InvalidInputFail
+		}
+		if !reflect.DeepEqual(got.ValidationRules, want) {
+			t.Errorf("validationRulesFromContext(%v) = %+v, want %+v", sent.MetadataPairs(), got.ValidationRules, want)
+		}
+		for _, inputNum := range sent.Disallowed {
+			if !got.disallowed[inputNum] {
+				t.Errorf("validationRulesFromContext(%v) does not disallow %d", sent.MetadataPairs(), inputNum)
+			}
+		}
+	}
+}